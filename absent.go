@@ -0,0 +1,26 @@
+package gouache
+
+// absent is the type of the Absent sentinel.
+type absent struct{}
+
+// Absent is a sentinel value stored in a cache to remember that a key is known
+// to be absent from the database, so that repeated lookups of a missing key do
+// not reach the database (negative caching).
+//
+// Read-through caches store Absent with a short TTL when the database returns
+// ErrNotFound, and translate it back into ErrNotFound when they read it.
+// Every backend of this module can store Absent, including the ones that
+// serialize values.
+var Absent any = absent{}
+
+// IsAbsent reports whether a cached value is the Absent sentinel.
+//
+// Parameters:
+//   - val: The cached value to check
+//
+// Returns:
+//   - true if val is Absent
+func IsAbsent(val any) bool {
+	_, ok := val.(absent)
+	return ok
+}
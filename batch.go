@@ -0,0 +1,153 @@
+package gouache
+
+import (
+	"context"
+	"errors"
+)
+
+// BatchCache is an optional interface that a Cache can implement to handle
+// several keys in a single operation, e.g. with one network round trip.
+//
+// Use the GetMulti, SetMulti and DeleteMulti helpers to call these methods,
+// they fall back to looping over the Cache methods for implementations that
+// do not support batching natively.
+type BatchCache interface {
+	// GetMulti retrieves the values for the given keys.
+	// Keys that do not exist in the cache are omitted from the returned map,
+	// a missing key is never reported as an error.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - keys: The keys to retrieve the values for
+	//
+	// Returns:
+	//   - A map of the found keys to their cached values
+	//   - An error if the operation fails
+	GetMulti(ctx context.Context, keys []string) (map[string]any, error)
+
+	// SetMulti stores every key/value pair of the given map in the cache.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - vals: The key/value pairs to store
+	//
+	// Returns:
+	//   - An error if the operation fails
+	SetMulti(ctx context.Context, vals map[string]any) error
+
+	// DeleteMulti removes the values for the given keys from the cache.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - keys: The keys of the values to delete
+	//
+	// Returns:
+	//   - An error if the operation fails
+	DeleteMulti(ctx context.Context, keys []string) error
+}
+
+// GetMulti retrieves the values for the given keys from the cache.
+// If the cache implements BatchCache its GetMulti method is used,
+// otherwise Get is called for every key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - cache: The cache to retrieve the values from
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys to their cached values
+//   - An error if the operation fails
+func GetMulti(ctx context.Context, cache Cache, keys []string) (map[string]any, error) {
+	// Prefer the native batch implementation
+	if batch, ok := cache.(BatchCache); ok {
+		return batch.GetMulti(ctx, keys)
+	}
+
+	// Fall back to one Get per key, skipping cache misses
+	vals := make(map[string]any, len(keys))
+	for _, key := range keys {
+		val, err := cache.Get(ctx, key)
+		if errors.Is(err, ErrCacheMiss) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		vals[key] = val
+	}
+	return vals, nil
+}
+
+// SetMulti stores every key/value pair of the given map in the cache.
+// If the cache implements BatchCache its SetMulti method is used,
+// otherwise Set is called for every pair.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - cache: The cache to store the values in
+//   - vals: The key/value pairs to store
+//
+// Returns:
+//   - An error if the operation fails
+func SetMulti(ctx context.Context, cache Cache, vals map[string]any) error {
+	// Prefer the native batch implementation
+	if batch, ok := cache.(BatchCache); ok {
+		return batch.SetMulti(ctx, vals)
+	}
+
+	// Fall back to one Set per pair
+	for key, val := range vals {
+		if err := cache.Set(ctx, key, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteMulti removes the values for the given keys from the cache.
+// If the cache implements BatchCache its DeleteMulti method is used,
+// otherwise Delete is called for every key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - cache: The cache to delete the values from
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func DeleteMulti(ctx context.Context, cache Cache, keys []string) error {
+	// Prefer the native batch implementation
+	if batch, ok := cache.(BatchCache); ok {
+		return batch.DeleteMulti(ctx, keys)
+	}
+
+	// Fall back to one Delete per key
+	for _, key := range keys {
+		if err := cache.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountMulti counts the keys found and the keys missing in the result of a
+// GetMulti, e.g. to report the hits and misses of a batch lookup.
+//
+// Parameters:
+//   - keys: The keys that were retrieved
+//   - vals: The values returned for the keys
+//
+// Returns:
+//   - The number of keys found in vals
+//   - The number of keys missing from vals
+func CountMulti(keys []string, vals map[string]any) (hits int, misses int) {
+	for _, key := range keys {
+		if _, ok := vals[key]; ok {
+			hits++
+		} else {
+			misses++
+		}
+	}
+	return hits, misses
+}
@@ -0,0 +1,85 @@
+package gouache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-leo/gouache"
+)
+
+// loopCache is a cache that doesn't implement gouache.BatchCache,
+// used to test the fallback of the batch helpers.
+type loopCache struct {
+	data map[string]any
+}
+
+func (c *loopCache) Get(ctx context.Context, key string) (any, error) {
+	if val, ok := c.data[key]; ok {
+		return val, nil
+	}
+	return nil, gouache.ErrCacheMiss
+}
+
+func (c *loopCache) Set(ctx context.Context, key string, val any) error {
+	c.data[key] = val
+	return nil
+}
+
+func (c *loopCache) Delete(ctx context.Context, key string) error {
+	delete(c.data, key)
+	return nil
+}
+
+// failingCache is a cache whose Get always fails.
+type failingCache struct {
+	loopCache
+}
+
+func (c *failingCache) Get(ctx context.Context, key string) (any, error) {
+	return nil, errors.New("intentional error")
+}
+
+// TestMulti_Fallback tests the batch helpers on a cache without native batch support.
+func TestMulti_Fallback(t *testing.T) {
+	cache := &loopCache{data: make(map[string]any)}
+	ctx := context.Background()
+
+	// SetMulti falls back to Set
+	if err := gouache.SetMulti(ctx, cache, map[string]any{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("Unexpected error when setting values: %v", err)
+	}
+
+	// GetMulti falls back to Get and skips cache misses
+	vals, err := gouache.GetMulti(ctx, cache, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("Unexpected error when getting values: %v", err)
+	}
+	if len(vals) != 2 || vals["a"] != 1 || vals["b"] != 2 {
+		t.Errorf("Expected a and b only, but got %v", vals)
+	}
+
+	// DeleteMulti falls back to Delete
+	if err := gouache.DeleteMulti(ctx, cache, []string{"a", "b"}); err != nil {
+		t.Fatalf("Unexpected error when deleting values: %v", err)
+	}
+	if len(cache.data) != 0 {
+		t.Errorf("Expected empty cache after deletion, but got %v", cache.data)
+	}
+}
+
+// TestGetMulti_Error tests that GetMulti propagates errors other than cache misses.
+func TestGetMulti_Error(t *testing.T) {
+	cache := &failingCache{loopCache{data: make(map[string]any)}}
+	if _, err := gouache.GetMulti(context.Background(), cache, []string{"a"}); err == nil {
+		t.Error("Expected an error but got none")
+	}
+}
+
+// TestCountMulti tests that every requested key is counted as a hit or a miss.
+func TestCountMulti(t *testing.T) {
+	hits, misses := gouache.CountMulti([]string{"a", "b", "c", "a"}, map[string]any{"a": 1, "c": nil})
+	if hits != 3 || misses != 1 {
+		t.Errorf("Expected 3 hits and 1 miss, got %d and %d", hits, misses)
+	}
+}
@@ -6,8 +6,12 @@
 package bigcache
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
+	"sync/atomic"
+	"time"
 
 	"github.com/allegro/bigcache/v3"
 	"github.com/go-leo/gouache"
@@ -16,24 +20,113 @@ import (
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.BatchCache interface at compile time.
+var _ gouache.BatchCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.StatsCache interface at compile time.
+var _ gouache.StatsCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.ScanCache interface at compile time.
+var _ gouache.ScanCache = (*Cache)(nil)
+
+// headerMagic starts the header prepended to every stored entry, its last
+// byte being the version of the header format. Entries not starting with it
+// are legacy entries, written before the header existed or directly to
+// BigCache, whose whole bytes are the data and which never expire.
+const headerMagic = "\xffgch\x01"
+
+// headerSize is the size of the header prepended to every stored entry:
+// headerMagic followed by the expiration time of the entry in Unix
+// nanoseconds, zero if the entry never expires.
+const headerSize = len(headerMagic) + 8
+
+// absentData is the data stored in BigCache for the gouache.Absent sentinel.
+var absentData = []byte("\x00gouache:absent\x00")
+
 // Cache is an implementation of gouache.Cache using BigCache as the storage backend.
 // It provides methods for storing, retrieving, and deleting cached values with
 // support for custom serialization and deserialization functions.
+//
+// BigCache only supports a global life window, so per-entry expiration set with
+// SetWithTTL is stored in a small header in front of every entry and checked on
+// read. An entry expires at the earlier of its own TTL and BigCache's life window.
 type Cache struct {
 	// Cache is the underlying Cache instance used for storage.
 	Cache *bigcache.BigCache
 
 	// Marshal is an optional function to serialize objects into bytes.
-	// If not provided, default type conversions are used for basic types.
+	// If not provided, the Codec or default type conversions for basic types are used.
 	Marshal func(key string, obj any) ([]byte, error)
 
 	// Unmarshal is an optional function to deserialize bytes into objects.
-	// If not provided, raw bytes are returned.
+	// If not provided, the Codec is used, or raw bytes are returned.
 	Unmarshal func(key string, data []byte) (any, error)
+
+	// Codec is an optional codec serializing every value, see the codec package
+	// for built-in codecs. The Marshal and Unmarshal functions take precedence.
+	Codec gouache.Codec
+
+	// counters counts the operations for Stats.
+	counters gouache.Counters
+
+	// tracked reports whether the cache was created with New, whose removal
+	// callback tracks the bytes and the evictions of BigCache.
+	tracked bool
+
+	// bytes is the size of the stored entries, tracked if the cache was
+	// created with New.
+	bytes atomic.Int64
+}
+
+// New creates a new cache backed by a BigCache created with the given config.
+// The BigCache reports every removed entry to the cache, so that Stats counts
+// the entries BigCache evicts because of its life window or maximum size, and
+// the size of the stored entries. The OnRemove or OnRemoveWithReason callback
+// of the config is still called for every removed entry. A filter set with
+// OnRemoveFilterSet applies to the tracking too, leave it unset for accurate
+// Stats.
+//
+// Parameters:
+//   - ctx: Context of the BigCache, cancelling it stops the cleanup of expired entries
+//   - config: The configuration of the BigCache, without OnRemoveWithMetadata
+//
+// Returns:
+//   - A cache whose Marshal, Unmarshal and Codec fields can be set before use
+//   - An error if the config sets OnRemoveWithMetadata, or the BigCache cannot be created
+func New(ctx context.Context, config bigcache.Config) (*Cache, error) {
+	// BigCache calls a single callback, and doesn't provide the metadata to
+	// the others
+	if config.OnRemoveWithMetadata != nil {
+		return nil, errors.New("gouache: OnRemoveWithMetadata is not supported")
+	}
+
+	// Replace the callback of the config with one chaining to it
+	cache := &Cache{tracked: true}
+	onRemove, onRemoveWithReason := config.OnRemove, config.OnRemoveWithReason
+	config.OnRemove = nil
+	config.OnRemoveWithReason = func(key string, entry []byte, reason bigcache.RemoveReason) {
+		cache.removed(entry, reason)
+		switch {
+		case onRemove != nil:
+			onRemove(key, entry)
+		case onRemoveWithReason != nil:
+			onRemoveWithReason(key, entry, reason)
+		}
+	}
+
+	bigCache, err := bigcache.New(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	cache.Cache = bigCache
+	return cache, nil
 }
 
 // Get retrieves a value from the cache by its key.
-// It returns gouache.ErrCacheMiss if the key does not exist.
+// It returns gouache.ErrCacheMiss if the key does not exist or has expired.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -43,74 +136,340 @@ type Cache struct {
 //   - The cached value or nil if not found
 //   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
 func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
-	// Attempt to get the value from BigCache
-	data, err := cache.Cache.Get(key)
+	val, _, err := cache.GetWithTTL(ctx, key)
+	return val, err
+}
 
-	// Handle case where entry is not found
-	if errors.Is(err, bigcache.ErrEntryNotFound) {
-		return nil, gouache.ErrCacheMiss
-	}
+// Set stores a value in the cache under the specified key.
+// It handles both raw byte slices and custom objects that require marshaling.
+// The entry only expires according to BigCache's life window.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store, either as []byte or any other type requiring marshaling
+//
+// Returns:
+//   - An error if the operation fails, including when Marshal is nil for non-byte values
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	return cache.SetWithTTL(ctx, key, val, 0)
+}
 
-	// Return other errors as-is
-	if err != nil {
-		return nil, err
+// Delete removes a value from the cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails, including bigcache.ErrEntryNotFound if key doesn't exist
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	// Delegate deletion to the underlying BigCache instance
+	err := cache.Cache.Delete(key)
+
+	// Count the requested key, whether it existed or not
+	if err == nil || errors.Is(err, bigcache.ErrEntryNotFound) {
+		cache.counters.AddDeletes(1)
 	}
+	return err
+}
 
-	// If no unmarshal function is defined, return raw data
-	if cache.Unmarshal == nil {
-		return data, nil
+// GetMulti retrieves the values for the given keys from the cache.
+// Keys that do not exist or have expired are omitted from the returned map.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys to their cached values
+//   - An error if the operation fails
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	vals := make(map[string]any, len(keys))
+	for _, key := range keys {
+		// Get every key, skipping the ones that do not exist
+		val, err := cache.Get(ctx, key)
+		if errors.Is(err, gouache.ErrCacheMiss) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		vals[key] = val
 	}
+	return vals, nil
+}
 
-	// Use custom unmarshal function to decode the data
-	obj, err := cache.Unmarshal(key, data)
-	if err != nil {
-		return nil, err
+// SetMulti stores every key/value pair of the given map in the cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The key/value pairs to store
+//
+// Returns:
+//   - An error if any of the values cannot be stored
+func (cache *Cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	for key, val := range vals {
+		if err := cache.Set(ctx, key, val); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	return obj, nil
+// DeleteMulti removes the values for the given keys from the cache.
+// Keys that do not exist are ignored.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		err := cache.Cache.Delete(key)
+		if err != nil && !errors.Is(err, bigcache.ErrEntryNotFound) {
+			return err
+		}
+		cache.counters.AddDeletes(1)
+	}
+	return nil
 }
 
-// Set stores a value in the cache under the specified key.
+// SetWithTTL stores a value in the cache under the specified key,
+// the entry expires once the ttl has passed.
 // It handles both raw byte slices and custom objects that require marshaling.
 //
 // Parameters:
 //   - ctx: Context for the operation
 //   - key: The key under which the value will be stored
 //   - val: The value to store, either as []byte or any other type requiring marshaling
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
 //
 // Returns:
 //   - An error if the operation fails, including when Marshal is nil for non-byte values
-func (cache *Cache) Set(ctx context.Context, key string, val any) error {
-	// Check if the value is already a byte slice
-	if data, ok := val.([]byte); ok {
-		// Directly store byte slices without marshaling
-		return cache.Cache.Set(key, data)
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	// Encode the value
+	data, err := cache.marshal(key, val)
+	if err != nil {
+		return err
 	}
 
-	// For non-byte values, ensure a marshal function is available
-	if cache.Marshal == nil {
-		return errors.New("gouache: Marshal is nil")
+	// Compute the expiration time, if any
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl).UnixNano()
 	}
 
-	// Marshal the value into bytes using the custom marshal function
-	data, err := cache.Marshal(key, val)
-	if err != nil {
+	// Prepend the header to the data
+	entry := make([]byte, headerSize+len(data))
+	copy(entry, headerMagic)
+	binary.BigEndian.PutUint64(entry[len(headerMagic):], uint64(expireAt))
+	copy(entry[headerSize:], data)
+
+	// Find the size of the entry being replaced, BigCache doesn't report
+	// overwritten entries to the removal callback
+	var replaced int
+	if cache.tracked {
+		if previous, err := cache.Cache.Get(key); err == nil {
+			replaced = len(previous)
+		}
+	}
+
+	// Store the entry in BigCache
+	if err := cache.Cache.Set(key, entry); err != nil {
 		return err
 	}
+	if cache.tracked {
+		cache.bytes.Add(int64(len(entry) - replaced))
+	}
+	cache.counters.AddSets(1)
+	return nil
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the cache.
+// It returns gouache.ErrCacheMiss if the key does not exist or has expired.
+// The remaining time-to-live doesn't take BigCache's life window into account.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	// Attempt to get the entry from BigCache
+	entry, err := cache.Cache.Get(key)
+
+	// Handle case where entry is not found
+	if errors.Is(err, bigcache.ErrEntryNotFound) {
+		cache.counters.AddMisses(1)
+		return nil, 0, gouache.ErrCacheMiss
+	}
 
-	// Store the marshaled data in BigCache
-	return cache.Cache.Set(key, data)
+	// Return other errors as-is
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Check the expiration time stored in the header, if any
+	data, expireAt := decodeEntry(entry)
+	var ttl time.Duration
+	if expireAt != 0 {
+		ttl = time.Until(time.Unix(0, expireAt))
+		if ttl <= 0 {
+			// Remove the expired entry
+			if cache.Cache.Delete(key) == nil {
+				cache.counters.AddEvictions(1)
+			}
+			cache.counters.AddMisses(1)
+			return nil, 0, gouache.ErrCacheMiss
+		}
+	}
+
+	// Decode the data following the header
+	obj, err := cache.unmarshal(key, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	cache.counters.AddHits(1)
+	return obj, ttl, nil
 }
 
-// Delete removes a value from the cache by its key.
+// Stats returns the counters of the cache, with the entries reported by
+// BigCache.
+//
+// The bytes and the evictions are only complete for a cache created with New:
+// the bytes are the size of the entries stored through the cache, headers
+// included, and the evictions count the entries BigCache removes because of
+// its life window or maximum size. Otherwise the bytes are unknown, BigCache
+// only reporting the capacity of its preallocated shards, and the evictions
+// only count the entries removed on read because their own TTL passed.
+// Concurrent writes of the same key may make the bytes drift slightly.
+//
+// Returns:
+//   - A snapshot of the counters
+func (cache *Cache) Stats() gouache.Stats {
+	stats := cache.counters.Stats()
+	stats.Entries = int64(cache.Cache.Len())
+	if cache.tracked {
+		// Entries written directly to BigCache are subtracted without being added
+		if stats.Bytes = cache.bytes.Load(); stats.Bytes < 0 {
+			stats.Bytes = 0
+		}
+	}
+	return stats
+}
+
+// removed is the removal callback of a BigCache created with New, it
+// subtracts the removed entry from the bytes and counts the evictions.
+//
+// Parameters:
+//   - entry: The removed entry, with its header
+//   - reason: Why BigCache removed the entry
+func (cache *Cache) removed(entry []byte, reason bigcache.RemoveReason) {
+	cache.bytes.Add(-int64(len(entry)))
+	if reason == bigcache.Expired || reason == bigcache.NoSpace {
+		cache.counters.AddEvictions(1)
+	}
+}
+
+// Scan calls fn for every key of the cache, skipping the entries whose own
+// TTL passed.
 //
 // Parameters:
 //   - ctx: Context for the operation
-//   - key: The key of the value to delete
+//   - fn: The function called with every key
 //
 // Returns:
-//   - An error if the operation fails
-func (cache *Cache) Delete(ctx context.Context, key string) error {
-	// Delegate deletion to the underlying BigCache instance
-	return cache.Cache.Delete(key)
+//   - The error returned by fn, or an error if the iteration fails
+func (cache *Cache) Scan(ctx context.Context, fn func(key string) error) error {
+	now := time.Now().UnixNano()
+	it := cache.Cache.Iterator()
+	for it.SetNext() {
+		info, err := it.Value()
+		if err != nil {
+			return err
+		}
+
+		// Skip the expired entries
+		if _, expireAt := decodeEntry(info.Value()); expireAt != 0 && expireAt <= now {
+			continue
+		}
+		if err := fn(info.Key()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeEntry splits an entry stored in BigCache into its data and its
+// expiration time. Legacy entries without a header are returned whole, and
+// never expire.
+//
+// Parameters:
+//   - entry: The entry stored in BigCache
+//
+// Returns:
+//   - The data following the header
+//   - The expiration time in Unix nanoseconds, zero if the entry never expires
+func decodeEntry(entry []byte) ([]byte, int64) {
+	if len(entry) < headerSize || string(entry[:len(headerMagic)]) != headerMagic {
+		return entry, 0
+	}
+	return entry[headerSize:], int64(binary.BigEndian.Uint64(entry[len(headerMagic):headerSize]))
+}
+
+// marshal encodes a value into the bytes stored in BigCache.
+// Byte slices are stored as-is, gouache.Absent as marker data,
+// other values require the Marshal function or the Codec.
+func (cache *Cache) marshal(key string, val any) ([]byte, error) {
+	// Store the absent sentinel as marker data
+	if gouache.IsAbsent(val) {
+		return absentData, nil
+	}
+
+	// Let the codec encode every value
+	if cache.Marshal == nil && cache.Codec != nil {
+		return cache.Codec.Marshal(key, val)
+	}
+
+	// Directly store byte slices without marshaling
+	if data, ok := val.([]byte); ok {
+		return data, nil
+	}
+
+	// For non-byte values, ensure a marshal function is available
+	if cache.Marshal == nil {
+		return nil, errors.New("gouache: Marshal is nil")
+	}
+
+	// Marshal the value into bytes using the custom marshal function
+	return cache.Marshal(key, val)
+}
+
+// unmarshal decodes the bytes stored in BigCache into a value.
+// The marker data is decoded as gouache.Absent, otherwise the raw bytes
+// are returned if neither an Unmarshal function nor a Codec is configured.
+func (cache *Cache) unmarshal(key string, data []byte) (any, error) {
+	// Restore the absent sentinel from its marker data
+	if bytes.Equal(data, absentData) {
+		return gouache.Absent, nil
+	}
+
+	// Let the codec decode every value
+	if cache.Unmarshal == nil && cache.Codec != nil {
+		return cache.Codec.Unmarshal(key, data)
+	}
+
+	// If no unmarshal function is defined, return raw data
+	if cache.Unmarshal == nil {
+		return data, nil
+	}
+
+	// Use custom unmarshal function to decode the data
+	return cache.Unmarshal(key, data)
 }
@@ -4,11 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/allegro/bigcache/v3"
 	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/codec"
 )
 
 // TestNewCache tests the creation of a new Cache instance
@@ -221,3 +224,306 @@ func TestCache_GetWithoutUnmarshal(t *testing.T) {
 		t.Errorf("Expected %v, got %v", string(value), string(result.([]byte)))
 	}
 }
+
+// TestCache_Multi tests GetMulti, SetMulti and DeleteMulti operations
+func TestCache_Multi(t *testing.T) {
+	config := bigcache.DefaultConfig(5 * time.Minute)
+	bigCache, err := bigcache.NewBigCache(config)
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+
+	cache := &Cache{
+		Cache: bigCache,
+	}
+
+	ctx := context.Background()
+
+	// Test SetMulti
+	err = cache.SetMulti(ctx, map[string]any{"key1": []byte("value1"), "key2": []byte("value2")})
+	if err != nil {
+		t.Errorf("Failed to set values: %v", err)
+	}
+
+	// Test GetMulti with a missing key
+	vals, err := cache.GetMulti(ctx, []string{"key1", "key2", "key3"})
+	if err != nil {
+		t.Errorf("Failed to get values: %v", err)
+	}
+	if len(vals) != 2 || string(vals["key1"].([]byte)) != "value1" || string(vals["key2"].([]byte)) != "value2" {
+		t.Errorf("Expected key1 and key2 only, got %v", vals)
+	}
+
+	// Test DeleteMulti, including a key that doesn't exist
+	err = cache.DeleteMulti(ctx, []string{"key1", "key2", "key3"})
+	if err != nil {
+		t.Errorf("Failed to delete values: %v", err)
+	}
+	if bigCache.Len() != 0 {
+		t.Errorf("Expected empty cache, got %d entries", bigCache.Len())
+	}
+}
+
+// TestCache_SetWithTTL tests SetWithTTL and GetWithTTL operations
+func TestCache_SetWithTTL(t *testing.T) {
+	config := bigcache.DefaultConfig(5 * time.Minute)
+	bigCache, err := bigcache.NewBigCache(config)
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+
+	cache := &Cache{
+		Cache: bigCache,
+	}
+
+	ctx := context.Background()
+
+	// Test SetWithTTL
+	err = cache.SetWithTTL(ctx, "key1", []byte("value1"), 20*time.Millisecond)
+	if err != nil {
+		t.Errorf("Failed to set value with TTL: %v", err)
+	}
+
+	// Test GetWithTTL before expiration
+	result, ttl, err := cache.GetWithTTL(ctx, "key1")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if string(result.([]byte)) != "value1" {
+		t.Errorf("Expected value1, got %v", string(result.([]byte)))
+	}
+	if ttl <= 0 || ttl > 20*time.Millisecond {
+		t.Errorf("Expected a remaining TTL within 20ms, got %v", ttl)
+	}
+
+	// Wait for expiration
+	time.Sleep(30 * time.Millisecond)
+
+	// Test Get after expiration
+	_, err = cache.Get(ctx, "key1")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss for expired key, got %v", err)
+	}
+}
+
+// TestCache_Absent tests that the gouache.Absent sentinel survives serialization
+func TestCache_Absent(t *testing.T) {
+	config := bigcache.DefaultConfig(5 * time.Minute)
+	bigCache, err := bigcache.NewBigCache(config)
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+
+	cache := &Cache{
+		Cache: bigCache,
+	}
+
+	ctx := context.Background()
+
+	// Set the absent sentinel without a Marshal function
+	err = cache.Set(ctx, "test-key", gouache.Absent)
+	if err != nil {
+		t.Errorf("Failed to set absent value: %v", err)
+	}
+
+	// Get the absent sentinel back
+	result, err := cache.Get(ctx, "test-key")
+	if err != nil {
+		t.Errorf("Failed to get value: %v", err)
+	}
+	if !gouache.IsAbsent(result) {
+		t.Errorf("Expected gouache.Absent, got %v", result)
+	}
+}
+
+// codecUser is the value type used by the codec test
+type codecUser struct {
+	Name string
+}
+
+// TestCache_Codec tests that the Codec round-trips the original type
+func TestCache_Codec(t *testing.T) {
+	config := bigcache.DefaultConfig(5 * time.Minute)
+	bigCache, err := bigcache.NewBigCache(config)
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+
+	registry := &codec.Registry{}
+	registry.Register("user", &codecUser{})
+	cacheImpl := &Cache{
+		Cache: bigCache,
+		Codec: registry,
+	}
+
+	ctx := context.Background()
+
+	// Set and get back a value of the registered type
+	if err := cacheImpl.Set(ctx, "user", &codecUser{Name: "alice"}); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	result, err := cacheImpl.Get(ctx, "user")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if u, ok := result.(*codecUser); !ok || u.Name != "alice" {
+		t.Errorf("Expected *codecUser alice, got %#v", result)
+	}
+
+	// The absent sentinel doesn't go through the codec
+	if err := cacheImpl.Set(ctx, "ghost", gouache.Absent); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if result, err := cacheImpl.Get(ctx, "ghost"); err != nil || !gouache.IsAbsent(result) {
+		t.Errorf("Expected gouache.Absent, got %v and %v", result, err)
+	}
+}
+
+// TestCache_Stats tests that the operations, evictions and entries are counted
+func TestCache_Stats(t *testing.T) {
+	config := bigcache.DefaultConfig(5 * time.Minute)
+	bigCache, err := bigcache.NewBigCache(config)
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+	cache := &Cache{Cache: bigCache}
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "key1", []byte("value1"))
+	_ = cache.SetWithTTL(ctx, "short", []byte("value2"), time.Millisecond)
+	_, _ = cache.Get(ctx, "key1")
+	_, _ = cache.GetMulti(ctx, []string{"key1", "missing"})
+
+	// The expired entry is a miss and an eviction
+	time.Sleep(5 * time.Millisecond)
+	_, _ = cache.Get(ctx, "short")
+	_ = cache.DeleteMulti(ctx, []string{"key1"})
+	_ = cache.Set(ctx, "key2", []byte("value3"))
+
+	// Deleting a missing key counts too
+	_ = cache.Delete(ctx, "missing")
+
+	stats := cache.Stats()
+	expected := gouache.Stats{Hits: 2, Misses: 2, Sets: 3, Deletes: 2, Evictions: 1, Entries: 1}
+	if stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+// TestCache_Scan tests that Scan lists the live keys and stops on the first error.
+func TestCache_Scan(t *testing.T) {
+	bigCache, err := bigcache.NewBigCache(bigcache.DefaultConfig(5 * time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+	cache := &Cache{Cache: bigCache}
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "a", []byte("a"))
+	_ = cache.Set(ctx, "b", []byte("b"))
+	_ = cache.SetWithTTL(ctx, "short", []byte("a"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	var keys []string
+	err = cache.Scan(ctx, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	sort.Strings(keys)
+	if err != nil || len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Expected keys [a b], got %v, %v", keys, err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = cache.Scan(ctx, func(key string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected Scan to stop with the error of fn, got %v after %d calls", err, calls)
+	}
+}
+
+// TestCache_LegacyEntries tests that entries without a header are read whole and never expire.
+func TestCache_LegacyEntries(t *testing.T) {
+	bigCache, err := bigcache.NewBigCache(bigcache.DefaultConfig(5 * time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+	cache := &Cache{Cache: bigCache}
+	ctx := context.Background()
+
+	// Entries written directly to BigCache, shorter and longer than the header
+	for key, val := range map[string]string{"short": "abc", "long": "a legacy value longer than the header"} {
+		if err := bigCache.Set(key, []byte(val)); err != nil {
+			t.Fatalf("Failed to set legacy entry: %v", err)
+		}
+		result, ttl, err := cache.GetWithTTL(ctx, key)
+		if err != nil || string(result.([]byte)) != val || ttl != 0 {
+			t.Errorf("Expected %q without expiration, got %v, %v, %v", val, result, ttl, err)
+		}
+	}
+
+	var keys []string
+	_ = cache.Scan(ctx, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if len(keys) != 2 {
+		t.Errorf("Expected the legacy keys to be scanned, got %v", keys)
+	}
+}
+
+// TestNew_Stats tests that a cache created with New tracks its bytes and the evictions of BigCache.
+func TestNew_Stats(t *testing.T) {
+	config := bigcache.DefaultConfig(5 * time.Minute)
+	config.Shards = 1
+	config.HardMaxCacheSize = 1
+	var removed []bigcache.RemoveReason
+	config.OnRemoveWithReason = func(key string, entry []byte, reason bigcache.RemoveReason) {
+		removed = append(removed, reason)
+	}
+	cache, err := New(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	// Overwriting a key replaces its bytes
+	value := make([]byte, 100*1024)
+	_ = cache.Set(ctx, "key", value)
+	_ = cache.Set(ctx, "key", value)
+	if stats := cache.Stats(); stats.Bytes != int64(headerSize+len(value)) {
+		t.Errorf("Expected %d bytes, got %d", headerSize+len(value), stats.Bytes)
+	}
+
+	// Filling the 1MB shard evicts the oldest entries
+	for i := 0; i < 20; i++ {
+		if err := cache.Set(ctx, fmt.Sprint(i), value); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	stats := cache.Stats()
+	if stats.Evictions == 0 {
+		t.Error("Expected evictions")
+	}
+	if stats.Bytes != stats.Entries*int64(headerSize+len(value)) {
+		t.Errorf("Expected %d bytes for %d entries, got %d", stats.Entries*int64(headerSize+len(value)), stats.Entries, stats.Bytes)
+	}
+	if len(removed) != int(stats.Evictions) {
+		t.Errorf("Expected the callback of the config for every eviction, got %v", removed)
+	}
+
+	// Deleting an entry subtracts its bytes
+	_ = cache.Delete(ctx, "19")
+	if after := cache.Stats(); after.Bytes != stats.Bytes-int64(headerSize+len(value)) || after.Evictions != stats.Evictions {
+		t.Errorf("Expected the deleted bytes to be subtracted, got %+v", after)
+	}
+
+	// The metadata callback can't be chained
+	config.OnRemoveWithMetadata = func(key string, entry []byte, keyMetadata bigcache.Metadata) {}
+	if _, err := New(context.Background(), config); err == nil {
+		t.Error("Expected an error")
+	}
+}
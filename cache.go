@@ -13,6 +13,11 @@ import (
 // does not exist in the cache.
 var ErrCacheMiss = errors.New("gouache: key not found")
 
+// ErrNotFound represents a missing record, returned by a Database when the
+// requested key does not exist in it. Unlike ErrCacheMiss, it means that the
+// value does not exist at all rather than that it is not cached.
+var ErrNotFound = errors.New("gouache: record not found")
+
 // Cache defines the basic operations for a cache implementation.
 type Cache interface {
 	// Get retrieves a value from the cache by its key.
@@ -52,6 +57,8 @@ type Cache interface {
 // Database defines the basic operations for a database implementation.
 type Database interface {
 	// Select retrieves a record from the database by its key.
+	// It should return ErrNotFound if the record does not exist, so that
+	// callers can remember the key as absent.
 	//
 	// Parameters:
 	//   - ctx: Context for the operation
@@ -59,7 +66,7 @@ type Database interface {
 	//
 	// Returns:
 	//   - The queried record or nil if not found
-	//   - An error if the operation fails
+	//   - An error if the operation fails, or ErrNotFound if the record doesn't exist
 	Select(ctx context.Context, key string) (any, error)
 
 	// Upsert inserts or updates a record in the database.
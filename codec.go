@@ -0,0 +1,29 @@
+package gouache
+
+// Codec is the interface for serializing values of caches that store bytes,
+// such as the redis and bigcache implementations.
+//
+// The codec package provides implementations for the standard encodings.
+type Codec interface {
+	// Marshal encodes a value into bytes.
+	//
+	// Parameters:
+	//   - key: The key under which the value will be stored
+	//   - val: The value to encode
+	//
+	// Returns:
+	//   - The encoded value
+	//   - An error if the value cannot be encoded
+	Marshal(key string, val any) ([]byte, error)
+
+	// Unmarshal decodes bytes into a value.
+	//
+	// Parameters:
+	//   - key: The key under which the value was stored
+	//   - data: The encoded value
+	//
+	// Returns:
+	//   - The decoded value
+	//   - An error if the data cannot be decoded
+	Unmarshal(key string, data []byte) (any, error)
+}
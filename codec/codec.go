@@ -0,0 +1,130 @@
+// Package codec provides gouache.Codec implementations based on the standard
+// library encodings.
+//
+// JSON and Gob encode any value, Raw and String store bytes and strings as-is,
+// Binary relies on encoding.BinaryMarshaler, and a Registry round-trips the
+// original Go type of the registered values.
+package codec
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-leo/gouache"
+)
+
+// Ensure that the codecs implement the gouache.Codec interface at compile time.
+var (
+	_ gouache.Codec = JSON{}
+	_ gouache.Codec = Gob{}
+	_ gouache.Codec = Raw{}
+	_ gouache.Codec = String{}
+	_ gouache.Codec = Binary{}
+)
+
+// JSON is a codec using encoding/json.
+// Values are decoded into the generic JSON types, e.g. map[string]any for
+// objects, use a Registry to get the original type back.
+type JSON struct{}
+
+// Marshal encodes a value as JSON.
+func (JSON) Marshal(key string, val any) ([]byte, error) {
+	return json.Marshal(val)
+}
+
+// Unmarshal decodes JSON into the generic JSON types.
+func (JSON) Unmarshal(key string, data []byte) (any, error) {
+	var val any
+	if err := json.Unmarshal(data, &val); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// Gob is a codec using encoding/gob.
+// Values are encoded as interface values, so their concrete types must be
+// registered with gob.Register, and are decoded into their original type.
+type Gob struct{}
+
+// Marshal encodes a value with gob.
+func (Gob) Marshal(key string, val any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&val); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a gob encoded value.
+func (Gob) Unmarshal(key string, data []byte) (any, error) {
+	var val any
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&val); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// Raw is a codec storing byte slices and strings as-is, and returning byte slices.
+type Raw struct{}
+
+// Marshal returns byte slices and strings as bytes.
+func (Raw) Marshal(key string, val any) ([]byte, error) {
+	switch val := val.(type) {
+	case []byte:
+		return val, nil
+	case string:
+		return []byte(val), nil
+	default:
+		return nil, fmt.Errorf("gouache: raw codec cannot encode %T", val)
+	}
+}
+
+// Unmarshal returns the bytes as-is.
+func (Raw) Unmarshal(key string, data []byte) (any, error) {
+	return data, nil
+}
+
+// String is a codec storing strings and byte slices as-is, and returning strings.
+type String struct{}
+
+// Marshal returns strings and byte slices as bytes.
+func (String) Marshal(key string, val any) ([]byte, error) {
+	return Raw{}.Marshal(key, val)
+}
+
+// Unmarshal returns the bytes as a string.
+func (String) Unmarshal(key string, data []byte) (any, error) {
+	return string(data), nil
+}
+
+// Binary is a codec for values implementing encoding.BinaryMarshaler.
+type Binary struct {
+	// New returns the value to decode the bytes into, e.g. new(time.Time).
+	New func(key string) encoding.BinaryUnmarshaler
+}
+
+// Marshal encodes a value with its MarshalBinary method.
+func (Binary) Marshal(key string, val any) ([]byte, error) {
+	marshaler, ok := val.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("gouache: %T does not implement encoding.BinaryMarshaler", val)
+	}
+	return marshaler.MarshalBinary()
+}
+
+// Unmarshal decodes the bytes into the value returned by New.
+// It returns an error if New is nil.
+func (c Binary) Unmarshal(key string, data []byte) (any, error) {
+	if c.New == nil {
+		return nil, errors.New("gouache: Binary.New is nil")
+	}
+	val := c.New(key)
+	if err := val.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
@@ -0,0 +1,118 @@
+package codec
+
+import (
+	"encoding"
+	"encoding/gob"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+)
+
+// user is the value type used by the codec tests.
+type user struct {
+	Name string
+	Age  int
+}
+
+func init() {
+	gob.Register(&user{})
+}
+
+// roundTrip encodes and decodes a value with a codec.
+func roundTrip(t *testing.T, c gouache.Codec, val any) any {
+	t.Helper()
+	data, err := c.Marshal("key", val)
+	if err != nil {
+		t.Fatalf("Unexpected error when marshaling: %v", err)
+	}
+	result, err := c.Unmarshal("key", data)
+	if err != nil {
+		t.Fatalf("Unexpected error when unmarshaling: %v", err)
+	}
+	return result
+}
+
+// TestJSON tests that JSON decodes into the generic JSON types.
+func TestJSON(t *testing.T) {
+	result := roundTrip(t, JSON{}, &user{Name: "alice", Age: 30})
+	expected := map[string]any{"Name": "alice", "Age": float64(30)}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+// TestGob tests that Gob decodes registered types into their original type.
+func TestGob(t *testing.T) {
+	result := roundTrip(t, Gob{}, &user{Name: "alice", Age: 30})
+	if u, ok := result.(*user); !ok || u.Name != "alice" || u.Age != 30 {
+		t.Errorf("Expected *user alice, got %#v", result)
+	}
+}
+
+// TestRaw tests that Raw and String store bytes and strings as-is.
+func TestRaw(t *testing.T) {
+	if result := roundTrip(t, Raw{}, "text"); !reflect.DeepEqual(result, []byte("text")) {
+		t.Errorf("Expected bytes, got %#v", result)
+	}
+	if result := roundTrip(t, String{}, []byte("text")); result != "text" {
+		t.Errorf("Expected a string, got %#v", result)
+	}
+	if _, err := (Raw{}).Marshal("key", 42); err == nil {
+		t.Error("Expected an error for an int")
+	}
+}
+
+// TestBinary tests that Binary uses the encoding.BinaryMarshaler methods.
+func TestBinary(t *testing.T) {
+	c := Binary{New: func(key string) encoding.BinaryUnmarshaler { return new(time.Time) }}
+	now := time.Now()
+	result := roundTrip(t, c, now)
+	if tm, ok := result.(*time.Time); !ok || !tm.Equal(now) {
+		t.Errorf("Expected %v, got %#v", now, result)
+	}
+	if _, err := c.Marshal("key", "text"); err == nil {
+		t.Error("Expected an error for a string")
+	}
+	if _, err := (Binary{}).Unmarshal("key", []byte("data")); err == nil {
+		t.Error("Expected an error without New")
+	}
+}
+
+// TestRegistry tests that the Registry round-trips the registered types.
+func TestRegistry(t *testing.T) {
+	registry := &Registry{}
+	registry.Register("user", &user{})
+	registry.Register("user-value", user{})
+
+	// Pointer types decode into pointers
+	result := roundTrip(t, registry, &user{Name: "alice", Age: 30})
+	if u, ok := result.(*user); !ok || u.Name != "alice" || u.Age != 30 {
+		t.Errorf("Expected *user alice, got %#v", result)
+	}
+
+	// Value types decode into values
+	result = roundTrip(t, registry, user{Name: "bob"})
+	if u, ok := result.(user); !ok || u.Name != "bob" {
+		t.Errorf("Expected user bob, got %#v", result)
+	}
+
+	// Unregistered types are rejected
+	if _, err := registry.Marshal("key", "text"); err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Errorf("Expected a not registered error, got %v", err)
+	}
+
+	// Unknown names are rejected
+	data, _ := registry.Marshal("key", &user{})
+	other := &Registry{}
+	if _, err := other.Unmarshal("key", data); err == nil {
+		t.Error("Expected an error for an unknown name")
+	}
+
+	// Truncated data is rejected
+	if _, err := registry.Unmarshal("key", data[:2]); err == nil {
+		t.Error("Expected an error for truncated data")
+	}
+}
@@ -0,0 +1,417 @@
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-leo/gouache"
+)
+
+// Ensure that Compressed implements the gouache.Codec interface at compile time.
+var _ gouache.Codec = (*Compressed)(nil)
+
+// Ensure that the built-in algorithms implement the LimitedDecompressor
+// interface at compile time.
+var (
+	_ LimitedDecompressor = Gzip{}
+	_ LimitedDecompressor = Zlib{}
+	_ LimitedDecompressor = Flate{}
+)
+
+// compressedMagic starts the header of the entries written by Compressed,
+// followed by the ID of their algorithm. Entries not starting with it are
+// stored uncompressed, e.g. written before the Compressed stage was enabled.
+const compressedMagic = "\xffgcz"
+
+// compressedHeaderSize is the size of the header of the entries written by
+// Compressed: compressedMagic followed by the ID byte.
+const compressedHeaderSize = len(compressedMagic) + 1
+
+// ID bytes of the entries written by Compressed.
+const (
+	// uncompressed marks an entry stored without compression, whose data
+	// would otherwise start with compressedMagic.
+	uncompressed byte = 0
+
+	// gzipID marks an entry compressed with Gzip.
+	gzipID byte = 1
+
+	// zlibID marks an entry compressed with Zlib.
+	zlibID byte = 2
+
+	// flateID marks an entry compressed with Flate.
+	flateID byte = 3
+
+	// reservedIDs is the number of header bytes reserved for the built-in
+	// algorithms, from 0 to 15.
+	reservedIDs = 16
+)
+
+// DefaultMaxSize is the maximum size in bytes of a decompressed entry when
+// Compressed.MaxSize is zero, 64 MiB.
+const DefaultMaxSize = 64 << 20
+
+// Compressor is a compression algorithm of the Compressed codec.
+type Compressor interface {
+	// ID identifies the algorithm in the header byte of the compressed entries.
+	// IDs 0 to 15 are reserved for the built-in algorithms.
+	ID() byte
+
+	// Compress compresses data.
+	Compress(data []byte) ([]byte, error)
+
+	// Decompress decompresses data compressed by Compress.
+	Decompress(data []byte) ([]byte, error)
+}
+
+// LimitedDecompressor is implemented by the Compressors able to stop
+// decompressing once the output exceeds a limit, e.g. to refuse compression
+// bombs before they are fully inflated in memory. The output of the other
+// Compressors is checked once decompressed.
+type LimitedDecompressor interface {
+	// DecompressLimit decompresses data compressed by Compress, and fails if
+	// the output exceeds limit bytes.
+	DecompressLimit(data []byte, limit int) ([]byte, error)
+}
+
+// Compressed is a codec stage compressing the output of another codec.
+//
+// Compressed entries start with a header, a magic prefix followed by the ID
+// of their algorithm, so that entries of different thresholds and algorithms
+// can coexist, e.g. while a new configuration is rolled out. Entries stored
+// uncompressed have no header, unless their data starts with the magic
+// prefix, so that the entries written before the Compressed stage was
+// enabled stay readable.
+// Custom Compressors must not use the IDs reserved for the built-in
+// algorithms, Marshal and Unmarshal fail otherwise.
+//
+//	c := &codec.Compressed{Codec: codec.JSON{}, Threshold: 1024}
+type Compressed struct {
+	// Codec encodes the values before compression, and decodes them after
+	// decompression.
+	Codec gouache.Codec
+
+	// Compressor compresses the entries, Gzip if not provided.
+	Compressor Compressor
+
+	// Threshold is the size in bytes below which entries are stored
+	// uncompressed. Entries that don't shrink are stored uncompressed as well.
+	Threshold int
+
+	// Decompressors are additional algorithms accepted when reading, besides
+	// the Compressor and the built-in algorithms.
+	Decompressors []Compressor
+
+	// MaxSize is the maximum size in bytes of a decompressed entry,
+	// DefaultMaxSize if zero. Larger entries fail to decode.
+	MaxSize int
+}
+
+// Marshal encodes a value with the Codec, and compresses it if it is at least
+// Threshold bytes long.
+//
+// Parameters:
+//   - key: The key under which the value will be stored
+//   - val: The value to encode
+//
+// Returns:
+//   - The header followed by the compressed data, or the uncompressed data
+//   - An error if the Codec is nil, a Compressor uses a reserved ID, or
+//     encoding or compressing fails
+func (c *Compressed) Marshal(key string, val any) ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	data, err := c.Codec.Marshal(key, val)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compress values large enough, and keep the result if it is smaller
+	if len(data) >= c.Threshold {
+		compressor := c.compressor()
+		compressed, err := compressor.Compress(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(compressed) < len(data) {
+			return withHeader(compressor.ID(), compressed), nil
+		}
+	}
+
+	// Uncompressed data mistakable for a compressed entry gets a header too
+	if bytes.HasPrefix(data, []byte(compressedMagic)) {
+		return withHeader(uncompressed, data), nil
+	}
+	return data, nil
+}
+
+// Unmarshal decompresses the data according to its header, if any, and
+// decodes it with the Codec. Data without header is decoded as it is.
+//
+// Parameters:
+//   - key: The key under which the value was stored
+//   - data: The header followed by the compressed data, or the uncompressed data
+//
+// Returns:
+//   - The decoded value
+//   - An error if the Codec is nil, a Compressor uses a reserved ID, the
+//     algorithm is unknown, the decompressed data exceeds MaxSize, or
+//     decompressing or decoding fails
+func (c *Compressed) Unmarshal(key string, data []byte) (any, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	// Data without header is stored uncompressed
+	if len(data) < compressedHeaderSize || string(data[:len(compressedMagic)]) != compressedMagic {
+		return c.Codec.Unmarshal(key, data)
+	}
+	id, payload := data[len(compressedMagic)], data[compressedHeaderSize:]
+
+	if id != uncompressed {
+		decompressor, err := c.decompressor(id)
+		if err != nil {
+			return nil, err
+		}
+		if payload, err = c.decompress(decompressor, payload); err != nil {
+			return nil, err
+		}
+	}
+	return c.Codec.Unmarshal(key, payload)
+}
+
+// withHeader prepends the header of an algorithm to data.
+func withHeader(id byte, data []byte) []byte {
+	entry := make([]byte, compressedHeaderSize+len(data))
+	copy(entry, compressedMagic)
+	entry[len(compressedMagic)] = id
+	copy(entry[compressedHeaderSize:], data)
+	return entry
+}
+
+// check verifies that the Codec is set and the custom Compressors don't use
+// a reserved ID.
+func (c *Compressed) check() error {
+	if c.Codec == nil {
+		return errors.New("gouache: Compressed.Codec is nil")
+	}
+	if err := checkID(c.Compressor); err != nil {
+		return err
+	}
+	for _, decompressor := range c.Decompressors {
+		if err := checkID(decompressor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkID verifies that a custom Compressor doesn't use a reserved ID.
+func checkID(compressor Compressor) error {
+	if compressor == nil || builtin(compressor) {
+		return nil
+	}
+	if id := compressor.ID(); id < reservedIDs {
+		return fmt.Errorf("gouache: compression %d is reserved, custom compressors must use IDs from %d", id, reservedIDs)
+	}
+	return nil
+}
+
+// decompress decompresses data with a decompressor, within MaxSize.
+func (c *Compressed) decompress(decompressor Compressor, data []byte) ([]byte, error) {
+	limit := c.MaxSize
+	if limit <= 0 {
+		limit = DefaultMaxSize
+	}
+	if limited, ok := decompressor.(LimitedDecompressor); ok {
+		return limited.DecompressLimit(data, limit)
+	}
+	data, err := decompressor.Decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, tooLarge(limit)
+	}
+	return data, nil
+}
+
+// builtin reports whether a Compressor is one of the built-in algorithms.
+func builtin(compressor Compressor) bool {
+	switch compressor.(type) {
+	case Gzip, *Gzip, Zlib, *Zlib, Flate, *Flate:
+		return true
+	default:
+		return false
+	}
+}
+
+// compressor returns the configured Compressor, or Gzip.
+func (c *Compressed) compressor() Compressor {
+	if c.Compressor == nil {
+		return Gzip{}
+	}
+	return c.Compressor
+}
+
+// decompressor finds the algorithm of a header byte.
+func (c *Compressed) decompressor(id byte) (Compressor, error) {
+	if compressor := c.compressor(); compressor.ID() == id {
+		return compressor, nil
+	}
+	for _, decompressor := range c.Decompressors {
+		if decompressor.ID() == id {
+			return decompressor, nil
+		}
+	}
+	switch id {
+	case gzipID:
+		return Gzip{}, nil
+	case zlibID:
+		return Zlib{}, nil
+	case flateID:
+		return Flate{}, nil
+	default:
+		return nil, fmt.Errorf("gouache: unknown compression %d", id)
+	}
+}
+
+// Gzip is a Compressor using compress/gzip.
+type Gzip struct {
+	// Level is the compression level, zero for gzip.DefaultCompression.
+	Level int
+}
+
+// ID returns the header byte of Gzip.
+func (Gzip) ID() byte {
+	return gzipID
+}
+
+// Compress compresses data with gzip.
+func (c Gzip) Compress(data []byte) ([]byte, error) {
+	return compress(data, func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, level(c.Level, gzip.DefaultCompression))
+	})
+}
+
+// Decompress decompresses gzip data, up to DefaultMaxSize bytes.
+func (c Gzip) Decompress(data []byte) ([]byte, error) {
+	return c.DecompressLimit(data, DefaultMaxSize)
+}
+
+// DecompressLimit decompresses gzip data, up to limit bytes.
+func (Gzip) DecompressLimit(data []byte, limit int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return decompress(r, limit)
+}
+
+// Zlib is a Compressor using compress/zlib.
+type Zlib struct {
+	// Level is the compression level, zero for zlib.DefaultCompression.
+	Level int
+}
+
+// ID returns the header byte of Zlib.
+func (Zlib) ID() byte {
+	return zlibID
+}
+
+// Compress compresses data with zlib.
+func (c Zlib) Compress(data []byte) ([]byte, error) {
+	return compress(data, func(w io.Writer) (io.WriteCloser, error) {
+		return zlib.NewWriterLevel(w, level(c.Level, zlib.DefaultCompression))
+	})
+}
+
+// Decompress decompresses zlib data, up to DefaultMaxSize bytes.
+func (c Zlib) Decompress(data []byte) ([]byte, error) {
+	return c.DecompressLimit(data, DefaultMaxSize)
+}
+
+// DecompressLimit decompresses zlib data, up to limit bytes.
+func (Zlib) DecompressLimit(data []byte, limit int) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return decompress(r, limit)
+}
+
+// Flate is a Compressor using compress/flate, without framing nor checksum.
+type Flate struct {
+	// Level is the compression level, zero for flate.DefaultCompression.
+	Level int
+}
+
+// ID returns the header byte of Flate.
+func (Flate) ID() byte {
+	return flateID
+}
+
+// Compress compresses data with flate.
+func (c Flate) Compress(data []byte) ([]byte, error) {
+	return compress(data, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, level(c.Level, flate.DefaultCompression))
+	})
+}
+
+// Decompress decompresses flate data, up to DefaultMaxSize bytes.
+func (c Flate) Decompress(data []byte) ([]byte, error) {
+	return c.DecompressLimit(data, DefaultMaxSize)
+}
+
+// DecompressLimit decompresses flate data, up to limit bytes.
+func (Flate) DecompressLimit(data []byte, limit int) ([]byte, error) {
+	return decompress(flate.NewReader(bytes.NewReader(data)), limit)
+}
+
+// compress writes data through a compressing writer.
+func compress(data []byte, newWriter func(w io.Writer) (io.WriteCloser, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress reads all data of a decompressing reader, and fails once it
+// exceeds limit bytes.
+func decompress(r io.ReadCloser, limit int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		_ = r.Close()
+		return nil, tooLarge(limit)
+	}
+	return data, r.Close()
+}
+
+// tooLarge returns the error of a decompressed entry exceeding limit bytes.
+func tooLarge(limit int) error {
+	return fmt.Errorf("gouache: decompressed entry exceeds %d bytes", limit)
+}
+
+// level returns the compression level, or the default one for zero.
+func level(level int, defaultLevel int) int {
+	if level == 0 {
+		return defaultLevel
+	}
+	return level
+}
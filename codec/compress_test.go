@@ -0,0 +1,180 @@
+package codec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// custom is a custom Compressor used by the tests, flate under another ID.
+type custom struct {
+	Flate
+}
+
+func (custom) ID() byte { return 42 }
+
+// TestCompressed tests that every built-in algorithm round-trips large values.
+func TestCompressed(t *testing.T) {
+	large := strings.Repeat("cached html fragment ", 100)
+	for _, compressor := range []Compressor{Gzip{}, Zlib{}, Flate{}, Gzip{Level: 9}} {
+		c := &Compressed{Codec: String{}, Compressor: compressor}
+		data, err := c.Marshal("key", large)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !bytes.HasPrefix(data, []byte(compressedMagic)) || data[len(compressedMagic)] != compressor.ID() {
+			t.Errorf("Expected header %d, got %v", compressor.ID(), data[:compressedHeaderSize])
+		}
+		if len(data) >= len(large) {
+			t.Errorf("Expected the data to be compressed, got %d bytes", len(data))
+		}
+		if result, err := c.Unmarshal("key", data); err != nil || result != large {
+			t.Errorf("Expected the value back, got an error %v", err)
+		}
+	}
+}
+
+// TestCompressed_Threshold tests that small and incompressible values are stored uncompressed.
+func TestCompressed_Threshold(t *testing.T) {
+	c := &Compressed{Codec: String{}, Threshold: 100}
+
+	// Below the threshold
+	data, err := c.Marshal("key", strings.Repeat("a", 99))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, bytes.Repeat([]byte("a"), 99)) {
+		t.Errorf("Expected an uncompressed entry without header, got %v", data)
+	}
+
+	// Above the threshold, but not shrinking
+	incompressible := make([]byte, 200)
+	for i := range incompressible {
+		incompressible[i] = byte(i * 7919 >> 3)
+	}
+	c.Codec = Raw{}
+	data, err = c.Marshal("key", incompressible)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(data) > len(incompressible) {
+		t.Errorf("Expected no overhead, got %d bytes", len(data))
+	}
+}
+
+// TestCompressed_Coexistence tests that entries of other configurations stay readable.
+func TestCompressed_Coexistence(t *testing.T) {
+	large := strings.Repeat("a", 1000)
+	old := &Compressed{Codec: String{}, Compressor: Zlib{}}
+	current := &Compressed{Codec: String{}, Compressor: custom{}, Threshold: 10}
+	small := &Compressed{Codec: String{}, Threshold: 10000}
+
+	// Built-in algorithms and uncompressed entries are always readable
+	for _, writer := range []*Compressed{old, small} {
+		data, err := writer.Marshal("key", large)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result, err := current.Unmarshal("key", data); err != nil || result != large {
+			t.Errorf("Expected the value back, got an error %v", err)
+		}
+	}
+
+	// Custom algorithms must be known to the reader
+	data, err := current.Marshal("key", large)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := old.Unmarshal("key", data); err == nil {
+		t.Error("Expected an error for an unknown algorithm")
+	}
+	old.Decompressors = []Compressor{custom{}}
+	if result, err := old.Unmarshal("key", data); err != nil || result != large {
+		t.Errorf("Expected the value back, got an error %v", err)
+	}
+}
+
+// TestCompressed_Uncompressed tests that entries written before the Compressed stage was enabled stay readable.
+func TestCompressed_Uncompressed(t *testing.T) {
+	c := &Compressed{Codec: JSON{}, Threshold: 10}
+
+	// A JSON payload stored without the Compressed stage
+	result, err := c.Unmarshal("key", []byte(`{"name":"alice"}`))
+	if m, ok := result.(map[string]any); err != nil || !ok || m["name"] != "alice" {
+		t.Errorf("Expected the uncompressed payload, got %v and %v", result, err)
+	}
+
+	// Uncompressed data starting with the magic prefix round-trips
+	raw := &Compressed{Codec: Raw{}, Threshold: 1000}
+	ambiguous := []byte(compressedMagic + "\x01payload")
+	data, err := raw.Marshal("key", ambiguous)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result, err := raw.Unmarshal("key", data); err != nil || !bytes.Equal(result.([]byte), ambiguous) {
+		t.Errorf("Expected the ambiguous data back, got %v and %v", result, err)
+	}
+
+	// A nil Codec fails instead of panicking
+	if _, err := (&Compressed{}).Marshal("key", "value"); err == nil {
+		t.Error("Expected an error without Codec")
+	}
+	if _, err := (&Compressed{}).Unmarshal("key", []byte("value")); err == nil {
+		t.Error("Expected an error without Codec")
+	}
+}
+
+// reserved is a custom Compressor using an ID reserved for the built-in algorithms.
+type reserved struct {
+	Flate
+	id byte
+}
+
+func (c reserved) ID() byte { return c.id }
+
+// unlimited is a custom Compressor without LimitedDecompressor support.
+type unlimited struct {
+	c Compressor
+}
+
+func (u unlimited) ID() byte                               { return 43 }
+func (u unlimited) Compress(data []byte) ([]byte, error)   { return u.c.Compress(data) }
+func (u unlimited) Decompress(data []byte) ([]byte, error) { return u.c.Decompress(data) }
+
+// TestCompressed_ReservedIDs tests that custom compressors can't use the reserved IDs.
+func TestCompressed_ReservedIDs(t *testing.T) {
+	for _, id := range []byte{uncompressed, gzipID, 15} {
+		c := &Compressed{Codec: String{}, Compressor: reserved{id: id}}
+		if _, err := c.Marshal("key", "value"); err == nil {
+			t.Errorf("Expected an error for the reserved ID %d", id)
+		}
+		c = &Compressed{Codec: String{}, Decompressors: []Compressor{reserved{id: id}}}
+		if _, err := c.Unmarshal("key", []byte{uncompressed}); err == nil {
+			t.Errorf("Expected an error for the reserved ID %d", id)
+		}
+	}
+	c := &Compressed{Codec: String{}, Compressor: &Gzip{}, Decompressors: []Compressor{custom{}}}
+	if _, err := c.Marshal("key", "value"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// TestCompressed_MaxSize tests that entries decompressing beyond MaxSize are rejected.
+func TestCompressed_MaxSize(t *testing.T) {
+	large := strings.Repeat("a", 1000)
+	for _, compressor := range []Compressor{Gzip{}, Zlib{}, Flate{}, custom{}, unlimited{c: Gzip{}}} {
+		writer := &Compressed{Codec: String{}, Compressor: compressor}
+		data, err := writer.Marshal("key", large)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		reader := &Compressed{Codec: String{}, Compressor: compressor, MaxSize: len(large)}
+		if result, err := reader.Unmarshal("key", data); err != nil || result != large {
+			t.Errorf("Expected the value back within MaxSize, got an error %v", err)
+		}
+		reader.MaxSize = len(large) - 1
+		if _, err := reader.Unmarshal("key", data); err == nil {
+			t.Errorf("Expected an error beyond MaxSize for %T", compressor)
+		}
+	}
+}
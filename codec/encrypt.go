@@ -0,0 +1,208 @@
+package codec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-leo/gouache"
+)
+
+// Ensure that Encrypted implements the gouache.Codec interface at compile time.
+var _ gouache.Codec = (*Encrypted)(nil)
+
+// keyIDSize is the size of the key ID stored in front of every ciphertext.
+const keyIDSize = 4
+
+// Encrypted is a codec stage encrypting the output of another codec with
+// AES-GCM.
+//
+// A value is stored as the big-endian ID of the key that encrypted it, the
+// nonce, and the sealed data. The cache key is bound as associated data, so a
+// value copied under another key fails to decrypt. Compression must happen
+// before encryption:
+//
+//	keyring := &codec.Keyring{}
+//	_ = keyring.Add(1, key)
+//	_ = keyring.Use(1)
+//	c := &codec.Encrypted{Codec: &codec.Compressed{Codec: codec.JSON{}}, Keyring: keyring}
+type Encrypted struct {
+	// Codec encodes the values before encryption, and decodes them after
+	// decryption.
+	Codec gouache.Codec
+
+	// Keyring holds the encryption keys.
+	Keyring *Keyring
+}
+
+// Marshal encodes a value with the Codec, and encrypts it with the current
+// key of the Keyring.
+//
+// Parameters:
+//   - key: The key under which the value will be stored, bound as associated data
+//   - val: The value to encode
+//
+// Returns:
+//   - The key ID, nonce and ciphertext
+//   - An error if encoding fails or the Keyring has no current key
+func (c *Encrypted) Marshal(key string, val any) ([]byte, error) {
+	data, err := c.Codec.Marshal(key, val)
+	if err != nil {
+		return nil, err
+	}
+
+	id, aead, err := c.Keyring.current()
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the key ID and a random nonce, then append the sealed data
+	out := make([]byte, keyIDSize+aead.NonceSize(), keyIDSize+aead.NonceSize()+len(data)+aead.Overhead())
+	binary.BigEndian.PutUint32(out, id)
+	nonce := out[keyIDSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, data, []byte(key)), nil
+}
+
+// Unmarshal decrypts the data with the key of the embedded key ID, and decodes
+// it with the Codec.
+//
+// Parameters:
+//   - key: The key under which the value was stored, bound as associated data
+//   - data: The key ID, nonce and ciphertext
+//
+// Returns:
+//   - The decoded value
+//   - An error if the key ID is unknown, authentication fails or decoding fails
+func (c *Encrypted) Unmarshal(key string, data []byte) (any, error) {
+	if len(data) < keyIDSize {
+		return nil, errors.New("gouache: invalid encrypted data")
+	}
+
+	aead, err := c.Keyring.get(binary.BigEndian.Uint32(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Split the nonce from the sealed data, and open it
+	data = data[keyIDSize:]
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("gouache: invalid encrypted data")
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return c.Codec.Unmarshal(key, plain)
+}
+
+// Keyring holds the AES keys of the Encrypted codec by ID.
+//
+// Keys are rotated by adding the new key to every process first, and then
+// using it: values encrypted with older keys stay readable as long as their
+// keys are in the Keyring.
+//
+// The zero value is an empty keyring ready to use.
+type Keyring struct {
+	// mu guards the fields below
+	mu sync.RWMutex
+
+	// keys holds the ciphers by key ID
+	keys map[uint32]cipher.AEAD
+
+	// id is the ID of the current key
+	id uint32
+
+	// ok tells whether a current key is set
+	ok bool
+}
+
+// Add adds a key to the keyring, replacing the key of the same ID if any.
+//
+// Parameters:
+//   - id: The ID of the key, stored in front of every ciphertext
+//   - key: The AES key, 16, 24 or 32 bytes long
+//
+// Returns:
+//   - An error if the key has an invalid size
+func (keyring *Keyring) Add(id uint32, key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+	if keyring.keys == nil {
+		keyring.keys = make(map[uint32]cipher.AEAD)
+	}
+	keyring.keys[id] = aead
+	return nil
+}
+
+// Use makes a key the current key, encrypting every new value.
+//
+// Parameters:
+//   - id: The ID of a key added to the keyring
+//
+// Returns:
+//   - An error if the key ID is unknown
+func (keyring *Keyring) Use(id uint32) error {
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+	if _, ok := keyring.keys[id]; !ok {
+		return fmt.Errorf("gouache: unknown encryption key %d", id)
+	}
+	keyring.id, keyring.ok = id, true
+	return nil
+}
+
+// Remove removes a key from the keyring, values encrypted with it can no
+// longer be decrypted. The current key cannot be removed.
+//
+// Parameters:
+//   - id: The ID of the key to remove
+//
+// Returns:
+//   - An error if the key is the current key
+func (keyring *Keyring) Remove(id uint32) error {
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+	if keyring.ok && keyring.id == id {
+		return fmt.Errorf("gouache: encryption key %d is in use", id)
+	}
+	delete(keyring.keys, id)
+	return nil
+}
+
+// current returns the current key and its ID.
+func (keyring *Keyring) current() (uint32, cipher.AEAD, error) {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	if !keyring.ok {
+		return 0, nil, errors.New("gouache: no current encryption key")
+	}
+	return keyring.id, keyring.keys[keyring.id], nil
+}
+
+// get returns the key of an ID.
+func (keyring *Keyring) get(id uint32) (cipher.AEAD, error) {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	aead, ok := keyring.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("gouache: unknown encryption key %d", id)
+	}
+	return aead, nil
+}
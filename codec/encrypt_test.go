@@ -0,0 +1,104 @@
+package codec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// newKeyring returns a keyring using a key of the given ID.
+func newKeyring(t *testing.T, id uint32) *Keyring {
+	t.Helper()
+	keyring := &Keyring{}
+	if err := keyring.Add(id, bytes.Repeat([]byte{byte(id)}, 32)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := keyring.Use(id); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return keyring
+}
+
+// TestEncrypted tests that values round-trip and are not stored in plaintext.
+func TestEncrypted(t *testing.T) {
+	c := &Encrypted{Codec: String{}, Keyring: newKeyring(t, 1)}
+	data, err := c.Marshal("user:1", "alice@example.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if bytes.Contains(data, []byte("alice")) {
+		t.Error("Expected the value to be encrypted")
+	}
+	if result, err := c.Unmarshal("user:1", data); err != nil || result != "alice@example.com" {
+		t.Errorf("Expected the value back, got %v, %v", result, err)
+	}
+
+	// Values cannot be moved to another key
+	if _, err := c.Unmarshal("user:2", data); err == nil {
+		t.Error("Expected an error for another key")
+	}
+
+	// Tampered and truncated data is rejected
+	data[len(data)-1] ^= 1
+	if _, err := c.Unmarshal("user:1", data); err == nil {
+		t.Error("Expected an error for tampered data")
+	}
+	if _, err := c.Unmarshal("user:1", data[:10]); err == nil {
+		t.Error("Expected an error for truncated data")
+	}
+}
+
+// TestEncrypted_Rotation tests that values of old keys stay readable after a rotation.
+func TestEncrypted_Rotation(t *testing.T) {
+	keyring := newKeyring(t, 1)
+	c := &Encrypted{Codec: String{}, Keyring: keyring}
+	old, err := c.Marshal("key", "old")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Rotate to a new key
+	if err := keyring.Add(2, bytes.Repeat([]byte{2}, 16)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := keyring.Use(2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	current, err := c.Marshal("key", "current")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(current[:keyIDSize], []byte{0, 0, 0, 2}) {
+		t.Errorf("Expected the new key ID, got %v", current[:keyIDSize])
+	}
+	for data, expected := range map[string]string{string(old): "old", string(current): "current"} {
+		if result, err := c.Unmarshal("key", []byte(data)); err != nil || result != expected {
+			t.Errorf("Expected %s, got %v, %v", expected, result, err)
+		}
+	}
+
+	// The current key cannot be removed, old keys can
+	if err := keyring.Remove(2); err == nil {
+		t.Error("Expected an error when removing the current key")
+	}
+	if err := keyring.Remove(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := c.Unmarshal("key", old); err == nil || !strings.Contains(err.Error(), "unknown encryption key") {
+		t.Errorf("Expected an unknown key error, got %v", err)
+	}
+}
+
+// TestEncrypted_NoKey tests that encryption fails without a current key.
+func TestEncrypted_NoKey(t *testing.T) {
+	c := &Encrypted{Codec: String{}, Keyring: &Keyring{}}
+	if _, err := c.Marshal("key", "value"); err == nil {
+		t.Error("Expected an error without a current key")
+	}
+	if err := c.Keyring.Use(1); err == nil {
+		t.Error("Expected an error for an unknown key")
+	}
+	if err := c.Keyring.Add(1, []byte("short")); err == nil {
+		t.Error("Expected an error for an invalid key size")
+	}
+}
@@ -0,0 +1,151 @@
+package codec
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/go-leo/gouache"
+)
+
+// Ensure that Registry implements the gouache.Codec interface at compile time.
+var _ gouache.Codec = (*Registry)(nil)
+
+// Registry is a codec that round-trips the original Go type of values.
+//
+// Every type is registered under a stable name, which is stored in front of
+// the encoded value, so that Get returns a *User rather than the generic
+// map[string]any of a plain JSON codec:
+//
+//	registry := &codec.Registry{}
+//	registry.Register("user", &User{})
+//	cache := &redis.Cache{Cache: rdb, Codec: registry}
+//
+// A value is stored as the uvarint length of the name, the name, and the
+// value encoded by the Codec, JSON if not provided.
+//
+// The zero value is an empty registry ready to use.
+type Registry struct {
+	// Codec encodes the values, it must decode into a pointer of the given
+	// type, as json.Unmarshal does. If not provided, encoding/json is used.
+	Codec interface {
+		Marshal(v any) ([]byte, error)
+		Unmarshal(data []byte, v any) error
+	}
+
+	// mu guards the maps
+	mu sync.RWMutex
+
+	// types holds the registered types by name
+	types map[string]reflect.Type
+
+	// names holds the registered names by type
+	names map[reflect.Type]string
+}
+
+// Register registers the type of a value under a name. The same name must be
+// used by every process sharing the cache. A pointer type decodes into a new
+// pointer, a value type into a value.
+//
+// Parameters:
+//   - name: The stable name of the type
+//   - val: A value of the type, e.g. &User{} or User{}
+func (registry *Registry) Register(name string, val any) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if registry.types == nil {
+		registry.types = make(map[string]reflect.Type)
+		registry.names = make(map[reflect.Type]string)
+	}
+	typ := reflect.TypeOf(val)
+	registry.types[name] = typ
+	registry.names[typ] = name
+}
+
+// Marshal encodes a value of a registered type together with its name.
+//
+// Parameters:
+//   - key: The key under which the value will be stored
+//   - val: The value to encode
+//
+// Returns:
+//   - The encoded value
+//   - An error if the type is not registered or the value cannot be encoded
+func (registry *Registry) Marshal(key string, val any) ([]byte, error) {
+	registry.mu.RLock()
+	name, ok := registry.names[reflect.TypeOf(val)]
+	registry.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("gouache: type %T is not registered", val)
+	}
+
+	payload, err := registry.marshal(val)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepend the length-prefixed name
+	data := make([]byte, 0, binary.MaxVarintLen64+len(name)+len(payload))
+	data = binary.AppendUvarint(data, uint64(len(name)))
+	data = append(data, name...)
+	return append(data, payload...), nil
+}
+
+// Unmarshal decodes a value into the type registered under the stored name.
+//
+// Parameters:
+//   - key: The key under which the value was stored
+//   - data: The encoded value
+//
+// Returns:
+//   - The decoded value, of the registered type
+//   - An error if the name is not registered or the data cannot be decoded
+func (registry *Registry) Unmarshal(key string, data []byte) (any, error) {
+	// Read the length-prefixed name
+	size, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < size {
+		return nil, errors.New("gouache: invalid registry codec data")
+	}
+	name := string(data[n : n+int(size)])
+	payload := data[n+int(size):]
+
+	registry.mu.RLock()
+	typ, ok := registry.types[name]
+	registry.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("gouache: type name %q is not registered", name)
+	}
+
+	// Decode into a new value of the registered type
+	if typ.Kind() == reflect.Pointer {
+		ptr := reflect.New(typ.Elem())
+		if err := registry.unmarshal(payload, ptr.Interface()); err != nil {
+			return nil, err
+		}
+		return ptr.Interface(), nil
+	}
+	ptr := reflect.New(typ)
+	if err := registry.unmarshal(payload, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// marshal encodes a value with the Codec, or JSON.
+func (registry *Registry) marshal(val any) ([]byte, error) {
+	if registry.Codec != nil {
+		return registry.Codec.Marshal(val)
+	}
+	return json.Marshal(val)
+}
+
+// unmarshal decodes data into v with the Codec, or JSON.
+func (registry *Registry) unmarshal(data []byte, v any) error {
+	if registry.Codec != nil {
+		return registry.Codec.Unmarshal(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
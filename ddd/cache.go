@@ -19,6 +19,9 @@ import (
 // Ensure that cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*cache)(nil)
 
+// Ensure that cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*cache)(nil)
+
 // Gopher is a function type that executes a given function asynchronously.
 // It's used to run delayed operations in the background.
 type Gopher func(f func()) error
@@ -36,6 +39,13 @@ type options struct {
 
 	// Gopher is responsible for executing functions asynchronously.
 	Gopher Gopher
+
+	// TTL determines the time-to-live of the entries populated from the database.
+	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
+
+	// NegativeTTL is the time-to-live of the gouache.Absent entries remembering
+	// keys the database doesn't have, zero disables negative caching.
+	NegativeTTL time.Duration
 }
 
 // Option is a function that modifies the cache options.
@@ -97,6 +107,38 @@ func WithGopher(gopher Gopher) Option {
 	}
 }
 
+// WithTTL returns an Option that sets a function to determine the
+// time-to-live of the cache entries populated from the database on a miss.
+// If not set, the entries are stored with the underlying cache's Set and
+// its own expiration policy applies.
+//
+// Parameters:
+//   - ttl: A function that returns the time-to-live for a key and value
+//
+// Returns:
+//   - An Option function that sets the TTL
+func WithTTL(ttl func(ctx context.Context, key string, val any) (time.Duration, error)) Option {
+	return func(o *options) {
+		o.TTL = ttl
+	}
+}
+
+// WithNegativeTTL returns an Option that enables negative caching.
+// When the database returns gouache.ErrNotFound for a key, gouache.Absent is
+// stored in the cache for the given duration, and lookups of the key return
+// gouache.ErrNotFound without reaching the database until it expires.
+//
+// Parameters:
+//   - dur: The time-to-live of absent entries, zero or less to disable negative caching
+//
+// Returns:
+//   - An Option function that sets the NegativeTTL
+func WithNegativeTTL(dur time.Duration) Option {
+	return func(o *options) {
+		o.NegativeTTL = dur
+	}
+}
+
 // newOptions creates a new options instance with default values and applies
 // the provided options.
 //
@@ -183,6 +225,21 @@ func New(c gouache.Cache, d gouache.Database, opts ...Option) gouache.Cache {
 	return &cache{Options: newOptions(opts...), Cache: c, Database: d}
 }
 
+// Middleware returns a gouache.Middleware keeping the next cache consistent
+// with a database using the delay double delete pattern.
+//
+// Parameters:
+//   - d: The underlying database implementation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A middleware wrapping the next cache with New
+func Middleware(d gouache.Database, opts ...Option) gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		return New(next, d, opts...)
+	}
+}
+
 // Get retrieves a value from the cache by its key. If the value is not found
 // in the cache, it attempts to retrieve it from the database and populate
 // the cache with the result.
@@ -193,25 +250,26 @@ func New(c gouache.Cache, d gouache.Database, opts ...Option) gouache.Cache {
 //
 // Returns:
 //   - The cached or database value or nil if not found
-//   - An error if the operation fails
+//   - An error if the operation fails, or gouache.ErrNotFound if the key is
+//     known to be absent from the database
 func (cache *cache) Get(ctx context.Context, key string) (any, error) {
 	// Try to get the value from cache first
 	val, err := cache.Cache.Get(ctx, key)
 
 	// If cache miss, try to get from database
 	if errors.Is(err, gouache.ErrCacheMiss) {
-		// Get value from database
-		val, err := cache.Database.Select(ctx, key)
-		if err != nil {
-			return nil, err
-		}
-
-		// Populate cache with database value
-		return val, cache.Cache.Set(ctx, key, val)
+		val, _, err := cache.load(ctx, key)
+		return val, err
 	}
 
 	// Return cache value or error
-	return val, err
+	if err != nil {
+		return nil, err
+	}
+	if gouache.IsAbsent(val) {
+		return nil, gouache.ErrNotFound
+	}
+	return val, nil
 }
 
 // Set stores a value in both the cache and database. It first deletes the
@@ -294,3 +352,120 @@ func (cache *cache) Delete(ctx context.Context, key string) error {
 		}
 	})
 }
+
+// SetWithTTL stores a value in the database exactly like Set does.
+//
+// The delay double delete pattern invalidates the cache entry instead of
+// writing the new value into it, so there is no entry the ttl could apply to.
+// The time-to-live of the entry populated by the next Get is determined by
+// the WithTTL option.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: Not used, see above
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	return cache.Set(ctx, key, val)
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the cache.
+// If the value is not found in the cache, it attempts to retrieve it from
+// the database and populates the cache with the TTL given by WithTTL.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached or database value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if the operation fails, or gouache.ErrNotFound if the key is
+//     known to be absent from the database
+func (cache *cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	// Try to get the value from cache first
+	val, ttl, err := gouache.GetWithTTL(ctx, cache.Cache, key)
+
+	// If cache miss, try to get from database
+	if errors.Is(err, gouache.ErrCacheMiss) {
+		return cache.load(ctx, key)
+	}
+
+	// Return cache value or error
+	if err != nil {
+		return nil, 0, err
+	}
+	if gouache.IsAbsent(val) {
+		return nil, 0, gouache.ErrNotFound
+	}
+	return val, ttl, nil
+}
+
+// load retrieves a value from the database and populates the cache with it.
+// If the database doesn't have the key and negative caching is enabled,
+// gouache.Absent is stored in the cache instead.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The database value or nil if not found
+//   - The time-to-live the entry was stored with, zero if none
+//   - An error if the operation fails, or gouache.ErrNotFound if the key
+//     doesn't exist in the database
+func (cache *cache) load(ctx context.Context, key string) (any, time.Duration, error) {
+	// Get value from database
+	val, err := cache.Database.Select(ctx, key)
+
+	// Remember absent keys so that they don't reach the database again
+	if errors.Is(err, gouache.ErrNotFound) && cache.Options.NegativeTTL > 0 {
+		if setErr := gouache.SetWithTTL(ctx, cache.Cache, key, gouache.Absent, cache.Options.NegativeTTL); setErr != nil {
+			return nil, 0, setErr
+		}
+		return nil, 0, err
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Populate cache with database value
+	ttl, err := cache.fill(ctx, key, val)
+	return val, ttl, err
+}
+
+// fill populates the cache with a value loaded from the database, using the
+// time-to-live returned by the TTL option if it is set.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value loaded from the database
+//
+// Returns:
+//   - The time-to-live the entry was stored with, zero if none
+//   - An error if the operation fails
+func (cache *cache) fill(ctx context.Context, key string, val any) (time.Duration, error) {
+	// Without a TTL option, let the cache apply its own expiration policy
+	if cache.Options.TTL == nil {
+		return 0, cache.Cache.Set(ctx, key, val)
+	}
+
+	// Determine the TTL and store the value with it
+	ttl, err := cache.Options.TTL(ctx, key, val)
+	if err != nil {
+		return 0, err
+	}
+	if err := gouache.SetWithTTL(ctx, cache.Cache, key, val, ttl); err != nil {
+		return 0, err
+	}
+
+	// A ttl of zero or less means the entry never expires
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl, nil
+}
@@ -0,0 +1,149 @@
+package ddd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// mockDatabase is a simple in-memory database implementation for testing purposes.
+type mockDatabase struct {
+	mu      sync.Mutex
+	data    map[string]any
+	selects int
+}
+
+// newMockDatabase creates a new mockDatabase instance.
+func newMockDatabase() *mockDatabase {
+	return &mockDatabase{data: make(map[string]any)}
+}
+
+// Select retrieves a record from the database, returning gouache.ErrNotFound if it doesn't exist.
+func (m *mockDatabase) Select(ctx context.Context, key string) (any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.selects++
+	if val, ok := m.data[key]; ok {
+		return val, nil
+	}
+	return nil, gouache.ErrNotFound
+}
+
+// Upsert inserts or updates a record in the database.
+func (m *mockDatabase) Upsert(ctx context.Context, key string, val any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = val
+	return nil
+}
+
+// Delete removes a record from the database.
+func (m *mockDatabase) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+// selectCount returns how many times Select has been called.
+func (m *mockDatabase) selectCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.selects
+}
+
+// TestCache_Get tests that Get populates the cache from the database.
+func TestCache_Get(t *testing.T) {
+	underlying := &sample.Cache{}
+	database := newMockDatabase()
+	database.data["key"] = "value"
+	cache := New(underlying, database)
+	ctx := context.Background()
+
+	// The first Get reads the database and populates the cache
+	val, err := cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val != "value" {
+		t.Errorf("Expected value, got %v", val)
+	}
+
+	// The second Get is served from the cache
+	if _, err := cache.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := database.selectCount(); n != 1 {
+		t.Errorf("Expected 1 select, got %d", n)
+	}
+}
+
+// TestCache_Set tests that Set invalidates the cache twice.
+func TestCache_Set(t *testing.T) {
+	underlying := &sample.Cache{}
+	database := newMockDatabase()
+	cache := New(underlying, database, WithDelayDuration(10*time.Millisecond))
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if database.data["key"] != "value" {
+		t.Errorf("Expected value in the database, got %v", database.data["key"])
+	}
+
+	// A stale value written after the first delete is removed by the delayed delete
+	_ = underlying.Set(ctx, "key", "stale")
+	time.Sleep(30 * time.Millisecond)
+	if _, err := underlying.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss after the delayed delete, got %v", err)
+	}
+}
+
+// TestCache_WithNegativeTTL tests that keys missing from the database are remembered as absent.
+func TestCache_WithNegativeTTL(t *testing.T) {
+	underlying := &sample.Cache{}
+	database := newMockDatabase()
+	cache := New(underlying, database, WithNegativeTTL(20*time.Millisecond))
+	ctx := context.Background()
+
+	// Repeated lookups of the absent key only reach the database once
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get(ctx, "missing"); !errors.Is(err, gouache.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if n := database.selectCount(); n != 1 {
+		t.Errorf("Expected 1 select, got %d", n)
+	}
+
+	// Once the absent entry expires the database is queried again
+	time.Sleep(30 * time.Millisecond)
+	if _, _, err := cache.(gouache.TTLCache).GetWithTTL(ctx, "missing"); !errors.Is(err, gouache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if n := database.selectCount(); n != 2 {
+		t.Errorf("Expected 2 selects, got %d", n)
+	}
+}
+
+// TestCache_WithoutNegativeTTL tests that absent keys are not remembered by default.
+func TestCache_WithoutNegativeTTL(t *testing.T) {
+	database := newMockDatabase()
+	cache := New(&sample.Cache{}, database)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Get(ctx, "missing"); !errors.Is(err, gouache.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if n := database.selectCount(); n != 2 {
+		t.Errorf("Expected 2 selects, got %d", n)
+	}
+}
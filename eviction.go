@@ -0,0 +1,15 @@
+package gouache
+
+// EvictionCache is an optional interface that a Cache can implement to report
+// the entries it removes by itself, e.g. to forget the tags of the entries
+// evicted by an LRU policy.
+type EvictionCache interface {
+	// OnEvict registers a function called with the key of every entry the
+	// cache removes, including the entries evicted to make room for others
+	// and the expired ones. The function may also be called for deleted
+	// entries, and must not call the cache back.
+	//
+	// Parameters:
+	//   - f: The function called with every removed key
+	OnEvict(f func(key string))
+}
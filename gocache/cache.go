@@ -16,6 +16,18 @@ import (
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.BatchCache interface at compile time.
+var _ gouache.BatchCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.StatsCache interface at compile time.
+var _ gouache.StatsCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.ScanCache interface at compile time.
+var _ gouache.ScanCache = (*Cache)(nil)
+
 // Cache is an implementation of gouache.Cache using go-cache as the storage backend.
 // It provides methods for storing, retrieving, and deleting cached values with
 // support for configurable time-to-live (TTL) settings.
@@ -26,6 +38,14 @@ type Cache struct {
 	// TTL is an optional function to determine the time-to-live duration for a cache entry.
 	// If not provided, the default expiration behavior of go-cache is used.
 	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
+
+	// Jitter is an optional function to spread the time-to-live of cache entries,
+	// so that entries stored together don't expire together. It is applied to
+	// every positive TTL, see the ttl package for built-in policies.
+	Jitter func(key string, ttl time.Duration) time.Duration
+
+	// counters counts the operations for Stats.
+	counters gouache.Counters
 }
 
 // Get retrieves a value from the cache by its key.
@@ -40,7 +60,7 @@ type Cache struct {
 //   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
 func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 	// Attempt to get the value from the go-cache
-	val, ok := cache.Cache.Get(key)
+	val, ok := cache.get(key)
 
 	// Handle case where entry is not found or has expired
 	if !ok {
@@ -74,13 +94,15 @@ func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 		if err != nil {
 			return err
 		}
-		// Store the value with the computed TTL
-		cache.Cache.Set(key, val, ttl)
+		// Store the value with the computed and jittered TTL
+		cache.Cache.Set(key, val, cache.jitter(key, ttl))
+		cache.counters.AddSets(1)
 		return nil
 	}
 
 	// Store the value with default expiration
 	cache.Cache.Set(key, val, ttl)
+	cache.counters.AddSets(1)
 	return nil
 }
 
@@ -95,5 +117,171 @@ func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 func (cache *Cache) Delete(ctx context.Context, key string) error {
 	// Delegate deletion to the underlying go-cache instance
 	cache.Cache.Delete(key)
+	cache.counters.AddDeletes(1)
+	return nil
+}
+
+// GetMulti retrieves the values for the given keys from the cache.
+// Keys that do not exist or have expired are omitted from the returned map.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys to their cached values
+//   - Always returns a nil error as go-cache.Get doesn't return errors
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	vals := make(map[string]any, len(keys))
+	for _, key := range keys {
+		// Get every key and collect the ones that exist
+		if val, ok := cache.get(key); ok {
+			vals[key] = val
+		}
+	}
+	return vals, nil
+}
+
+// SetMulti stores every key/value pair of the given map in the cache.
+// The TTL of each entry is determined the same way as in Set.
+//
+// Parameters:
+//   - ctx: Context for the operation, passed to the TTL function if configured
+//   - vals: The key/value pairs to store
+//
+// Returns:
+//   - An error if the TTL function (if configured) returns an error, otherwise nil
+func (cache *Cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	for key, val := range vals {
+		if err := cache.Set(ctx, key, val); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// DeleteMulti removes the values for the given keys from the cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - Always returns nil as go-cache.Delete doesn't return errors
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		cache.Cache.Delete(key)
+	}
+	cache.counters.AddDeletes(int64(len(keys)))
+	return nil
+}
+
+// SetWithTTL stores a value in the cache under the specified key with the given TTL.
+// The TTL function, if configured, is not consulted, the Jitter function is.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store in the cache
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - Always returns nil as go-cache.Set doesn't return errors
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	// go-cache treats zero as its default expiration, so map it explicitly
+	if ttl <= 0 {
+		ttl = gocache.NoExpiration
+	}
+
+	// Store the value with the given and jittered TTL
+	cache.Cache.Set(key, val, cache.jitter(key, ttl))
+	cache.counters.AddSets(1)
+	return nil
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the cache.
+// It returns gouache.ErrCacheMiss if the key does not exist or has expired.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	// Attempt to get the value and its expiration time from the go-cache
+	val, expireAt, ok := cache.Cache.GetWithExpiration(key)
+
+	// Handle case where entry is not found or has expired
+	if !ok {
+		cache.counters.AddMisses(1)
+		return nil, 0, gouache.ErrCacheMiss
+	}
+
+	// Entries without expiration report a zero TTL
+	if expireAt.IsZero() {
+		cache.counters.AddHits(1)
+		return val, 0, nil
+	}
+
+	// The entry may have expired since go-cache checked it
+	ttl := time.Until(expireAt)
+	if ttl <= 0 {
+		cache.counters.AddMisses(1)
+		return nil, 0, gouache.ErrCacheMiss
+	}
+	cache.counters.AddHits(1)
+	return val, ttl, nil
+}
+
+// Stats returns the counters of the cache.
+// go-cache removes expired entries without notice, so evictions are not
+// counted, and the entries include the expired entries not yet removed by its
+// janitor. The bytes are unknown.
+//
+// Returns:
+//   - A snapshot of the counters
+func (cache *Cache) Stats() gouache.Stats {
+	stats := cache.counters.Stats()
+	stats.Entries = int64(cache.Cache.ItemCount())
+	return stats
+}
+
+// Scan calls fn for every key of the cache, skipping the expired entries.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - fn: The function called with every key
+//
+// Returns:
+//   - The error returned by fn
+func (cache *Cache) Scan(ctx context.Context, fn func(key string) error) error {
+	// Items copies the unexpired entries, so fn may modify the cache
+	for key := range cache.Cache.Items() {
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get retrieves a value from the go-cache, and counts the lookup.
+func (cache *Cache) get(key string) (any, bool) {
+	val, ok := cache.Cache.Get(key)
+	if ok {
+		cache.counters.AddHits(1)
+	} else {
+		cache.counters.AddMisses(1)
+	}
+	return val, ok
+}
+
+// jitter applies the Jitter function, if configured, to a positive TTL.
+func (cache *Cache) jitter(key string, ttl time.Duration) time.Duration {
+	if cache.Jitter == nil || ttl <= 0 {
+		return ttl
+	}
+	return cache.Jitter(key, ttl)
+}
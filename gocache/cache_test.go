@@ -3,6 +3,7 @@ package gocache
 import (
 	"context"
 	"errors"
+	"sort"
 	"testing"
 	"time"
 
@@ -183,3 +184,179 @@ func TestCache_TTLWithError(t *testing.T) {
 		t.Errorf("Expected TTL error, got %v", err)
 	}
 }
+
+// TestCache_Multi tests GetMulti, SetMulti and DeleteMulti operations
+func TestCache_Multi(t *testing.T) {
+	goCache := cache.New(5*time.Minute, 10*time.Minute)
+
+	cacheImpl := &Cache{
+		Cache: goCache,
+	}
+
+	ctx := context.Background()
+
+	// Test SetMulti
+	err := cacheImpl.SetMulti(ctx, map[string]any{"key1": "value1", "key2": "value2"})
+	if err != nil {
+		t.Errorf("Failed to set values: %v", err)
+	}
+
+	// Test GetMulti with a missing key
+	vals, err := cacheImpl.GetMulti(ctx, []string{"key1", "key2", "key3"})
+	if err != nil {
+		t.Errorf("Failed to get values: %v", err)
+	}
+	if len(vals) != 2 || vals["key1"] != "value1" || vals["key2"] != "value2" {
+		t.Errorf("Expected key1 and key2 only, got %v", vals)
+	}
+
+	// Test DeleteMulti
+	err = cacheImpl.DeleteMulti(ctx, []string{"key1", "key2"})
+	if err != nil {
+		t.Errorf("Failed to delete values: %v", err)
+	}
+	if goCache.ItemCount() != 0 {
+		t.Errorf("Expected empty cache, got %d items", goCache.ItemCount())
+	}
+}
+
+// TestCache_SetWithTTL tests SetWithTTL and GetWithTTL operations
+func TestCache_SetWithTTL(t *testing.T) {
+	goCache := cache.New(5*time.Minute, 10*time.Minute)
+
+	cacheImpl := &Cache{
+		Cache: goCache,
+	}
+
+	ctx := context.Background()
+
+	// Test SetWithTTL
+	err := cacheImpl.SetWithTTL(ctx, "key1", "value1", 20*time.Millisecond)
+	if err != nil {
+		t.Errorf("Failed to set value with TTL: %v", err)
+	}
+
+	// Test GetWithTTL before expiration
+	result, ttl, err := cacheImpl.GetWithTTL(ctx, "key1")
+	if err != nil {
+		t.Errorf("Failed to get value: %v", err)
+	}
+	if result != "value1" {
+		t.Errorf("Expected value1, got %v", result)
+	}
+	if ttl <= 0 || ttl > 20*time.Millisecond {
+		t.Errorf("Expected a remaining TTL within 20ms, got %v", ttl)
+	}
+
+	// A zero TTL means no expiration rather than go-cache's default expiration
+	err = cacheImpl.SetWithTTL(ctx, "key2", "value2", 0)
+	if err != nil {
+		t.Errorf("Failed to set value with TTL: %v", err)
+	}
+	_, ttl, err = cacheImpl.GetWithTTL(ctx, "key2")
+	if err != nil {
+		t.Errorf("Failed to get value: %v", err)
+	}
+	if ttl != 0 {
+		t.Errorf("Expected zero TTL, got %v", ttl)
+	}
+
+	// Wait for expiration
+	time.Sleep(30 * time.Millisecond)
+
+	// Test GetWithTTL after expiration
+	_, _, err = cacheImpl.GetWithTTL(ctx, "key1")
+	if err != gouache.ErrCacheMiss {
+		t.Errorf("Expected gouache.ErrCacheMiss for expired key, got %v", err)
+	}
+}
+
+// TestCache_Jitter tests that the Jitter function is applied to positive TTLs only
+func TestCache_Jitter(t *testing.T) {
+	goCache := cache.New(cache.NoExpiration, 10*time.Minute)
+
+	cacheImpl := &Cache{
+		Cache: goCache,
+		TTL: func(ctx context.Context, key string, val any) (time.Duration, error) {
+			return time.Minute, nil
+		},
+		Jitter: func(key string, ttl time.Duration) time.Duration {
+			return ttl + time.Hour
+		},
+	}
+
+	ctx := context.Background()
+
+	// The TTL from the TTL function is jittered
+	if err := cacheImpl.Set(ctx, "key1", "value1"); err != nil {
+		t.Errorf("Failed to set value: %v", err)
+	}
+	_, ttl, err := cacheImpl.GetWithTTL(ctx, "key1")
+	if err != nil {
+		t.Errorf("Failed to get value: %v", err)
+	}
+	if ttl <= time.Hour || ttl > time.Hour+time.Minute {
+		t.Errorf("Expected a jittered TTL of about 1h1m, got %v", ttl)
+	}
+
+	// A zero TTL still means no expiration
+	if err := cacheImpl.SetWithTTL(ctx, "key2", "value2", 0); err != nil {
+		t.Errorf("Failed to set value with TTL: %v", err)
+	}
+	_, ttl, err = cacheImpl.GetWithTTL(ctx, "key2")
+	if err != nil {
+		t.Errorf("Failed to get value: %v", err)
+	}
+	if ttl != 0 {
+		t.Errorf("Expected zero TTL, got %v", ttl)
+	}
+}
+
+// TestCache_Stats tests that the operations and entries are counted
+func TestCache_Stats(t *testing.T) {
+	cacheImpl := &Cache{Cache: cache.New(cache.NoExpiration, 0)}
+	ctx := context.Background()
+
+	_ = cacheImpl.Set(ctx, "key1", "value1")
+	_ = cacheImpl.SetWithTTL(ctx, "key2", "value2", time.Minute)
+	_, _ = cacheImpl.Get(ctx, "key1")
+	_, _, _ = cacheImpl.GetWithTTL(ctx, "key2")
+	_, _ = cacheImpl.GetMulti(ctx, []string{"key1", "missing"})
+	_ = cacheImpl.DeleteMulti(ctx, []string{"key1"})
+
+	expected := gouache.Stats{Hits: 3, Misses: 1, Sets: 2, Deletes: 1, Entries: 1}
+	if stats := cacheImpl.Stats(); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+// TestCache_Scan tests that Scan lists the live keys and stops on the first error.
+func TestCache_Scan(t *testing.T) {
+	cacheImpl := &Cache{Cache: cache.New(cache.NoExpiration, 0)}
+	ctx := context.Background()
+
+	_ = cacheImpl.Set(ctx, "a", "a")
+	_ = cacheImpl.Set(ctx, "b", "b")
+	_ = cacheImpl.SetWithTTL(ctx, "short", "a", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	var keys []string
+	err := cacheImpl.Scan(ctx, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	sort.Strings(keys)
+	if err != nil || len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Expected keys [a b], got %v, %v", keys, err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = cacheImpl.Scan(ctx, func(key string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected Scan to stop with the error of fn, got %v after %d calls", err, calls)
+	}
+}
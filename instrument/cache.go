@@ -0,0 +1,344 @@
+// Package instrument provides cache and database implementations that record
+// metrics and spans of every operation.
+//
+// This package implements the gouache.Cache and gouache.Database interfaces
+// by wrapping existing ones. Every operation is measured with its backend
+// name, operation name, outcome (hit, miss, ok or error) and latency, and
+// traced with a span. The measurements and spans go to a Sink, which can be
+// the in-memory Recorder or an adapter to OpenTelemetry or Prometheus.
+package instrument
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-leo/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchCache interface at compile time.
+var _ gouache.BatchCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
+// Ensure that Database implements the gouache.Database interface at compile time.
+var _ gouache.Database = (*Database)(nil)
+
+// Cache is a cache that records metrics and spans of every operation.
+type Cache struct {
+	// cache is the underlying cache implementation
+	cache gouache.Cache
+
+	// name is the backend name of the measurements
+	name string
+
+	// sink receives the measurements and spans
+	sink Sink
+}
+
+// New creates a new instrumented cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - name: The backend name of the measurements, e.g. "redis" or "local"
+//   - sink: The sink receiving the measurements and spans
+//
+// Returns:
+//   - An instrumented cache
+func New(c gouache.Cache, name string, sink Sink) *Cache {
+	return &Cache{cache: c, name: name, sink: sink}
+}
+
+// Middleware returns a gouache.Middleware recording metrics and spans of the
+// operations of the next cache.
+//
+// Parameters:
+//   - name: The backend name of the measurements
+//   - sink: The sink receiving the measurements and spans
+//
+// Returns:
+//   - A middleware wrapping the next cache with New
+func Middleware(name string, sink Sink) gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		return New(next, name, sink)
+	}
+}
+
+// Get retrieves a value from the underlying cache, recorded as a "get"
+// operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	var val any
+	err := observe(ctx, cache.sink, cache.name, "get", key, true, func(ctx context.Context) (err error) {
+		val, err = cache.cache.Get(ctx, key)
+		return err
+	})
+	return val, err
+}
+
+// Set stores a value in the underlying cache, recorded as a "set" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	return observe(ctx, cache.sink, cache.name, "set", key, false, func(ctx context.Context) error {
+		return cache.cache.Set(ctx, key, val)
+	})
+}
+
+// Delete removes a value from the underlying cache, recorded as a "delete"
+// operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	return observe(ctx, cache.sink, cache.name, "delete", key, false, func(ctx context.Context) error {
+		return cache.cache.Delete(ctx, key)
+	})
+}
+
+// GetMulti retrieves the values for the given keys from the underlying cache,
+// recorded as a "get_multi" operation. Every key found counts as a hit and
+// every key missing as a miss.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys to their cached values
+//   - An error if the operation fails
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	ctx, end := cache.sink.StartSpan(ctx, cache.name, "get_multi", "")
+	start := time.Now()
+	vals, err := gouache.GetMulti(ctx, cache.cache, keys)
+	duration := time.Since(start)
+
+	// A failed batch is one error
+	if err != nil {
+		cache.sink.Observe(ctx, Event{Backend: cache.name, Operation: "get_multi", Outcome: Error, Count: 1, Duration: duration})
+		end(Error, err)
+		return vals, err
+	}
+
+	// Count the keys found and missing, misses are not errors of the span
+	hits, misses := gouache.CountMulti(keys, vals)
+	if hits > 0 {
+		cache.sink.Observe(ctx, Event{Backend: cache.name, Operation: "get_multi", Outcome: Hit, Count: int64(hits), Duration: duration})
+	}
+	outcome := Hit
+	if misses > 0 {
+		cache.sink.Observe(ctx, Event{Backend: cache.name, Operation: "get_multi", Outcome: Miss, Count: int64(misses), Duration: duration})
+		outcome = Miss
+	}
+	end(outcome, nil)
+	return vals, nil
+}
+
+// SetMulti stores every key/value pair of the given map in the underlying
+// cache, recorded as a "set_multi" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The key/value pairs to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	return observe(ctx, cache.sink, cache.name, "set_multi", "", false, func(ctx context.Context) error {
+		return gouache.SetMulti(ctx, cache.cache, vals)
+	})
+}
+
+// DeleteMulti removes the values for the given keys from the underlying
+// cache, recorded as a "delete_multi" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	return observe(ctx, cache.sink, cache.name, "delete_multi", "", false, func(ctx context.Context) error {
+		return gouache.DeleteMulti(ctx, cache.cache, keys)
+	})
+}
+
+// SetWithTTL stores a value with the given TTL in the underlying cache,
+// recorded as a "set" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	return observe(ctx, cache.sink, cache.name, "set", key, false, func(ctx context.Context) error {
+		return gouache.SetWithTTL(ctx, cache.cache, key, val, ttl)
+	})
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the
+// underlying cache, recorded as a "get" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	var val any
+	var ttl time.Duration
+	err := observe(ctx, cache.sink, cache.name, "get", key, true, func(ctx context.Context) (err error) {
+		val, ttl, err = gouache.GetWithTTL(ctx, cache.cache, key)
+		return err
+	})
+	return val, ttl, err
+}
+
+// Database is a database that records metrics and spans of every operation.
+type Database struct {
+	// database is the underlying database implementation
+	database gouache.Database
+
+	// name is the backend name of the measurements
+	name string
+
+	// sink receives the measurements and spans
+	sink Sink
+}
+
+// NewDatabase creates a new instrumented database.
+//
+// Parameters:
+//   - d: The underlying database implementation
+//   - name: The backend name of the measurements, e.g. "mysql"
+//   - sink: The sink receiving the measurements and spans
+//
+// Returns:
+//   - An instrumented database
+func NewDatabase(d gouache.Database, name string, sink Sink) *Database {
+	return &Database{database: d, name: name, sink: sink}
+}
+
+// Select retrieves a record from the underlying database, recorded as a
+// "select" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the record
+//
+// Returns:
+//   - The record
+//   - An error if the operation fails, or gouache.ErrNotFound if the record doesn't exist
+func (database *Database) Select(ctx context.Context, key string) (any, error) {
+	var val any
+	err := observe(ctx, database.sink, database.name, "select", key, true, func(ctx context.Context) (err error) {
+		val, err = database.database.Select(ctx, key)
+		return err
+	})
+	return val, err
+}
+
+// Upsert inserts or updates a record in the underlying database, recorded as
+// an "upsert" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the record
+//   - val: The record
+//
+// Returns:
+//   - An error if the operation fails
+func (database *Database) Upsert(ctx context.Context, key string, val any) error {
+	return observe(ctx, database.sink, database.name, "upsert", key, false, func(ctx context.Context) error {
+		return database.database.Upsert(ctx, key, val)
+	})
+}
+
+// Delete removes a record from the underlying database, recorded as a
+// "delete" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the record
+//
+// Returns:
+//   - An error if the operation fails
+func (database *Database) Delete(ctx context.Context, key string) error {
+	return observe(ctx, database.sink, database.name, "delete", key, false, func(ctx context.Context) error {
+		return database.database.Delete(ctx, key)
+	})
+}
+
+// observe runs an operation within a span, and records its measurement.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - sink: The sink receiving the measurement and span
+//   - backend: The backend name
+//   - operation: The operation name
+//   - key: The key of the operation, empty for several keys
+//   - lookup: Whether the operation is a lookup, whose outcome is a hit or a miss
+//   - f: The operation
+//
+// Returns:
+//   - The error of the operation
+func observe(ctx context.Context, sink Sink, backend string, operation string, key string, lookup bool, f func(ctx context.Context) error) error {
+	ctx, end := sink.StartSpan(ctx, backend, operation, key)
+	start := time.Now()
+	err := f(ctx)
+	duration := time.Since(start)
+
+	// Classify the outcome, misses are not errors of the span
+	outcome := outcomeOf(err, lookup)
+	spanErr := err
+	if outcome == Miss {
+		spanErr = nil
+	}
+
+	sink.Observe(ctx, Event{Backend: backend, Operation: operation, Outcome: outcome, Count: 1, Duration: duration})
+	end(outcome, spanErr)
+	return err
+}
+
+// outcomeOf returns the outcome of an operation given its error.
+func outcomeOf(err error, lookup bool) Outcome {
+	switch {
+	case err == nil && lookup:
+		return Hit
+	case err == nil:
+		return OK
+	case lookup && (errors.Is(err, gouache.ErrCacheMiss) || errors.Is(err, gouache.ErrNotFound)):
+		return Miss
+	default:
+		return Error
+	}
+}
@@ -0,0 +1,165 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// mockDatabase is a simple in-memory database implementation for testing purposes.
+type mockDatabase struct {
+	data map[string]any
+}
+
+func (m *mockDatabase) Select(ctx context.Context, key string) (any, error) {
+	val, ok := m.data[key]
+	if !ok {
+		return nil, gouache.ErrNotFound
+	}
+	return val, nil
+}
+
+func (m *mockDatabase) Upsert(ctx context.Context, key string, val any) error {
+	m.data[key] = val
+	return nil
+}
+
+func (m *mockDatabase) Delete(ctx context.Context, key string) error {
+	return errors.New("delete failed")
+}
+
+// TestCache tests that hits, misses and writes are recorded per operation.
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	recorder := &Recorder{}
+	cache := New(&sample.Cache{}, "local", recorder)
+
+	if err := cache.Set(ctx, "a", 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cache.SetWithTTL(ctx, "b", 2, time.Minute); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, err := cache.Get(ctx, "a"); err != nil || val != 1 {
+		t.Errorf("Expected 1, got %v, %v", val, err)
+	}
+	if _, err := cache.Get(ctx, "missing"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected a cache miss, got %v", err)
+	}
+	if _, _, err := cache.GetWithTTL(ctx, "b"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if counts := recorder.Series("local", "set").Counts; counts[OK] != 2 {
+		t.Errorf("Expected 2 sets, got %v", counts)
+	}
+	series := recorder.Series("local", "get")
+	if series.Counts[Hit] != 2 || series.Counts[Miss] != 1 || series.Counts[Error] != 0 {
+		t.Errorf("Expected 2 hits and 1 miss, got %v", series.Counts)
+	}
+	var observed int64
+	for _, count := range series.Histogram {
+		observed += count
+	}
+	if observed != 3 || len(series.Histogram) != len(DefaultBuckets)+1 {
+		t.Errorf("Expected 3 latencies in the histogram, got %v", series.Histogram)
+	}
+	if ratio := recorder.HitRatio("local"); ratio != 2.0/3.0 {
+		t.Errorf("Expected a hit ratio of 2/3, got %v", ratio)
+	}
+}
+
+// TestCache_Multi tests that batch operations are recorded once, and batch lookups once per key.
+func TestCache_Multi(t *testing.T) {
+	ctx := context.Background()
+	recorder := &Recorder{}
+	cache := New(&sample.Cache{}, "local", recorder)
+
+	if err := cache.SetMulti(ctx, map[string]any{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if vals, err := cache.GetMulti(ctx, []string{"a", "b"}); err != nil || len(vals) != 2 {
+		t.Errorf("Expected 2 values, got %v, %v", vals, err)
+	}
+	if vals, err := cache.GetMulti(ctx, []string{"a", "c"}); err != nil || len(vals) != 1 {
+		t.Errorf("Expected 1 value, got %v, %v", vals, err)
+	}
+	if err := cache.DeleteMulti(ctx, []string{"a", "b"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	counts := recorder.Series("local", "get_multi").Counts
+	if counts[Hit] != 3 || counts[Miss] != 1 {
+		t.Errorf("Expected 3 hits and 1 miss, got %v", counts)
+	}
+	if counts := recorder.Series("local", "delete_multi").Counts; counts[OK] != 1 {
+		t.Errorf("Expected 1 delete, got %v", counts)
+	}
+}
+
+// TestDatabase tests that database operations are recorded and traced.
+func TestDatabase(t *testing.T) {
+	ctx := context.Background()
+	recorder := &Recorder{}
+	database := NewDatabase(&mockDatabase{data: map[string]any{}}, "db", recorder)
+
+	if err := database.Upsert(ctx, "a", 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, err := database.Select(ctx, "a"); err != nil || val != 1 {
+		t.Errorf("Expected 1, got %v, %v", val, err)
+	}
+	if _, err := database.Select(ctx, "missing"); !errors.Is(err, gouache.ErrNotFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+	if err := database.Delete(ctx, "a"); err == nil {
+		t.Error("Expected an error")
+	}
+
+	if counts := recorder.Series("db", "delete").Counts; counts[Error] != 1 {
+		t.Errorf("Expected 1 error, got %v", counts)
+	}
+
+	// Every operation is traced, misses are not span errors
+	spans := recorder.Spans()
+	if len(spans) != 4 {
+		t.Fatalf("Expected 4 spans, got %d", len(spans))
+	}
+	expected := []Outcome{OK, Hit, Miss, Error}
+	for i, span := range spans {
+		if span.Backend != "db" || span.Outcome != expected[i] {
+			t.Errorf("Expected a %s span of db, got %+v", expected[i], span)
+		}
+		if (span.Err != nil) != (span.Outcome == Error) {
+			t.Errorf("Expected an error only for failed operations, got %+v", span)
+		}
+	}
+	if spans[2].Key != "missing" || spans[2].Operation != "select" {
+		t.Errorf("Expected the select of missing, got %+v", spans[2])
+	}
+
+	// Reset clears everything
+	recorder.Reset()
+	if len(recorder.Spans()) != 0 || len(recorder.Series("db", "select").Counts) != 0 {
+		t.Error("Expected an empty recorder")
+	}
+}
+
+// TestRecorder_Buckets tests that latencies fall in the first bucket that fits them.
+func TestRecorder_Buckets(t *testing.T) {
+	recorder := &Recorder{Buckets: []time.Duration{time.Millisecond, time.Second}}
+	for _, d := range []time.Duration{time.Microsecond, time.Millisecond, time.Minute} {
+		recorder.Observe(context.Background(), Event{Backend: "b", Operation: "get", Outcome: Hit, Duration: d})
+	}
+	series := recorder.Series("b", "get")
+	if series.Histogram[0] != 2 || series.Histogram[1] != 0 || series.Histogram[2] != 1 {
+		t.Errorf("Expected [2 0 1], got %v", series.Histogram)
+	}
+	if series.Sum != time.Microsecond+time.Millisecond+time.Minute {
+		t.Errorf("Unexpected sum %v", series.Sum)
+	}
+}
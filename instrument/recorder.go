@@ -0,0 +1,223 @@
+package instrument
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Ensure that Recorder implements the Sink interface at compile time.
+var _ Sink = (*Recorder)(nil)
+
+// DefaultBuckets are the upper bounds of the latency histogram buckets of a
+// Recorder without Buckets.
+var DefaultBuckets = []time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// Series holds the measurements of an operation of a backend.
+type Series struct {
+	// Counts holds the number of operations by outcome.
+	Counts map[Outcome]int64
+
+	// Buckets are the upper bounds of the histogram buckets.
+	Buckets []time.Duration
+
+	// Histogram holds the number of operations by latency bucket. The last
+	// element counts the operations slower than every bucket.
+	Histogram []int64
+
+	// Sum is the total latency of the operations.
+	Sum time.Duration
+}
+
+// Span is a span recorded by a Recorder.
+type Span struct {
+	// Backend is the name of the instrumented cache or database.
+	Backend string
+
+	// Operation is the name of the operation.
+	Operation string
+
+	// Key is the key of the operation, empty for several keys.
+	Key string
+
+	// Outcome is the outcome of the operation.
+	Outcome Outcome
+
+	// Err is the error of the operation, if any.
+	Err error
+
+	// Duration is the duration of the span.
+	Duration time.Duration
+}
+
+// seriesKey identifies a series.
+type seriesKey struct {
+	backend   string
+	operation string
+}
+
+// Recorder is an in-memory Sink, e.g. for tests or a debug endpoint.
+//
+// The zero value is an empty recorder ready to use.
+type Recorder struct {
+	// Buckets are the upper bounds of the latency histogram buckets, in
+	// increasing order. If not provided, DefaultBuckets are used.
+	Buckets []time.Duration
+
+	// mu guards the fields below
+	mu sync.Mutex
+
+	// series holds the measurements by backend and operation
+	series map[seriesKey]*Series
+
+	// spans holds the ended spans
+	spans []Span
+}
+
+// Observe records the measurement of an operation.
+//
+// Parameters:
+//   - ctx: Context of the operation
+//   - event: The measurement of the operation
+func (recorder *Recorder) Observe(ctx context.Context, event Event) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if recorder.series == nil {
+		recorder.series = make(map[seriesKey]*Series)
+	}
+
+	// Create the series on its first event
+	key := seriesKey{backend: event.Backend, operation: event.Operation}
+	series, ok := recorder.series[key]
+	if !ok {
+		buckets := recorder.Buckets
+		if buckets == nil {
+			buckets = DefaultBuckets
+		}
+		series = &Series{
+			Counts:    make(map[Outcome]int64),
+			Buckets:   buckets,
+			Histogram: make([]int64, len(buckets)+1),
+		}
+		recorder.series[key] = series
+	}
+
+	// Count the outcome, and the latency in the first bucket that fits it
+	count := event.Count
+	if count <= 0 {
+		count = 1
+	}
+	series.Counts[event.Outcome] += count
+	series.Histogram[sort.Search(len(series.Buckets), func(i int) bool {
+		return event.Duration <= series.Buckets[i]
+	})]++
+	series.Sum += event.Duration
+}
+
+// StartSpan starts a span, recorded once it ends.
+//
+// Parameters:
+//   - ctx: Context of the operation
+//   - backend: The name of the backend
+//   - operation: The name of the operation
+//   - key: The key of the operation
+//
+// Returns:
+//   - The context, unchanged
+//   - The function ending the span
+func (recorder *Recorder) StartSpan(ctx context.Context, backend string, operation string, key string) (context.Context, func(outcome Outcome, err error)) {
+	start := time.Now()
+	return ctx, func(outcome Outcome, err error) {
+		span := Span{
+			Backend:   backend,
+			Operation: operation,
+			Key:       key,
+			Outcome:   outcome,
+			Err:       err,
+			Duration:  time.Since(start),
+		}
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		recorder.spans = append(recorder.spans, span)
+	}
+}
+
+// Series returns a copy of the measurements of an operation of a backend.
+//
+// Parameters:
+//   - backend: The name of the backend
+//   - operation: The name of the operation
+//
+// Returns:
+//   - The measurements, empty if the operation was never observed
+func (recorder *Recorder) Series(backend string, operation string) Series {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	series, ok := recorder.series[seriesKey{backend: backend, operation: operation}]
+	if !ok {
+		return Series{Counts: map[Outcome]int64{}}
+	}
+	counts := make(map[Outcome]int64, len(series.Counts))
+	for outcome, count := range series.Counts {
+		counts[outcome] = count
+	}
+	return Series{
+		Counts:    counts,
+		Buckets:   series.Buckets,
+		Histogram: append([]int64(nil), series.Histogram...),
+		Sum:       series.Sum,
+	}
+}
+
+// HitRatio returns the ratio of hits to lookups of a backend, over every
+// operation.
+//
+// Parameters:
+//   - backend: The name of the backend
+//
+// Returns:
+//   - The hit ratio, zero if there was no lookup
+func (recorder *Recorder) HitRatio(backend string) float64 {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	var hits, lookups int64
+	for key, series := range recorder.series {
+		if key.backend == backend {
+			hits += series.Counts[Hit]
+			lookups += series.Counts[Hit] + series.Counts[Miss]
+		}
+	}
+	if lookups == 0 {
+		return 0
+	}
+	return float64(hits) / float64(lookups)
+}
+
+// Spans returns the ended spans, in the order they ended.
+//
+// Returns:
+//   - A copy of the spans
+func (recorder *Recorder) Spans() []Span {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return append([]Span(nil), recorder.spans...)
+}
+
+// Reset clears every measurement and span.
+func (recorder *Recorder) Reset() {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.series = nil
+	recorder.spans = nil
+}
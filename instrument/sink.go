@@ -0,0 +1,60 @@
+package instrument
+
+import (
+	"context"
+	"time"
+)
+
+// Outcome is the outcome of an operation.
+type Outcome string
+
+const (
+	// Hit is the outcome of a lookup that found the value.
+	Hit Outcome = "hit"
+
+	// Miss is the outcome of a lookup that didn't find the value, i.e. that
+	// returned gouache.ErrCacheMiss or gouache.ErrNotFound.
+	Miss Outcome = "miss"
+
+	// OK is the outcome of a successful write or delete.
+	OK Outcome = "ok"
+
+	// Error is the outcome of an operation that failed.
+	Error Outcome = "error"
+)
+
+// Event is the measurement of one operation.
+type Event struct {
+	// Backend is the name of the instrumented cache or database.
+	Backend string
+
+	// Operation is the name of the operation, e.g. "get" or "select".
+	Operation string
+
+	// Outcome is the outcome of the operation.
+	Outcome Outcome
+
+	// Count is the number of keys the outcome applies to: 1 for the
+	// operations on one key, and the number of keys found or missing for the
+	// hits and misses of a "get_multi" operation. Zero is read as 1.
+	Count int64
+
+	// Duration is the latency of the operation.
+	Duration time.Duration
+}
+
+// Sink receives the measurements and spans of the instrumented caches and
+// databases. Adapters to OpenTelemetry or Prometheus implement it, e.g. by
+// adding the Count of the events to a counter and their Duration to a
+// histogram labeled by backend, operation and outcome. A "get_multi"
+// operation finding some keys but not all is observed as two events, a hit
+// and a miss, sharing the latency of the operation.
+type Sink interface {
+	// Observe records the measurement of an operation.
+	Observe(ctx context.Context, event Event)
+
+	// StartSpan starts a span of an operation, and returns the context of the
+	// span, passed to the instrumented operation, and the function ending it.
+	// The key is empty for the operations on several keys.
+	StartSpan(ctx context.Context, backend string, operation string, key string) (context.Context, func(outcome Outcome, err error))
+}
@@ -0,0 +1,82 @@
+package invalidation
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Ensure that Bus implements the Transport interface at compile time.
+var _ Transport = (*Bus)(nil)
+
+// Bus is an in-memory Transport, connecting the caches of a single process.
+// It is mainly useful for tests. Messages are delivered synchronously,
+// Publish returns once every handler has returned.
+//
+// The zero value is an empty bus ready to use.
+type Bus struct {
+	// mu guards handlers
+	mu sync.RWMutex
+
+	// handlers are the active subscriptions
+	handlers map[*subscription]func(data []byte)
+}
+
+// subscription is a subscription to a Bus.
+type subscription struct {
+	bus *Bus
+}
+
+// Publish delivers a message to all subscribers.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - data: The message
+//
+// Returns:
+//   - Always returns nil
+func (bus *Bus) Publish(ctx context.Context, data []byte) error {
+	bus.mu.RLock()
+	handlers := make([]func(data []byte), 0, len(bus.handlers))
+	for _, handler := range bus.handlers {
+		handlers = append(handlers, handler)
+	}
+	bus.mu.RUnlock()
+
+	// Call handlers without holding the lock, so that they may publish
+	for _, handler := range handlers {
+		handler(data)
+	}
+	return nil
+}
+
+// Subscribe registers a handler for the messages published from now on.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - handler: The function receiving the messages
+//
+// Returns:
+//   - A Closer ending the subscription
+//   - Always returns a nil error
+func (bus *Bus) Subscribe(ctx context.Context, handler func(data []byte)) (io.Closer, error) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	if bus.handlers == nil {
+		bus.handlers = make(map[*subscription]func(data []byte))
+	}
+	sub := &subscription{bus: bus}
+	bus.handlers[sub] = handler
+	return sub, nil
+}
+
+// Close ends the subscription.
+//
+// Returns:
+//   - Always returns nil
+func (sub *subscription) Close() error {
+	sub.bus.mu.Lock()
+	defer sub.bus.mu.Unlock()
+	delete(sub.bus.handlers, sub)
+	return nil
+}
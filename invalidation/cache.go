@@ -0,0 +1,327 @@
+// Package invalidation provides a cache implementation that keeps in-process
+// caches of several instances consistent by broadcasting invalidations.
+//
+// This package implements the gouache.Cache interface by wrapping an existing
+// cache. Every Set and Delete publishes the key on a Transport, and every
+// instance evicts the key from its local cache when it receives the message.
+// Messages published by an instance are ignored by that same instance.
+//
+// A typical setup wraps a tiered cache and evicts its in-process layer:
+//
+//	l1 := &lru.Cache{Cache: lruCache}
+//	cache, err := invalidation.New(
+//		&tiered.Cache{Layers: []tiered.Layer{{Cache: l1}, {Cache: redisCache}}},
+//		&redis.PubSubTransport{Client: rdb, Channel: "gouache:invalidation"},
+//		invalidation.WithLocal(l1),
+//	)
+//
+// Messages sent while an instance is disconnected from the transport are lost,
+// so local entries should still have a short TTL.
+package invalidation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/go-leo/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
+// Transport delivers invalidation messages to all subscribed instances,
+// including the publishing one.
+type Transport interface {
+	// Publish sends a message to all subscribers.
+	Publish(ctx context.Context, data []byte) error
+
+	// Subscribe registers a handler for the messages published from now on.
+	// It returns once the subscription is active, closing the returned
+	// Closer ends it.
+	Subscribe(ctx context.Context, handler func(data []byte)) (io.Closer, error)
+}
+
+// Message is an invalidation message, encoded as JSON on the transport.
+type Message struct {
+	// Source identifies the instance that published the message.
+	Source string `json:"source"`
+
+	// Keys are the keys to evict.
+	Keys []string `json:"keys"`
+}
+
+// options holds configuration options for the invalidation cache.
+type options struct {
+	// Local is the cache evicted when a message is received.
+	Local gouache.Cache
+
+	// Source identifies this instance in the published messages.
+	Source string
+
+	// ErrorHandler is called when a received message cannot be handled.
+	ErrorHandler func(error)
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithLocal returns an Option that sets the cache evicted when a message is
+// received, typically the in-process layer of the wrapped cache. It is
+// required: evicting the wrapped cache itself would delete the shared layer
+// that the publisher just wrote. Pass the wrapped cache explicitly if it is
+// purely in-process.
+//
+// Parameters:
+//   - c: The local cache to evict
+//
+// Returns:
+//   - An Option function that sets the Local cache
+func WithLocal(c gouache.Cache) Option {
+	return func(o *options) {
+		o.Local = c
+	}
+}
+
+// WithSource returns an Option that sets the identifier of this instance.
+// If not specified, a random identifier is generated.
+//
+// Parameters:
+//   - id: The unique identifier of this instance
+//
+// Returns:
+//   - An Option function that sets the Source
+func WithSource(id string) Option {
+	return func(o *options) {
+		o.Source = id
+	}
+}
+
+// WithErrorHandler returns an Option that sets a custom error handler for
+// errors that occur while handling a received message.
+//
+// Parameters:
+//   - f: A function to handle errors
+//
+// Returns:
+//   - An Option function that sets the ErrorHandler
+func WithErrorHandler(f func(error)) Option {
+	return func(o *options) {
+		o.ErrorHandler = f
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+// The Local cache has no default, New requires it.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Generate a random source if not specified
+	if o.Source == "" {
+		var id [16]byte
+		_, _ = rand.Read(id[:])
+		o.Source = hex.EncodeToString(id[:])
+	}
+
+	// Set default error handler if not specified
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = func(err error) {
+			slog.Error("invalidation.Cache.handle", slog.String("err", err.Error()))
+		}
+	}
+	return o
+}
+
+// Cache is a cache that broadcasts the keys it writes and deletes, and evicts
+// the keys written and deleted by other instances.
+type Cache struct {
+	// options contains configuration options for the cache
+	options *options
+
+	// cache is the wrapped cache implementation
+	cache gouache.Cache
+
+	// transport delivers the invalidation messages
+	transport Transport
+
+	// subscription is the active subscription on the transport
+	subscription io.Closer
+}
+
+// New creates a new invalidation cache and subscribes to the transport.
+//
+// Parameters:
+//   - c: The wrapped cache implementation
+//   - t: The transport delivering the invalidation messages
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - An invalidation cache, to be closed when no longer used
+//   - An error if WithLocal is missing or the subscription fails
+func New(c gouache.Cache, t Transport, opts ...Option) (*Cache, error) {
+	options := newOptions(opts...)
+	if options.Local == nil {
+		return nil, errors.New("gouache: local cache is required, see WithLocal")
+	}
+	cache := &Cache{options: options, cache: c, transport: t}
+
+	// Start receiving invalidations of other instances
+	subscription, err := t.Subscribe(context.Background(), cache.handle)
+	if err != nil {
+		return nil, err
+	}
+	cache.subscription = subscription
+	return cache, nil
+}
+
+// Get retrieves a value from the wrapped cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	return cache.cache.Get(ctx, key)
+}
+
+// Set stores a value in the wrapped cache, then tells the other instances
+// to evict their copy.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation or the publication fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	if err := cache.cache.Set(ctx, key, val); err != nil {
+		return err
+	}
+	return cache.publish(ctx, key)
+}
+
+// Delete removes a value from the wrapped cache, then tells the other
+// instances to evict their copy.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation or the publication fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	if err := cache.cache.Delete(ctx, key); err != nil {
+		return err
+	}
+	return cache.publish(ctx, key)
+}
+
+// SetWithTTL stores a value with the given TTL in the wrapped cache, then
+// tells the other instances to evict their copy.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if the operation or the publication fails
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	if err := gouache.SetWithTTL(ctx, cache.cache, key, val, ttl); err != nil {
+		return err
+	}
+	return cache.publish(ctx, key)
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the
+// wrapped cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if the operation fails
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	return gouache.GetWithTTL(ctx, cache.cache, key)
+}
+
+// Close ends the subscription to the transport.
+//
+// Returns:
+//   - An error if closing the subscription fails
+func (cache *Cache) Close() error {
+	return cache.subscription.Close()
+}
+
+// publish sends an invalidation message for the given keys.
+func (cache *Cache) publish(ctx context.Context, keys ...string) error {
+	data, err := json.Marshal(&Message{Source: cache.options.Source, Keys: keys})
+	if err != nil {
+		return err
+	}
+	return cache.transport.Publish(ctx, data)
+}
+
+// handle evicts the keys of a message published by another instance.
+func (cache *Cache) handle(data []byte) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		cache.options.ErrorHandler(err)
+		return
+	}
+
+	// Our own writes are already reflected in the local cache
+	if msg.Source == cache.options.Source {
+		return
+	}
+
+	for _, key := range msg.Keys {
+		if err := cache.options.Local.Delete(context.Background(), key); err != nil {
+			cache.options.ErrorHandler(err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package invalidation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+	"github.com/go-leo/gouache/tiered"
+)
+
+// newInstance creates the cache of one instance: a local layer in front of a
+// shared layer, connected to the bus.
+func newInstance(t *testing.T, bus *Bus, shared gouache.Cache) (*Cache, *sample.Cache) {
+	local := &sample.Cache{}
+	cache, err := New(&tiered.Cache{Layers: []tiered.Layer{{Cache: local}, {Cache: shared}}}, bus, WithLocal(local))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = cache.Close() })
+	return cache, local
+}
+
+// TestCache_Delete tests that a delete on one instance evicts the local copies of the others.
+func TestCache_Delete(t *testing.T) {
+	bus := &Bus{}
+	shared := &sample.Cache{}
+	a, _ := newInstance(t, bus, shared)
+	b, bLocal := newInstance(t, bus, shared)
+	ctx := context.Background()
+
+	// Both instances have the value in their local layer
+	if err := a.Set(ctx, "key", "v1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, err := b.Get(ctx, "key"); err != nil || val != "v1" {
+		t.Fatalf("Expected v1, got %v and %v", val, err)
+	}
+
+	// A delete on a evicts the copy of b
+	if err := a.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := bLocal.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the local copy to be evicted, got %v", err)
+	}
+	if _, err := b.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, got %v", err)
+	}
+}
+
+// TestCache_Set tests that an update on one instance is seen by the others.
+func TestCache_Set(t *testing.T) {
+	bus := &Bus{}
+	shared := &sample.Cache{}
+	a, aLocal := newInstance(t, bus, shared)
+	b, _ := newInstance(t, bus, shared)
+	ctx := context.Background()
+
+	if err := a.Set(ctx, "key", "v1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := b.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// An update on b is seen by a
+	if err := b.Set(ctx, "key", "v2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, err := a.Get(ctx, "key"); err != nil || val != "v2" {
+		t.Errorf("Expected v2, got %v and %v", val, err)
+	}
+
+	// Back-fills of the local layer don't evict the other instances
+	if val, err := aLocal.Get(ctx, "key"); err != nil || val != "v2" {
+		t.Fatalf("Expected a back-filled v2, got %v and %v", val, err)
+	}
+	if _, err := b.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, err := aLocal.Get(ctx, "key"); err != nil || val != "v2" {
+		t.Errorf("Expected the local copy to be kept, got %v and %v", val, err)
+	}
+}
+
+// TestCache_OwnMessages tests that an instance ignores its own messages.
+func TestCache_OwnMessages(t *testing.T) {
+	bus := &Bus{}
+	local := &sample.Cache{}
+	cache, err := New(local, bus, WithLocal(local))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer cache.Close()
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, err := local.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected value, got %v and %v", val, err)
+	}
+}
+
+// TestCache_Close tests that a closed instance stops receiving messages.
+func TestCache_Close(t *testing.T) {
+	bus := &Bus{}
+	local := &sample.Cache{}
+	closed, err := New(local, bus, WithLocal(local))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := closed.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	otherLocal := &sample.Cache{}
+	other, err := New(otherLocal, bus, WithLocal(otherLocal))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer other.Close()
+	ctx := context.Background()
+
+	if err := local.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := other.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, err := local.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected the closed instance to keep its value, got %v and %v", val, err)
+	}
+}
+
+// TestCache_BadMessage tests that undecodable messages are reported.
+func TestCache_BadMessage(t *testing.T) {
+	var handled error
+	bus := &Bus{}
+	local := &sample.Cache{}
+	cache, err := New(local, bus, WithLocal(local), WithErrorHandler(func(err error) { handled = err }))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer cache.Close()
+
+	if err := bus.Publish(context.Background(), []byte("not json")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if handled == nil {
+		t.Error("Expected the error handler to be called")
+	}
+}
+
+// TestCache_SharedLayer tests that the invalidations of a peer keep the shared layer.
+func TestCache_SharedLayer(t *testing.T) {
+	bus := &Bus{}
+	shared := &sample.Cache{}
+	a, _ := newInstance(t, bus, shared)
+	_, bLocal := newInstance(t, bus, shared)
+	ctx := context.Background()
+
+	_ = bLocal.Set(ctx, "key", "v0")
+	if err := a.Set(ctx, "key", "v1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := bLocal.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the local copy of b to be evicted, got %v", err)
+	}
+	if val, err := shared.Get(ctx, "key"); err != nil || val != "v1" {
+		t.Errorf("Expected the shared layer to keep v1, got %v and %v", val, err)
+	}
+}
+
+// TestNew_WithoutLocal tests that New requires the local cache.
+func TestNew_WithoutLocal(t *testing.T) {
+	if _, err := New(&sample.Cache{}, &Bus{}); err == nil {
+		t.Error("Expected an error without WithLocal")
+	}
+}
@@ -0,0 +1,446 @@
+// Package loader provides a read-through cache that loads missing values
+// with a pluggable Loader function.
+//
+// This package implements the gouache.Cache interface by wrapping an existing
+// cache. On a cache miss the value is loaded, stored in the cache and returned.
+// Concurrent loads of the same key are coalesced with singleflight, and the
+// ErrorPolicy decides what happens when a load fails.
+package loader
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+	"golang.org/x/sync/singleflight"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Loader is a function type that loads the value for a key that is missing
+// from the cache, typically from a database or a remote service.
+type Loader func(ctx context.Context, key string) (any, error)
+
+// ErrorPolicy decides how the cache reacts when the Loader returns an error.
+// gouache.ErrNotFound is not a load failure and is never subject to the policy,
+// see WithNegativeTTL.
+type ErrorPolicy int
+
+const (
+	// PropagateErrors returns load errors to the caller. The next call for
+	// the same key tries to load the value again. This is the default policy.
+	PropagateErrors ErrorPolicy = iota
+
+	// CacheErrors returns load errors to the caller and remembers them for the
+	// error TTL, so that calls for the same key fail fast without calling the
+	// Loader until the error expires.
+	CacheErrors
+
+	// ServeStale returns the last successfully loaded value for the key when
+	// a load fails, and the load error only if there is no such value.
+	ServeStale
+)
+
+// options holds configuration options for the read-through cache.
+type options struct {
+	// TTL determines the time-to-live of the entries populated by the Loader.
+	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
+
+	// ErrorPolicy decides what happens when the Loader returns an error.
+	ErrorPolicy ErrorPolicy
+
+	// ErrorTTL is how long load errors are remembered with CacheErrors.
+	ErrorTTL time.Duration
+
+	// StaleCache keeps the last loaded value of the keys for ServeStale.
+	StaleCache gouache.Cache
+
+	// NegativeTTL is the time-to-live of the gouache.Absent entries remembering
+	// keys the Loader doesn't have, zero disables negative caching.
+	NegativeTTL time.Duration
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithTTL returns an Option that sets a function to determine the
+// time-to-live of the cache entries populated by the Loader.
+// If not set, the entries are stored with the underlying cache's Set and
+// its own expiration policy applies.
+//
+// Parameters:
+//   - ttl: A function that returns the time-to-live for a key and value
+//
+// Returns:
+//   - An Option function that sets the TTL
+func WithTTL(ttl func(ctx context.Context, key string, val any) (time.Duration, error)) Option {
+	return func(o *options) {
+		o.TTL = ttl
+	}
+}
+
+// WithErrorPolicy returns an Option that sets how load errors are handled.
+//
+// Parameters:
+//   - policy: The policy to apply when the Loader returns an error
+//
+// Returns:
+//   - An Option function that sets the ErrorPolicy
+func WithErrorPolicy(policy ErrorPolicy) Option {
+	return func(o *options) {
+		o.ErrorPolicy = policy
+	}
+}
+
+// WithErrorTTL returns an Option that sets how long load errors are
+// remembered when the CacheErrors policy is used.
+//
+// Parameters:
+//   - dur: The duration load errors are remembered for
+//
+// Returns:
+//   - An Option function that sets the ErrorTTL
+func WithErrorTTL(dur time.Duration) Option {
+	return func(o *options) {
+		o.ErrorTTL = dur
+	}
+}
+
+// WithStaleCache returns an Option that sets the cache holding the last
+// loaded value of the keys when the ServeStale policy is used.
+// Its entries should outlive the ones in the main cache, e.g. a bounded
+// lru.Cache without expiration. If not set, an in-memory LRU cache keeping
+// the last loaded value of the 10000 most recently used keys is used.
+// It is neither read nor written with the other policies.
+//
+// Parameters:
+//   - c: The cache holding stale values
+//
+// Returns:
+//   - An Option function that sets the StaleCache
+func WithStaleCache(c gouache.Cache) Option {
+	return func(o *options) {
+		o.StaleCache = c
+	}
+}
+
+// WithNegativeTTL returns an Option that enables negative caching.
+// When the Loader returns gouache.ErrNotFound for a key, gouache.Absent is
+// stored in the cache for the given duration, and lookups of the key return
+// gouache.ErrNotFound without calling the Loader until it expires.
+//
+// Parameters:
+//   - dur: The time-to-live of absent entries, zero or less to disable negative caching
+//
+// Returns:
+//   - An Option function that sets the NegativeTTL
+func WithNegativeTTL(dur time.Duration) Option {
+	return func(o *options) {
+		o.NegativeTTL = dur
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Set default error TTL to 1s if not specified or invalid
+	if o.ErrorTTL <= 0 {
+		o.ErrorTTL = time.Second
+	}
+
+	// Keep a bounded number of stale values in memory if serving stale
+	// values without a stale cache
+	if o.ErrorPolicy == ServeStale && o.StaleCache == nil {
+		o.StaleCache = newStaleCache(defaultStaleCapacity)
+	}
+	return o
+}
+
+// Cache is a read-through cache that loads missing values with a Loader.
+type Cache struct {
+	// options contains configuration options for the cache
+	options *options
+
+	// cache is the underlying cache implementation
+	cache gouache.Cache
+
+	// loader loads the values missing from the cache
+	loader Loader
+
+	// group is the singleflight group used to coalesce loads of the same key.
+	group singleflight.Group
+
+	// errs remembers load errors when the CacheErrors policy is used.
+	// Errors never read again are purged as errors keep being remembered,
+	// see sample.PurgeEvery.
+	errs sample.Cache
+}
+
+// New creates a new read-through cache with the specified cache, loader and options.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - l: The function loading values missing from the cache
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A read-through cache
+func New(c gouache.Cache, l Loader, opts ...Option) *Cache {
+	return &Cache{options: newOptions(opts...), cache: c, loader: l}
+}
+
+// Middleware returns a gouache.Middleware loading the missing keys of the next cache with a Loader.
+//
+// Parameters:
+//   - l: The loader of the missing values
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A middleware wrapping the next cache with New
+func Middleware(l Loader, opts ...Option) gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		return New(next, l, opts...)
+	}
+}
+
+// GetOrLoad retrieves a value from the cache by its key. If the value is not
+// found in the cache, it is loaded with the Loader and stored in the cache.
+//
+// Concurrent calls for the same missing key share a single load.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached or loaded value
+//   - An error if the operation or the load fails, depending on the ErrorPolicy,
+//     or gouache.ErrNotFound if the key is known to be absent
+func (cache *Cache) GetOrLoad(ctx context.Context, key string) (any, error) {
+	// Try to get the value from cache first
+	val, err := cache.cache.Get(ctx, key)
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		// Return cache value or error
+		if err != nil {
+			return nil, err
+		}
+		if gouache.IsAbsent(val) {
+			return nil, gouache.ErrNotFound
+		}
+		return val, nil
+	}
+
+	// Fail fast if a recent load of this key failed
+	if cache.options.ErrorPolicy == CacheErrors {
+		if loadErr, err := cache.errs.Get(ctx, key); err == nil {
+			return nil, loadErr.(error)
+		}
+	}
+
+	// Use singleflight to ensure only one load for this key runs at a time
+	val, err, _ = cache.group.Do(key, func() (any, error) {
+		return cache.load(ctx, key)
+	})
+	return val, err
+}
+
+// Get is an alias of GetOrLoad, so that Cache can be used as a gouache.Cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached or loaded value
+//   - An error if the operation or the load fails, depending on the ErrorPolicy,
+//     or gouache.ErrNotFound if the key is known to be absent
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	return cache.GetOrLoad(ctx, key)
+}
+
+// Set stores a value in the cache under the specified key and forgets any
+// load error remembered for it.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	// Forget any remembered load error
+	_ = cache.errs.Delete(ctx, key)
+
+	// Keep the stale copy up to date
+	if cache.options.ErrorPolicy == ServeStale {
+		if err := cache.options.StaleCache.Set(ctx, key, val); err != nil {
+			return err
+		}
+	}
+
+	// Delegate to the underlying cache
+	return cache.cache.Set(ctx, key, val)
+}
+
+// Delete removes a value from the cache by its key, together with any load
+// error or stale value remembered for it.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	// Forget any remembered load error
+	_ = cache.errs.Delete(ctx, key)
+
+	// A deleted value must not be served as stale value
+	if cache.options.ErrorPolicy == ServeStale {
+		if err := cache.options.StaleCache.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	// Delegate to the underlying cache
+	return cache.cache.Delete(ctx, key)
+}
+
+// load loads the value for a key with the Loader and stores it in the cache.
+// Load errors are handled according to the ErrorPolicy.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to load the value for
+//
+// Returns:
+//   - The loaded value, or a stale value with ServeStale
+//   - An error if the load fails, or the value cannot be stored
+func (cache *Cache) load(ctx context.Context, key string) (any, error) {
+	// Load the value
+	val, err := cache.loader(ctx, key)
+	if errors.Is(err, gouache.ErrNotFound) {
+		return nil, cache.handleNotFound(ctx, key, err)
+	}
+	if err != nil {
+		return cache.handleError(ctx, key, err)
+	}
+
+	// Remember the value in case a later load fails
+	if cache.options.ErrorPolicy == ServeStale {
+		if err := cache.options.StaleCache.Set(ctx, key, val); err != nil {
+			return nil, err
+		}
+	}
+
+	// Populate cache with the loaded value
+	return val, cache.fill(ctx, key, val)
+}
+
+// handleError applies the ErrorPolicy to a load error.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key whose load failed
+//   - err: The load error
+//
+// Returns:
+//   - A stale value with ServeStale if there is one
+//   - The load error otherwise
+func (cache *Cache) handleError(ctx context.Context, key string, err error) (any, error) {
+	switch cache.options.ErrorPolicy {
+	case CacheErrors:
+		// Remember the error so that the next calls fail fast
+		_ = cache.errs.SetWithTTL(ctx, key, err, cache.options.ErrorTTL)
+	case ServeStale:
+		// Fall back to the last loaded value
+		if val, staleErr := cache.options.StaleCache.Get(ctx, key); staleErr == nil {
+			return val, nil
+		}
+	}
+	return nil, err
+}
+
+// handleNotFound forgets the stale value of a key the Loader doesn't have,
+// and remembers the key as absent if negative caching is enabled.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key the Loader doesn't have
+//   - err: The gouache.ErrNotFound error returned by the Loader
+//
+// Returns:
+//   - The Loader's error, or an error if the cache cannot be updated
+func (cache *Cache) handleNotFound(ctx context.Context, key string, err error) error {
+	// A value that no longer exists must not be served as stale value
+	if cache.options.ErrorPolicy == ServeStale {
+		if staleErr := cache.options.StaleCache.Delete(ctx, key); staleErr != nil {
+			return staleErr
+		}
+	}
+
+	// Remember the key as absent so that it doesn't reach the Loader again
+	if cache.options.NegativeTTL > 0 {
+		if setErr := gouache.SetWithTTL(ctx, cache.cache, key, gouache.Absent, cache.options.NegativeTTL); setErr != nil {
+			return setErr
+		}
+	}
+	return err
+}
+
+// fill populates the cache with a loaded value, using the time-to-live
+// returned by the TTL option if it is set.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The loaded value
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) fill(ctx context.Context, key string, val any) error {
+	// Without a TTL option, let the cache apply its own expiration policy
+	if cache.options.TTL == nil {
+		return cache.cache.Set(ctx, key, val)
+	}
+
+	// Determine the TTL and store the value with it
+	ttl, err := cache.options.TTL(ctx, key, val)
+	if err != nil {
+		return err
+	}
+	return gouache.SetWithTTL(ctx, cache.cache, key, val, ttl)
+}
@@ -0,0 +1,309 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// countingLoader is a loader that counts its calls and returns the
+// value or error currently configured.
+type countingLoader struct {
+	calls atomic.Int32
+	delay time.Duration
+	mu    sync.Mutex
+	val   any
+	err   error
+}
+
+func (l *countingLoader) Load(ctx context.Context, key string) (any, error) {
+	l.calls.Add(1)
+	if l.delay > 0 {
+		time.Sleep(l.delay)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.val, l.err
+}
+
+func (l *countingLoader) set(val any, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.val, l.err = val, err
+}
+
+// TestCache_GetOrLoad tests that missing values are loaded and stored in the cache.
+func TestCache_GetOrLoad(t *testing.T) {
+	underlying := &sample.Cache{}
+	l := &countingLoader{val: "loaded"}
+	cache := New(underlying, l.Load)
+	ctx := context.Background()
+
+	// The first call loads the value
+	val, err := cache.GetOrLoad(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val != "loaded" {
+		t.Errorf("Expected loaded, got %v", val)
+	}
+
+	// The value is now in the underlying cache
+	if val, err := underlying.Get(ctx, "key"); err != nil || val != "loaded" {
+		t.Errorf("Expected loaded in the underlying cache, got %v and %v", val, err)
+	}
+
+	// The second call is served from the cache
+	if _, err := cache.GetOrLoad(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls := l.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 load, got %d", calls)
+	}
+}
+
+// TestCache_GetOrLoad_Coalesce tests that concurrent loads of the same key are coalesced.
+func TestCache_GetOrLoad_Coalesce(t *testing.T) {
+	l := &countingLoader{val: "loaded", delay: 50 * time.Millisecond}
+	cache := New(&sample.Cache{}, l.Load)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.GetOrLoad(context.Background(), "key"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := l.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 load, got %d", calls)
+	}
+}
+
+// TestCache_WithTTL tests that loaded values are stored with the configured TTL.
+func TestCache_WithTTL(t *testing.T) {
+	underlying := &sample.Cache{}
+	l := &countingLoader{val: "loaded"}
+	cache := New(underlying, l.Load, WithTTL(func(ctx context.Context, key string, val any) (time.Duration, error) {
+		return time.Minute, nil
+	}))
+	ctx := context.Background()
+
+	if _, err := cache.GetOrLoad(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, ttl, err := underlying.GetWithTTL(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected a remaining TTL within a minute, got %v", ttl)
+	}
+}
+
+// TestCache_PropagateErrors tests the default error policy.
+func TestCache_PropagateErrors(t *testing.T) {
+	loadErr := errors.New("load error")
+	l := &countingLoader{err: loadErr}
+	cache := New(&sample.Cache{}, l.Load)
+	ctx := context.Background()
+
+	// Every call tries to load again and returns the error
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetOrLoad(ctx, "key"); !errors.Is(err, loadErr) {
+			t.Errorf("Expected load error, got %v", err)
+		}
+	}
+	if calls := l.calls.Load(); calls != 2 {
+		t.Errorf("Expected 2 loads, got %d", calls)
+	}
+}
+
+// TestCache_CacheErrors tests that load errors are remembered for the error TTL.
+func TestCache_CacheErrors(t *testing.T) {
+	loadErr := errors.New("load error")
+	l := &countingLoader{err: loadErr}
+	cache := New(&sample.Cache{}, l.Load, WithErrorPolicy(CacheErrors), WithErrorTTL(20*time.Millisecond))
+	ctx := context.Background()
+
+	// The second call fails fast with the remembered error
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetOrLoad(ctx, "key"); !errors.Is(err, loadErr) {
+			t.Errorf("Expected load error, got %v", err)
+		}
+	}
+	if calls := l.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 load, got %d", calls)
+	}
+
+	// Once the error expires the value is loaded again
+	time.Sleep(30 * time.Millisecond)
+	l.set("loaded", nil)
+	val, err := cache.GetOrLoad(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val != "loaded" {
+		t.Errorf("Expected loaded, got %v", val)
+	}
+}
+
+// TestCache_ServeStale tests that the last loaded value is served when a load fails.
+func TestCache_ServeStale(t *testing.T) {
+	underlying := &sample.Cache{}
+	l := &countingLoader{val: "v1"}
+	cache := New(underlying, l.Load, WithErrorPolicy(ServeStale))
+	ctx := context.Background()
+
+	// Load a first value
+	if _, err := cache.GetOrLoad(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Expire the value from the main cache and make the loader fail
+	_ = underlying.Delete(ctx, "key")
+	loadErr := errors.New("load error")
+	l.set(nil, loadErr)
+
+	// The stale value is served instead of the error
+	val, err := cache.GetOrLoad(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val != "v1" {
+		t.Errorf("Expected stale v1, got %v", val)
+	}
+
+	// Without a stale value the error is returned
+	if _, err := cache.GetOrLoad(ctx, "other"); !errors.Is(err, loadErr) {
+		t.Errorf("Expected load error, got %v", err)
+	}
+
+	// A deleted value is not served as stale value
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := cache.GetOrLoad(ctx, "key"); !errors.Is(err, loadErr) {
+		t.Errorf("Expected load error, got %v", err)
+	}
+}
+
+// TestCache_StaleCache tests that the default stale cache is bounded, and that
+// the stale cache is only written with ServeStale.
+func TestCache_StaleCache(t *testing.T) {
+	ctx := context.Background()
+	l := &countingLoader{val: "value"}
+	cache := New(&sample.Cache{}, l.Load, WithErrorPolicy(ServeStale))
+	for i := 0; i < defaultStaleCapacity+10; i++ {
+		_, _ = cache.GetOrLoad(ctx, fmt.Sprintf("key%d", i))
+	}
+	stale := cache.options.StaleCache.(*staleCache)
+	if n := stale.lru.Len(); n != defaultStaleCapacity || len(stale.entries) != defaultStaleCapacity {
+		t.Errorf("Expected %d stale values, got %d", defaultStaleCapacity, n)
+	}
+	if _, err := stale.Get(ctx, "key0"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the least recently used key to be evicted, got %v", err)
+	}
+
+	// Other policies neither create nor write a stale cache
+	explicit := &sample.Cache{}
+	for _, policy := range []ErrorPolicy{PropagateErrors, CacheErrors} {
+		cache := New(&sample.Cache{}, l.Load, WithErrorPolicy(policy), WithStaleCache(explicit))
+		_, _ = cache.GetOrLoad(ctx, "key")
+		_ = cache.Set(ctx, "other", "value")
+	}
+	if n := explicit.Stats().Sets; n != 0 {
+		t.Errorf("Expected no stale value to be written, got %d", n)
+	}
+	if New(&sample.Cache{}, l.Load).options.StaleCache != nil {
+		t.Error("Expected no default stale cache without ServeStale")
+	}
+}
+
+// TestCache_CacheErrors_Purge tests that remembered errors never read again are purged.
+func TestCache_CacheErrors_Purge(t *testing.T) {
+	ctx := context.Background()
+	l := &countingLoader{err: errors.New("load error")}
+	cache := New(&sample.Cache{}, l.Load, WithErrorPolicy(CacheErrors), WithErrorTTL(time.Millisecond))
+	for i := 0; i < sample.PurgeEvery-1; i++ {
+		_, _ = cache.GetOrLoad(ctx, fmt.Sprintf("key%d", i))
+	}
+	time.Sleep(5 * time.Millisecond)
+	_, _ = cache.GetOrLoad(ctx, "last")
+	if n := cache.errs.Stats().Entries; n != 1 {
+		t.Errorf("Expected the expired errors to be purged, got %d entries", n)
+	}
+}
+
+// TestCache_GetError tests that errors other than cache misses are not loaded over.
+func TestCache_GetError(t *testing.T) {
+	l := &countingLoader{val: "loaded"}
+	cache := New(&failingCache{}, l.Load)
+
+	if _, err := cache.GetOrLoad(context.Background(), "key"); err == nil || errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the cache error, got %v", err)
+	}
+	if calls := l.calls.Load(); calls != 0 {
+		t.Errorf("Expected no load, got %d", calls)
+	}
+}
+
+// failingCache is a cache implementation that always returns an error for testing purposes.
+type failingCache struct{}
+
+func (c *failingCache) Get(ctx context.Context, key string) (any, error) {
+	return nil, errors.New("intentional error")
+}
+
+func (c *failingCache) Set(ctx context.Context, key string, val any) error {
+	return errors.New("intentional error")
+}
+
+func (c *failingCache) Delete(ctx context.Context, key string) error {
+	return errors.New("intentional error")
+}
+
+// TestCache_WithNegativeTTL tests that keys the Loader doesn't have are remembered as absent.
+func TestCache_WithNegativeTTL(t *testing.T) {
+	underlying := &sample.Cache{}
+	l := &countingLoader{err: gouache.ErrNotFound}
+	cache := New(underlying, l.Load, WithNegativeTTL(20*time.Millisecond))
+	ctx := context.Background()
+
+	// Repeated lookups of the absent key only reach the Loader once
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetOrLoad(ctx, "key"); !errors.Is(err, gouache.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if calls := l.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 load, got %d", calls)
+	}
+
+	// The absent sentinel is stored in the underlying cache
+	if val, err := underlying.Get(ctx, "key"); err != nil || !gouache.IsAbsent(val) {
+		t.Errorf("Expected gouache.Absent in the underlying cache, got %v and %v", val, err)
+	}
+
+	// Once the absent entry expires the Loader is called again
+	time.Sleep(30 * time.Millisecond)
+	l.set("loaded", nil)
+	val, err := cache.GetOrLoad(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val != "loaded" {
+		t.Errorf("Expected loaded, got %v", val)
+	}
+}
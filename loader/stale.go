@@ -0,0 +1,110 @@
+package loader
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/go-leo/gouache"
+)
+
+// Ensure that staleCache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*staleCache)(nil)
+
+// defaultStaleCapacity is the number of keys kept by the default stale cache.
+const defaultStaleCapacity = 10000
+
+// staleEntry is an entry of the stale cache.
+type staleEntry struct {
+	// key is the key of the entry
+	key string
+
+	// val is the last loaded value of the key
+	val any
+}
+
+// staleCache is the default stale cache used with ServeStale: a bounded
+// in-memory LRU cache whose entries never expire.
+type staleCache struct {
+	// capacity is the maximum number of keys kept
+	capacity int
+
+	// mu guards entries and lru
+	mu sync.Mutex
+
+	// entries are the elements of lru by key
+	entries map[string]*list.Element
+
+	// lru holds the *staleEntry values, most recently used first
+	lru *list.List
+}
+
+// newStaleCache creates a stale cache keeping at most capacity keys.
+func newStaleCache(capacity int) *staleCache {
+	return &staleCache{capacity: capacity, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// Get retrieves the last loaded value of a key and marks it as recently used.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The stale value or nil if not found
+//   - gouache.ErrCacheMiss if key doesn't exist
+func (cache *staleCache) Get(ctx context.Context, key string) (any, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	elem, ok := cache.entries[key]
+	if !ok {
+		return nil, gouache.ErrCacheMiss
+	}
+	cache.lru.MoveToFront(elem)
+	return elem.Value.(*staleEntry).val, nil
+}
+
+// Set stores the last loaded value of a key, evicting the least recently
+// used keys beyond the capacity.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - Always returns nil
+func (cache *staleCache) Set(ctx context.Context, key string, val any) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if elem, ok := cache.entries[key]; ok {
+		elem.Value.(*staleEntry).val = val
+		cache.lru.MoveToFront(elem)
+		return nil
+	}
+	cache.entries[key] = cache.lru.PushFront(&staleEntry{key: key, val: val})
+
+	// Evict the least recently used keys beyond the capacity
+	for cache.lru.Len() > cache.capacity {
+		delete(cache.entries, cache.lru.Remove(cache.lru.Back()).(*staleEntry).key)
+	}
+	return nil
+}
+
+// Delete removes the last loaded value of a key.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - Always returns nil
+func (cache *staleCache) Delete(ctx context.Context, key string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if elem, ok := cache.entries[key]; ok {
+		cache.lru.Remove(elem)
+		delete(cache.entries, key)
+	}
+	return nil
+}
@@ -0,0 +1,573 @@
+// Package logging provides cache and database implementations that log every
+// operation with log/slog.
+//
+// This package implements the gouache.Cache and gouache.Database interfaces
+// by wrapping existing ones. Every operation emits a record with its
+// operation, key, outcome (hit, miss, ok or error) and duration. Records of
+// successful operations can be sampled, keys can be redacted or hashed, and
+// operations slower than a threshold are logged at a higher level.
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/go-leo/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchCache interface at compile time.
+var _ gouache.BatchCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
+// Ensure that Database implements the gouache.Database interface at compile time.
+var _ gouache.Database = (*Database)(nil)
+
+// Outcomes of the operations, logged as the "outcome" attribute.
+const (
+	hit     = "hit"
+	miss    = "miss"
+	ok      = "ok"
+	failure = "error"
+)
+
+// options holds configuration options for the logging cache and database.
+type options struct {
+	// Logger emits the records.
+	Logger *slog.Logger
+
+	// Level is the level of the records of successful operations.
+	Level slog.Level
+
+	// SlowThreshold is the duration above which operations are slow.
+	SlowThreshold time.Duration
+
+	// SlowLevel is the level of the records of slow operations.
+	SlowLevel slog.Level
+
+	// ErrorLevel is the level of the records of failed operations.
+	ErrorLevel slog.Level
+
+	// Sampler decides whether a successful operation is logged.
+	Sampler func(ctx context.Context, operation string, key string) bool
+
+	// Key transforms the keys before they are logged.
+	Key func(key string) string
+}
+
+// Option is a function that modifies the logging options.
+type Option func(*options)
+
+// WithLogger returns an Option that sets the logger emitting the records.
+// If not specified, slog.Default() is used.
+//
+// Parameters:
+//   - logger: The logger
+//
+// Returns:
+//   - An Option function that sets the Logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.Logger = logger
+	}
+}
+
+// WithLevel returns an Option that sets the level of the records of
+// successful operations, including misses. Defaults to slog.LevelDebug.
+//
+// Parameters:
+//   - level: The level of the records
+//
+// Returns:
+//   - An Option function that sets the Level
+func WithLevel(level slog.Level) Option {
+	return func(o *options) {
+		o.Level = level
+	}
+}
+
+// WithSlowThreshold returns an Option that escalates the records of the
+// operations slower than a threshold to another level. Slow operations are
+// always logged, regardless of the sampling.
+//
+// Parameters:
+//   - threshold: The duration above which an operation is slow, zero to disable
+//   - level: The level of the records of slow operations, e.g. slog.LevelWarn
+//
+// Returns:
+//   - An Option function that sets the SlowThreshold and SlowLevel
+func WithSlowThreshold(threshold time.Duration, level slog.Level) Option {
+	return func(o *options) {
+		o.SlowThreshold = threshold
+		o.SlowLevel = level
+	}
+}
+
+// WithErrorLevel returns an Option that sets the level of the records of
+// failed operations. Defaults to slog.LevelError. Failed operations are
+// always logged, regardless of the sampling.
+//
+// Parameters:
+//   - level: The level of the records of failed operations
+//
+// Returns:
+//   - An Option function that sets the ErrorLevel
+func WithErrorLevel(level slog.Level) Option {
+	return func(o *options) {
+		o.ErrorLevel = level
+	}
+}
+
+// WithSampler returns an Option that sets a function deciding whether a
+// successful, fast operation is logged. The key is empty for the operations
+// on several keys.
+//
+// Parameters:
+//   - sampler: A function returning true to log the operation
+//
+// Returns:
+//   - An Option function that sets the Sampler
+func WithSampler(sampler func(ctx context.Context, operation string, key string) bool) Option {
+	return func(o *options) {
+		o.Sampler = sampler
+	}
+}
+
+// WithSampleRate returns an Option that logs a random fraction of the
+// successful, fast operations.
+//
+// Parameters:
+//   - rate: The fraction of the operations logged, between 0 and 1
+//
+// Returns:
+//   - An Option function that sets the Sampler
+func WithSampleRate(rate float64) Option {
+	return WithSampler(func(ctx context.Context, operation string, key string) bool {
+		return rand.Float64() < rate
+	})
+}
+
+// WithKey returns an Option that sets a function transforming the keys before
+// they are logged, e.g. Redact or Hash for keys holding personal data.
+//
+// Parameters:
+//   - f: A function returning the logged form of a key
+//
+// Returns:
+//   - An Option function that sets the Key
+func WithKey(f func(key string) string) Option {
+	return func(o *options) {
+		o.Key = f
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{Level: slog.LevelDebug, ErrorLevel: slog.LevelError}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Use the default logger if not specified
+	if o.Logger == nil {
+		o.Logger = slog.Default()
+	}
+
+	// Log every operation if not specified
+	if o.Sampler == nil {
+		o.Sampler = func(ctx context.Context, operation string, key string) bool {
+			return true
+		}
+	}
+
+	// Log the keys as-is if not specified
+	if o.Key == nil {
+		o.Key = func(key string) string {
+			return key
+		}
+	}
+	return o
+}
+
+// Redact replaces every key with a fixed placeholder.
+//
+// Parameters:
+//   - key: The key to redact
+//
+// Returns:
+//   - The placeholder
+func Redact(key string) string {
+	return "[REDACTED]"
+}
+
+// Hash replaces a key with the first 16 hex digits of its SHA-256, so that
+// the records of a key can be correlated without revealing it.
+//
+// Parameters:
+//   - key: The key to hash
+//
+// Returns:
+//   - The hashed key
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Cache is a cache that logs every operation.
+type Cache struct {
+	// options contains configuration options for the cache
+	options *options
+
+	// cache is the underlying cache implementation
+	cache gouache.Cache
+}
+
+// New creates a new logging cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A logging cache
+func New(c gouache.Cache, opts ...Option) *Cache {
+	return &Cache{options: newOptions(opts...), cache: c}
+}
+
+// Middleware returns a gouache.Middleware logging the operations of the next
+// cache.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A middleware wrapping the next cache with New
+func Middleware(opts ...Option) gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		return New(next, opts...)
+	}
+}
+
+// Get retrieves a value from the underlying cache, logged as a "get" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	var val any
+	err := cache.options.log(ctx, "cache", "get", key, true, func() (err error) {
+		val, err = cache.cache.Get(ctx, key)
+		return err
+	})
+	return val, err
+}
+
+// Set stores a value in the underlying cache, logged as a "set" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	return cache.options.log(ctx, "cache", "set", key, false, func() error {
+		return cache.cache.Set(ctx, key, val)
+	})
+}
+
+// Delete removes a value from the underlying cache, logged as a "delete"
+// operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	return cache.options.log(ctx, "cache", "delete", key, false, func() error {
+		return cache.cache.Delete(ctx, key)
+	})
+}
+
+// GetMulti retrieves the values for the given keys from the underlying cache,
+// logged as a "get_multi" operation with the number of keys instead of the
+// keys, and the number of hits and misses. The outcome is a hit if every key
+// was found, a miss otherwise.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys to their cached values
+//   - An error if the operation fails
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	start := time.Now()
+	vals, err := gouache.GetMulti(ctx, cache.cache, keys)
+	duration := time.Since(start)
+
+	// A failed batch has no hits nor misses
+	if err != nil {
+		cache.options.write(ctx, "cache", "get_multi", "", failure, duration, err, slog.Int("keys", len(keys)))
+		return vals, err
+	}
+
+	// Count the keys found and missing
+	hits, misses := gouache.CountMulti(keys, vals)
+	outcome := hit
+	if misses > 0 {
+		outcome = miss
+	}
+	cache.options.write(ctx, "cache", "get_multi", "", outcome, duration, nil,
+		slog.Int("keys", len(keys)), slog.Int("hits", hits), slog.Int("misses", misses))
+	return vals, nil
+}
+
+// SetMulti stores every key/value pair of the given map in the underlying
+// cache, logged as a "set_multi" operation with the number of keys.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The key/value pairs to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	return cache.options.log(ctx, "cache", "set_multi", "", false, func() error {
+		return gouache.SetMulti(ctx, cache.cache, vals)
+	}, slog.Int("keys", len(vals)))
+}
+
+// DeleteMulti removes the values for the given keys from the underlying
+// cache, logged as a "delete_multi" operation with the number of keys.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	return cache.options.log(ctx, "cache", "delete_multi", "", false, func() error {
+		return gouache.DeleteMulti(ctx, cache.cache, keys)
+	}, slog.Int("keys", len(keys)))
+}
+
+// SetWithTTL stores a value with the given TTL in the underlying cache,
+// logged as a "set" operation with the TTL.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	return cache.options.log(ctx, "cache", "set", key, false, func() error {
+		return gouache.SetWithTTL(ctx, cache.cache, key, val, ttl)
+	}, slog.Duration("ttl", ttl))
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the
+// underlying cache, logged as a "get" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	var val any
+	var ttl time.Duration
+	err := cache.options.log(ctx, "cache", "get", key, true, func() (err error) {
+		val, ttl, err = gouache.GetWithTTL(ctx, cache.cache, key)
+		return err
+	})
+	return val, ttl, err
+}
+
+// Database is a database that logs every operation.
+type Database struct {
+	// options contains configuration options for the database
+	options *options
+
+	// database is the underlying database implementation
+	database gouache.Database
+}
+
+// NewDatabase creates a new logging database.
+//
+// Parameters:
+//   - d: The underlying database implementation
+//   - opts: Variable number of Option functions to configure the database
+//
+// Returns:
+//   - A logging database
+func NewDatabase(d gouache.Database, opts ...Option) *Database {
+	return &Database{options: newOptions(opts...), database: d}
+}
+
+// Select retrieves a record from the underlying database, logged as a
+// "select" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the record
+//
+// Returns:
+//   - The record
+//   - An error if the operation fails, or gouache.ErrNotFound if the record doesn't exist
+func (database *Database) Select(ctx context.Context, key string) (any, error) {
+	var val any
+	err := database.options.log(ctx, "database", "select", key, true, func() (err error) {
+		val, err = database.database.Select(ctx, key)
+		return err
+	})
+	return val, err
+}
+
+// Upsert inserts or updates a record in the underlying database, logged as an
+// "upsert" operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the record
+//   - val: The record
+//
+// Returns:
+//   - An error if the operation fails
+func (database *Database) Upsert(ctx context.Context, key string, val any) error {
+	return database.options.log(ctx, "database", "upsert", key, false, func() error {
+		return database.database.Upsert(ctx, key, val)
+	})
+}
+
+// Delete removes a record from the underlying database, logged as a "delete"
+// operation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the record
+//
+// Returns:
+//   - An error if the operation fails
+func (database *Database) Delete(ctx context.Context, key string) error {
+	return database.options.log(ctx, "database", "delete", key, false, func() error {
+		return database.database.Delete(ctx, key)
+	})
+}
+
+// log runs an operation and logs it.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - msg: The message of the record, "cache" or "database"
+//   - operation: The operation name
+//   - key: The key of the operation, empty for several keys
+//   - lookup: Whether the operation is a lookup, whose outcome is a hit or a miss
+//   - f: The operation
+//   - attrs: Additional attributes of the operation, e.g. the number of keys
+//
+// Returns:
+//   - The error of the operation
+func (o *options) log(ctx context.Context, msg string, operation string, key string, lookup bool, f func() error, attrs ...slog.Attr) error {
+	start := time.Now()
+	err := f()
+	duration := time.Since(start)
+
+	// Classify the outcome, misses are not failures
+	outcome := ok
+	switch {
+	case err == nil && lookup:
+		outcome = hit
+	case lookup && (errors.Is(err, gouache.ErrCacheMiss) || errors.Is(err, gouache.ErrNotFound)):
+		outcome = miss
+	case err != nil:
+		outcome = failure
+	}
+
+	o.write(ctx, msg, operation, key, outcome, duration, err, attrs...)
+	return err
+}
+
+// write logs an operation that ran.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - msg: The message of the record, "cache" or "database"
+//   - operation: The operation name
+//   - key: The key of the operation, empty for several keys
+//   - outcome: The outcome of the operation
+//   - duration: The latency of the operation
+//   - err: The error of the operation, logged if the outcome is a failure
+//   - attrs: Additional attributes of the operation, e.g. the number of keys
+func (o *options) write(ctx context.Context, msg string, operation string, key string, outcome string, duration time.Duration, err error, attrs ...slog.Attr) {
+	// Pick the level, sampling only the successful and fast operations
+	level := o.Level
+	switch {
+	case outcome == failure:
+		level = o.ErrorLevel
+	case o.SlowThreshold > 0 && duration >= o.SlowThreshold:
+		level = o.SlowLevel
+	case !o.Logger.Enabled(ctx, level) || !o.Sampler(ctx, operation, key):
+		return
+	}
+
+	record := make([]slog.Attr, 0, len(attrs)+5)
+	record = append(record, slog.String("operation", operation))
+	if key != "" {
+		record = append(record, slog.String("key", o.Key(key)))
+	}
+	record = append(record, attrs...)
+	record = append(record, slog.String("outcome", outcome), slog.Duration("duration", duration))
+	if outcome == failure {
+		record = append(record, slog.String("err", err.Error()))
+	}
+	o.Logger.LogAttrs(ctx, level, msg, record...)
+}
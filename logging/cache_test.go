@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// slowCache is a cache whose Get takes some time.
+type slowCache struct {
+	gouache.Cache
+	delay time.Duration
+}
+
+func (c *slowCache) Get(ctx context.Context, key string) (any, error) {
+	time.Sleep(c.delay)
+	return c.Cache.Get(ctx, key)
+}
+
+// mockDatabase is a database whose operations fail.
+type mockDatabase struct{}
+
+func (mockDatabase) Select(ctx context.Context, key string) (any, error) {
+	return nil, gouache.ErrNotFound
+}
+
+func (mockDatabase) Upsert(ctx context.Context, key string, val any) error {
+	return errors.New("upsert failed")
+}
+
+func (mockDatabase) Delete(ctx context.Context, key string) error {
+	return nil
+}
+
+// newLogger returns a logger writing text records of every level to buf.
+func newLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+// TestCache tests that every operation is logged with its outcome.
+func TestCache(t *testing.T) {
+	var buf bytes.Buffer
+	cache := New(&sample.Cache{}, WithLogger(newLogger(&buf)))
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "a", 1)
+	_, _ = cache.Get(ctx, "a")
+	_, _ = cache.Get(ctx, "b")
+	_, _ = cache.GetMulti(ctx, []string{"a", "b"})
+	_ = cache.SetWithTTL(ctx, "c", 3, time.Minute)
+
+	expected := `level=DEBUG msg=cache operation=set key=a outcome=ok
+level=DEBUG msg=cache operation=get key=a outcome=hit
+level=DEBUG msg=cache operation=get key=b outcome=miss
+level=DEBUG msg=cache operation=get_multi keys=2 hits=1 misses=1 outcome=miss
+level=DEBUG msg=cache operation=set key=c ttl=1m0s outcome=ok
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+// TestCache_Sampling tests that only successful, fast operations are sampled.
+func TestCache_Sampling(t *testing.T) {
+	var buf bytes.Buffer
+	cache := New(&slowCache{Cache: &sample.Cache{}, delay: 20 * time.Millisecond},
+		WithLogger(newLogger(&buf)),
+		WithSampleRate(0),
+		WithSlowThreshold(10*time.Millisecond, slog.LevelWarn),
+	)
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "a", 1)
+	_, _ = cache.Get(ctx, "a")
+
+	expected := "level=WARN msg=cache operation=get key=a outcome=hit\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+// TestCache_Key tests that keys are transformed before they are logged.
+func TestCache_Key(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := context.Background()
+
+	_ = New(&sample.Cache{}, WithLogger(newLogger(&buf)), WithKey(Redact)).Set(ctx, "user:alice", 1)
+	_ = New(&sample.Cache{}, WithLogger(newLogger(&buf)), WithKey(Hash)).Set(ctx, "user:alice", 1)
+
+	if strings.Contains(buf.String(), "alice") {
+		t.Errorf("Expected the keys to be hidden, got:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "key=[REDACTED]") || !strings.Contains(buf.String(), "key="+Hash("user:alice")) {
+		t.Errorf("Expected a redacted and a hashed key, got:\n%s", buf.String())
+	}
+	if len(Hash("user:alice")) != 16 || Hash("user:alice") == Hash("user:bob") {
+		t.Errorf("Expected distinct 16 digit hashes, got %s", Hash("user:alice"))
+	}
+}
+
+// TestDatabase tests that failures are logged at the error level regardless of the sampling.
+func TestDatabase(t *testing.T) {
+	var buf bytes.Buffer
+	database := NewDatabase(mockDatabase{}, WithLogger(newLogger(&buf)), WithLevel(slog.LevelInfo), WithSampleRate(0))
+	ctx := context.Background()
+
+	_, _ = database.Select(ctx, "a")
+	_ = database.Upsert(ctx, "a", 1)
+	_ = database.Delete(ctx, "a")
+
+	expected := "level=ERROR msg=database operation=upsert key=a outcome=error err=\"upsert failed\"\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -7,6 +7,9 @@ package lru
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-leo/gouache"
 	lrucache "github.com/hashicorp/golang-lru"
@@ -15,16 +18,86 @@ import (
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.BatchCache interface at compile time.
+var _ gouache.BatchCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.StatsCache interface at compile time.
+var _ gouache.StatsCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.ScanCache interface at compile time.
+var _ gouache.ScanCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.EvictionCache interface at compile time.
+var _ gouache.EvictionCache = (*Cache)(nil)
+
 // Cache is an implementation of gouache.Cache using LRU cache as the storage backend.
 // It provides methods for storing, retrieving, and deleting cached values with
 // LRU eviction policy when the cache reaches its capacity.
+//
+// Entries stored with SetWithTTL expire lazily: an expired entry is removed
+// the next time it is read, or by the purge run every PurgeEvery writes
+// with a TTL, or by DeleteExpired, or evicted earlier by the LRU policy.
 type Cache struct {
 	// Cache is the underlying LRU cache instance used for storage.
+	// The values are stored as unexported *entry wrappers holding the value
+	// together with its expiration time: values read directly from Cache are
+	// such wrappers. Values added directly to Cache are read as they are,
+	// and never expire.
 	Cache *lrucache.Cache
+
+	// counters counts the operations for Stats.
+	counters gouache.Counters
+
+	// ttlSets counts the writes with a TTL, to purge the expired entries
+	// every PurgeEvery of them.
+	ttlSets atomic.Int64
+
+	// mu guards evictHandlers
+	mu sync.RWMutex
+
+	// evictHandlers are the functions registered with OnEvict, called by the
+	// eviction callback of the LRU cache created by New.
+	evictHandlers []func(key string)
+}
+
+// New creates a Cache over a new LRU cache of the given size, whose eviction
+// callback calls the functions registered with OnEvict.
+//
+// Parameters:
+//   - size: The maximum number of entries
+//
+// Returns:
+//   - The cache
+//   - An error if the size is not positive
+func New(size int) (*Cache, error) {
+	cache := &Cache{}
+	lruCache, err := lrucache.NewWithEvict(size, cache.evicted)
+	if err != nil {
+		return nil, err
+	}
+	cache.Cache = lruCache
+	return cache, nil
+}
+
+// PurgeEvery is the number of writes with a TTL after which the expired
+// entries are purged, so that the expired entries never read again don't
+// occupy the LRU cache until they are evicted.
+const PurgeEvery = 1024
+
+// entry is a cached value together with its expiration time.
+type entry struct {
+	// val is the cached value.
+	val any
+
+	// expireAt is the time at which the entry expires, zero if it never expires.
+	expireAt time.Time
 }
 
 // Get retrieves a value from the cache by its key.
-// It returns gouache.ErrCacheMiss if the key does not exist.
+// It returns gouache.ErrCacheMiss if the key does not exist or has expired.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -34,8 +107,8 @@ type Cache struct {
 //   - The cached value or nil if not found
 //   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
 func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
-	// Attempt to get the value from the LRU cache
-	val, ok := cache.Cache.Get(key)
+	// Attempt to get the entry from the LRU cache
+	e, ok := cache.get(key)
 
 	// Handle case where entry is not found
 	if !ok {
@@ -43,10 +116,11 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 	}
 
 	// Return the found value
-	return val, nil
+	return e.val, nil
 }
 
 // Set stores a value in the cache with the given key.
+// The entry never expires, but may be evicted by the LRU policy.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -57,7 +131,7 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 //   - Always returns nil as LRU cache Add operation is always successful
 func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 	// Add the value to the LRU cache
-	_ = cache.Cache.Add(key, val)
+	cache.add(key, &entry{val: val})
 	return nil
 }
 
@@ -72,5 +146,245 @@ func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 func (cache *Cache) Delete(ctx context.Context, key string) error {
 	// Remove the value from the LRU cache
 	_ = cache.Cache.Remove(key)
+	cache.counters.AddDeletes(1)
+	return nil
+}
+
+// GetMulti retrieves the values for the given keys from the cache.
+// Keys that do not exist or have expired are omitted from the returned map.
+// Every found key is marked as recently used.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys to their cached values
+//   - Always returns a nil error as LRU cache Get operation doesn't return errors
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	vals := make(map[string]any, len(keys))
+	for _, key := range keys {
+		// Get every key and collect the ones that exist
+		if e, ok := cache.get(key); ok {
+			vals[key] = e.val
+		}
+	}
+	return vals, nil
+}
+
+// SetMulti stores every key/value pair of the given map in the cache.
+// The entries never expire, but may be evicted by the LRU policy.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The key/value pairs to store
+//
+// Returns:
+//   - Always returns nil as LRU cache Add operation is always successful
+func (cache *Cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	for key, val := range vals {
+		cache.add(key, &entry{val: val})
+	}
+	return nil
+}
+
+// DeleteMulti removes the values for the given keys from the cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - Always returns nil as LRU cache Remove operation doesn't return errors
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		_ = cache.Cache.Remove(key)
+	}
+	cache.counters.AddDeletes(int64(len(keys)))
+	return nil
+}
+
+// SetWithTTL stores a value in the cache with the given key,
+// the entry expires once the ttl has passed.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to store the value under
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - Always returns nil as LRU cache Add operation is always successful
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	e := &entry{val: val}
+
+	// Compute the expiration time, if any
+	if ttl > 0 {
+		e.expireAt = time.Now().Add(ttl)
+	}
+
+	// Add the entry to the LRU cache
+	cache.add(key, e)
+
+	// Purge the expired entries every PurgeEvery writes with a TTL
+	if ttl > 0 && cache.ttlSets.Add(1)%PurgeEvery == 0 {
+		cache.DeleteExpired()
+	}
 	return nil
 }
+
+// DeleteExpired removes every expired entry from the cache, and counts them
+// as evictions. Purging doesn't mark the keys as recently used.
+func (cache *Cache) DeleteExpired() {
+	now := time.Now()
+	for _, key := range cache.Cache.Keys() {
+		// Skip the keys removed since the listing and the live entries
+		val, ok := cache.Cache.Peek(key)
+		if !ok {
+			continue
+		}
+		if e := toEntry(val); !e.expireAt.IsZero() && !now.Before(e.expireAt) {
+			cache.remove(key, e)
+		}
+	}
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the cache.
+// It returns gouache.ErrCacheMiss if the key does not exist or has expired.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	// Attempt to get the entry from the LRU cache
+	e, ok := cache.get(key)
+	if !ok {
+		return nil, 0, gouache.ErrCacheMiss
+	}
+
+	// Entries without expiration report a zero TTL
+	if e.expireAt.IsZero() {
+		return e.val, 0, nil
+	}
+	return e.val, time.Until(e.expireAt), nil
+}
+
+// Stats returns the counters of the cache.
+// The evictions count the entries evicted by the LRU policy and the expired
+// entries removed on read, the bytes are unknown.
+//
+// Returns:
+//   - A snapshot of the counters
+func (cache *Cache) Stats() gouache.Stats {
+	stats := cache.counters.Stats()
+	stats.Entries = int64(cache.Cache.Len())
+	return stats
+}
+
+// Scan calls fn for every key of the cache, from the oldest to the newest,
+// skipping the expired entries. Scanning doesn't mark the keys as recently used.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - fn: The function called with every key
+//
+// Returns:
+//   - The error returned by fn
+func (cache *Cache) Scan(ctx context.Context, fn func(key string) error) error {
+	now := time.Now()
+	for _, key := range cache.Cache.Keys() {
+		// Skip the keys removed since the listing and the expired entries
+		val, ok := cache.Cache.Peek(key)
+		if !ok {
+			continue
+		}
+		if e := toEntry(val); !e.expireAt.IsZero() && !now.Before(e.expireAt) {
+			continue
+		}
+		if err := fn(key.(string)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnEvict registers a function called with the key of every entry removed
+// from the LRU cache: evicted by the LRU policy, expired or deleted.
+// Only the caches created by New call the registered functions, the eviction
+// callback of an LRU cache assigned to the Cache field can't be hooked.
+//
+// Parameters:
+//   - f: The function called with every removed key
+func (cache *Cache) OnEvict(f func(key string)) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.evictHandlers = append(cache.evictHandlers, f)
+}
+
+// evicted is the eviction callback of the LRU caches created by New.
+func (cache *Cache) evicted(key any, val any) {
+	k, ok := key.(string)
+	if !ok {
+		return
+	}
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	for _, f := range cache.evictHandlers {
+		f(k)
+	}
+}
+
+// add adds an entry to the LRU cache, and counts the set and the eviction it caused.
+func (cache *Cache) add(key string, e *entry) {
+	if cache.Cache.Add(key, e) {
+		cache.counters.AddEvictions(1)
+	}
+	cache.counters.AddSets(1)
+}
+
+// get returns the live entry stored under key and marks it as recently used,
+// and counts the lookup.
+// An expired entry is removed from the LRU cache and reported as not found.
+func (cache *Cache) get(key string) (*entry, bool) {
+	val, ok := cache.Cache.Get(key)
+	if !ok {
+		cache.counters.AddMisses(1)
+		return nil, false
+	}
+	e := toEntry(val)
+
+	// Entries without expiration are always live
+	if e.expireAt.IsZero() || time.Now().Before(e.expireAt) {
+		cache.counters.AddHits(1)
+		return e, true
+	}
+
+	// Remove the expired entry
+	cache.remove(key, e)
+	cache.counters.AddMisses(1)
+	return nil, false
+}
+
+// toEntry returns the entry of a value of the LRU cache. Values added
+// directly to the LRU cache are not wrapped, they never expire.
+func toEntry(val any) *entry {
+	if e, ok := val.(*entry); ok {
+		return e
+	}
+	return &entry{val: val}
+}
+
+// remove removes an expired entry from the LRU cache, unless it has been
+// replaced in the meantime, and counts the eviction.
+func (cache *Cache) remove(key any, e *entry) {
+	if current, ok := cache.Cache.Peek(key); ok && current == e {
+		if cache.Cache.Remove(key) {
+			cache.counters.AddEvictions(1)
+		}
+	}
+}
@@ -2,7 +2,11 @@ package lru
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/go-leo/gouache"
 	lru "github.com/hashicorp/golang-lru"
@@ -214,3 +218,229 @@ func TestCache_LRU_AccessOrder(t *testing.T) {
 		t.Errorf("Failed to get value3: %v", err)
 	}
 }
+
+// TestCache_Multi tests GetMulti, SetMulti and DeleteMulti operations
+func TestCache_Multi(t *testing.T) {
+	lruCache, err := lru.New(100)
+	if err != nil {
+		t.Fatalf("Failed to create LRU cache: %v", err)
+	}
+
+	cache := &Cache{
+		Cache: lruCache,
+	}
+
+	ctx := context.Background()
+
+	// Test SetMulti
+	err = cache.SetMulti(ctx, map[string]any{"key1": "value1", "key2": "value2"})
+	if err != nil {
+		t.Errorf("Failed to set values: %v", err)
+	}
+
+	// Test GetMulti with a missing key
+	vals, err := cache.GetMulti(ctx, []string{"key1", "key2", "key3"})
+	if err != nil {
+		t.Errorf("Failed to get values: %v", err)
+	}
+	if len(vals) != 2 || vals["key1"] != "value1" || vals["key2"] != "value2" {
+		t.Errorf("Expected key1 and key2 only, got %v", vals)
+	}
+
+	// Test DeleteMulti
+	err = cache.DeleteMulti(ctx, []string{"key1", "key2"})
+	if err != nil {
+		t.Errorf("Failed to delete values: %v", err)
+	}
+	if cache.Cache.Len() != 0 {
+		t.Errorf("Expected empty cache, got %d entries", cache.Cache.Len())
+	}
+}
+
+// TestCache_TTL tests SetWithTTL and GetWithTTL operations
+func TestCache_TTL(t *testing.T) {
+	lruCache, err := lru.New(100)
+	if err != nil {
+		t.Fatalf("Failed to create LRU cache: %v", err)
+	}
+
+	cache := &Cache{
+		Cache: lruCache,
+	}
+
+	ctx := context.Background()
+
+	// Test SetWithTTL
+	err = cache.SetWithTTL(ctx, "key1", "value1", 20*time.Millisecond)
+	if err != nil {
+		t.Errorf("Failed to set value with TTL: %v", err)
+	}
+
+	// Test GetWithTTL before expiration
+	result, ttl, err := cache.GetWithTTL(ctx, "key1")
+	if err != nil {
+		t.Errorf("Failed to get value: %v", err)
+	}
+	if result != "value1" {
+		t.Errorf("Expected value1, got %v", result)
+	}
+	if ttl <= 0 || ttl > 20*time.Millisecond {
+		t.Errorf("Expected a remaining TTL within 20ms, got %v", ttl)
+	}
+
+	// Wait for expiration
+	time.Sleep(30 * time.Millisecond)
+
+	// Test Get after expiration
+	_, err = cache.Get(ctx, "key1")
+	if err != gouache.ErrCacheMiss {
+		t.Errorf("Expected gouache.ErrCacheMiss for expired key, got %v", err)
+	}
+	if cache.Cache.Contains("key1") {
+		t.Error("Expected expired key to be removed")
+	}
+}
+
+// TestCache_DeleteExpired tests that the expired entries never read again are purged
+func TestCache_DeleteExpired(t *testing.T) {
+	lruCache, err := lru.New(10 * PurgeEvery)
+	if err != nil {
+		t.Fatalf("Failed to create LRU cache: %v", err)
+	}
+	cache := &Cache{Cache: lruCache}
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "forever", "value")
+	for i := 0; i < PurgeEvery-1; i++ {
+		_ = cache.SetWithTTL(ctx, fmt.Sprintf("key%d", i), i, time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// The next write with a TTL purges the expired entries
+	_ = cache.SetWithTTL(ctx, "live", "value", time.Minute)
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Evictions != PurgeEvery-1 {
+		t.Errorf("Expected 2 entries and %d evictions, got %+v", PurgeEvery-1, stats)
+	}
+
+	// DeleteExpired purges on demand
+	_ = cache.SetWithTTL(ctx, "short", "value", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	cache.DeleteExpired()
+	if stats := cache.Stats(); stats.Entries != 2 {
+		t.Errorf("Expected 2 entries, got %+v", stats)
+	}
+}
+
+// TestCache_Stats tests that the operations, evictions and entries are counted
+func TestCache_Stats(t *testing.T) {
+	lruCache, err := lru.New(2)
+	if err != nil {
+		t.Fatalf("Failed to create LRU cache: %v", err)
+	}
+	cache := &Cache{Cache: lruCache}
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "key1", "value1")
+	_ = cache.SetWithTTL(ctx, "short", "value2", time.Millisecond)
+
+	// Evicts key1
+	_ = cache.SetMulti(ctx, map[string]any{"key2": "value3"})
+	_, _ = cache.GetMulti(ctx, []string{"key1", "key2"})
+
+	// The expired entry is a miss and an eviction
+	time.Sleep(5 * time.Millisecond)
+	_, _ = cache.Get(ctx, "short")
+	_ = cache.Delete(ctx, "key2")
+
+	expected := gouache.Stats{Hits: 1, Misses: 2, Sets: 3, Deletes: 1, Evictions: 2, Entries: 0}
+	if stats := cache.Stats(); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+// TestCache_Scan tests that Scan lists the live keys and stops on the first error.
+func TestCache_Scan(t *testing.T) {
+	lruCache, err := lru.New(10)
+	if err != nil {
+		t.Fatalf("Failed to create LRU cache: %v", err)
+	}
+	cache := &Cache{Cache: lruCache}
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "a", "a")
+	_ = cache.Set(ctx, "b", "b")
+	_ = cache.SetWithTTL(ctx, "short", "a", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	var keys []string
+	err = cache.Scan(ctx, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	sort.Strings(keys)
+	if err != nil || len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Expected keys [a b], got %v, %v", keys, err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = cache.Scan(ctx, func(key string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected Scan to stop with the error of fn, got %v after %d calls", err, calls)
+	}
+}
+
+// TestCache_OnEvict tests that the registered functions are called with the removed keys.
+func TestCache_OnEvict(t *testing.T) {
+	cache, err := New(2)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+	var evicted []string
+	cache.OnEvict(func(key string) {
+		evicted = append(evicted, key)
+	})
+
+	// Evicted by the LRU policy, then expired
+	_ = cache.Set(ctx, "a", 1)
+	_ = cache.Set(ctx, "b", 2)
+	_ = cache.Set(ctx, "c", 3)
+	_ = cache.SetWithTTL(ctx, "d", 4, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, _ = cache.Get(ctx, "d")
+	if len(evicted) != 3 || evicted[0] != "a" || evicted[1] != "b" || evicted[2] != "d" {
+		t.Errorf("Expected [a b d] to be evicted, got %v", evicted)
+	}
+
+	if _, err := New(0); err == nil {
+		t.Error("Expected an error for a zero size")
+	}
+}
+
+// TestCache_ForeignValues tests that values added directly to the LRU cache are read as they are and never expire.
+func TestCache_ForeignValues(t *testing.T) {
+	lruCache, err := lru.New(100)
+	if err != nil {
+		t.Fatalf("Failed to create LRU cache: %v", err)
+	}
+	cache := &Cache{Cache: lruCache}
+	ctx := context.Background()
+	lruCache.Add("foreign", "value")
+
+	if val, ttl, err := cache.GetWithTTL(ctx, "foreign"); err != nil || val != "value" || ttl != 0 {
+		t.Errorf("Expected value without expiration, got %v, %v, %v", val, ttl, err)
+	}
+	cache.DeleteExpired()
+	var keys []string
+	_ = cache.Scan(ctx, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if len(keys) != 1 || keys[0] != "foreign" {
+		t.Errorf("Expected the foreign key to be scanned, got %v", keys)
+	}
+}
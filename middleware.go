@@ -0,0 +1,37 @@
+package gouache
+
+// Middleware wraps a Cache into another Cache that adds a behavior to it,
+// e.g. singleflight, logging or delay double delete.
+//
+// The wrapper packages of this module provide a Middleware function adapting
+// their constructor, so that stacks are declared with Chain. The exceptions
+// are the wrappers that can fail to start or hold resources to release:
+// invalidation.New returns an error and its cache must be closed, and the
+// redis NearCache wraps a redis Cache rather than any Cache and must be
+// closed too. Create them first and pass them as the base of Chain, or wrap
+// them in a middleware of your own.
+type Middleware func(next Cache) Cache
+
+// Chain wraps a base cache with middlewares. The first middleware is the
+// outermost one, it sees every call first:
+//
+//	cache := gouache.Chain(redisCache,
+//		ddd.Middleware(db),
+//		sf.Middleware(),
+//	)
+//
+// is the same as ddd.New(&sf.Cache{Cache: redisCache}, db).
+//
+// Parameters:
+//   - base: The innermost cache, usually a backend
+//   - mws: The middlewares, from the outermost to the innermost
+//
+// Returns:
+//   - The wrapped cache, base itself without middlewares
+func Chain(base Cache, mws ...Middleware) Cache {
+	cache := base
+	for i := len(mws) - 1; i >= 0; i-- {
+		cache = mws[i](cache)
+	}
+	return cache
+}
@@ -0,0 +1,68 @@
+package gouache_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+	"github.com/go-leo/gouache/sf"
+	"github.com/go-leo/gouache/tiered"
+)
+
+// tracingCache is a cache recording the order in which it is called.
+type tracingCache struct {
+	gouache.Cache
+	name  string
+	calls *[]string
+}
+
+func (c *tracingCache) Get(ctx context.Context, key string) (any, error) {
+	*c.calls = append(*c.calls, c.name)
+	return c.Cache.Get(ctx, key)
+}
+
+// tracing returns a middleware recording its calls under a name.
+func tracing(name string, calls *[]string) gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		return &tracingCache{Cache: next, name: name, calls: calls}
+	}
+}
+
+// TestChain tests that the first middleware is the outermost one.
+func TestChain(t *testing.T) {
+	var calls []string
+	cache := gouache.Chain(&sample.Cache{}, tracing("a", &calls), tracing("b", &calls), tracing("c", &calls))
+	_, _ = cache.Get(context.Background(), "key")
+	if len(calls) != 3 || calls[0] != "a" || calls[1] != "b" || calls[2] != "c" {
+		t.Errorf("Expected calls a, b, c, got %v", calls)
+	}
+
+	// Without middlewares the base is returned as-is
+	base := &sample.Cache{}
+	if gouache.Chain(base) != base {
+		t.Error("Expected the base cache")
+	}
+}
+
+// TestChain_Stack tests a stack of the middlewares of the wrapper packages.
+func TestChain_Stack(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := &sample.Cache{}, &sample.Cache{}
+	cache := gouache.Chain(l2,
+		sf.Middleware(),
+		tiered.Middleware(tiered.Layer{Cache: l1}),
+	)
+	if _, ok := cache.(*sf.Cache); !ok {
+		t.Fatalf("Expected the outermost cache to be a *sf.Cache, got %T", cache)
+	}
+
+	// Reads go through singleflight, then the tiers, and back-fill L1
+	_ = l2.Set(ctx, "key", "value")
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Fatalf("Expected value, got %v, %v", val, err)
+	}
+	if val, err := l1.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected L1 to be back-filled, got %v, %v", val, err)
+	}
+}
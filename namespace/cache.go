@@ -0,0 +1,405 @@
+// Package namespace provides a cache implementation that isolates the keys of
+// a namespace and lets the whole namespace be invalidated at once.
+//
+// This package implements the gouache.Cache interface by wrapping an existing
+// cache. Every key is prefixed with the namespace and its current version,
+// "name:v{version}:key". Bumping the version, a single operation on the
+// VersionStore, logically invalidates every entry of the namespace without
+// scanning keys: the entries of older versions are no longer addressed and
+// are left to expire.
+package namespace
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-leo/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchCache interface at compile time.
+var _ gouache.BatchCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
+// VersionStore stores the current version of namespaces.
+type VersionStore interface {
+	// Version returns the current version of the namespace, zero if it was never bumped.
+	Version(ctx context.Context, namespace string) (int64, error)
+
+	// Bump increments the version of the namespace and returns the new version.
+	Bump(ctx context.Context, namespace string) (int64, error)
+}
+
+// options holds configuration options for the namespace cache.
+type options struct {
+	// Store stores the version of the namespace.
+	Store VersionStore
+
+	// VersionTTL is how long a version read from the store is reused.
+	VersionTTL time.Duration
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithVersionStore returns an Option that sets the store of the namespace
+// version. Instances sharing a namespace must share the store, e.g. a Redis
+// version store. If not specified, an in-process MemoryVersionStore is used.
+//
+// Parameters:
+//   - store: The version store
+//
+// Returns:
+//   - An Option function that sets the Store
+func WithVersionStore(store VersionStore) Option {
+	return func(o *options) {
+		o.Store = store
+	}
+}
+
+// WithVersionTTL returns an Option that sets how long a version read from the
+// store is reused before it is read again, saving a round trip to a remote
+// store on every operation. Bumps by other instances are seen after at most
+// this duration, bumps by this instance immediately.
+//
+// Parameters:
+//   - dur: The duration a version is reused, zero to read it on every operation
+//
+// Returns:
+//   - An Option function that sets the VersionTTL
+func WithVersionTTL(dur time.Duration) Option {
+	return func(o *options) {
+		o.VersionTTL = dur
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Use an in-process store if not specified
+	if o.Store == nil {
+		o.Store = &MemoryVersionStore{}
+	}
+	return o
+}
+
+// version is a version read from the store.
+type version struct {
+	// value is the version
+	value int64
+
+	// expireAt is the time after which the version is read again
+	expireAt time.Time
+}
+
+// Cache is a cache that prefixes every key with a namespace and its version.
+type Cache struct {
+	// options contains configuration options for the cache
+	options *options
+
+	// cache is the underlying cache implementation
+	cache gouache.Cache
+
+	// name is the namespace
+	name string
+
+	// version is the last version read from the store
+	version atomic.Pointer[version]
+}
+
+// New creates a new namespace cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - name: The namespace, e.g. the service name and the schema of its values
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A namespace cache
+func New(c gouache.Cache, name string, opts ...Option) *Cache {
+	return &Cache{options: newOptions(opts...), cache: c, name: name}
+}
+
+// Middleware returns a gouache.Middleware prefixing the keys of the next cache
+// with a namespace and its version. Bump is reached by asserting the returned
+// cache to *Cache.
+//
+// Parameters:
+//   - name: The namespace
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A middleware wrapping the next cache with New
+func Middleware(name string, opts ...Option) gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		return New(next, name, opts...)
+	}
+}
+
+// Get retrieves a value of the current version of the namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the version cannot be read or the operation fails
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	prefix, err := cache.prefix(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cache.cache.Get(ctx, prefix+key)
+}
+
+// Set stores a value in the current version of the namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the version cannot be read or the operation fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	prefix, err := cache.prefix(ctx)
+	if err != nil {
+		return err
+	}
+	return cache.cache.Set(ctx, prefix+key, val)
+}
+
+// Delete removes a value from the current version of the namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the version cannot be read or the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	prefix, err := cache.prefix(ctx)
+	if err != nil {
+		return err
+	}
+	return cache.cache.Delete(ctx, prefix+key)
+}
+
+// GetMulti retrieves the values for the given keys from the current version
+// of the namespace. Keys that do not exist are omitted from the returned map.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys, without prefix, to their cached values
+//   - An error if the version cannot be read or the operation fails
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	prefix, err := cache.prefix(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = prefix + key
+	}
+	found, err := gouache.GetMulti(ctx, cache.cache, prefixed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Strip the prefix from the found keys
+	vals := make(map[string]any, len(found))
+	for key, val := range found {
+		vals[key[len(prefix):]] = val
+	}
+	return vals, nil
+}
+
+// SetMulti stores every key/value pair of the given map in the current
+// version of the namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The key/value pairs to store
+//
+// Returns:
+//   - An error if the version cannot be read or the operation fails
+func (cache *Cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	prefix, err := cache.prefix(ctx)
+	if err != nil {
+		return err
+	}
+	prefixed := make(map[string]any, len(vals))
+	for key, val := range vals {
+		prefixed[prefix+key] = val
+	}
+	return gouache.SetMulti(ctx, cache.cache, prefixed)
+}
+
+// DeleteMulti removes the values for the given keys from the current version
+// of the namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the version cannot be read or the operation fails
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	prefix, err := cache.prefix(ctx)
+	if err != nil {
+		return err
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = prefix + key
+	}
+	return gouache.DeleteMulti(ctx, cache.cache, prefixed)
+}
+
+// SetWithTTL stores a value with the given TTL in the current version of the namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if the version cannot be read or the operation fails
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	prefix, err := cache.prefix(ctx)
+	if err != nil {
+		return err
+	}
+	return gouache.SetWithTTL(ctx, cache.cache, prefix+key, val, ttl)
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the
+// current version of the namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if the version cannot be read or the operation fails
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	prefix, err := cache.prefix(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return gouache.GetWithTTL(ctx, cache.cache, prefix+key)
+}
+
+// Version returns the current version of the namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//
+// Returns:
+//   - The current version
+//   - An error if the version cannot be read
+func (cache *Cache) Version(ctx context.Context) (int64, error) {
+	// Reuse the last version read while it is fresh
+	if v := cache.version.Load(); v != nil && time.Now().Before(v.expireAt) {
+		return v.value, nil
+	}
+
+	value, err := cache.options.Store.Version(ctx, cache.name)
+	if err != nil {
+		return 0, err
+	}
+	return cache.remember(value), nil
+}
+
+// Bump increments the version of the namespace, which logically invalidates
+// all its entries in O(1).
+//
+// Parameters:
+//   - ctx: Context for the operation
+//
+// Returns:
+//   - The new version
+//   - An error if the version cannot be incremented
+func (cache *Cache) Bump(ctx context.Context) (int64, error) {
+	value, err := cache.options.Store.Bump(ctx, cache.name)
+	if err != nil {
+		return 0, err
+	}
+	return cache.remember(value), nil
+}
+
+// remember keeps a version read from the store for the VersionTTL, unless a
+// newer version is remembered and still fresh, e.g. a read that started
+// before a concurrent Bump.
+//
+// Parameters:
+//   - value: The version read from the store
+//
+// Returns:
+//   - The version to use, the remembered one if it is newer
+func (cache *Cache) remember(value int64) int64 {
+	if cache.options.VersionTTL <= 0 {
+		return value
+	}
+	next := &version{value: value, expireAt: time.Now().Add(cache.options.VersionTTL)}
+	for {
+		current := cache.version.Load()
+		if current != nil && current.value > value && time.Now().Before(current.expireAt) {
+			return current.value
+		}
+		if cache.version.CompareAndSwap(current, next) {
+			return value
+		}
+	}
+}
+
+// prefix returns the prefix of the keys of the current version, "name:v{version}:".
+func (cache *Cache) prefix(ctx context.Context) (string, error) {
+	value, err := cache.Version(ctx)
+	if err != nil {
+		return "", err
+	}
+	return cache.name + ":v" + strconv.FormatInt(value, 10) + ":", nil
+}
@@ -0,0 +1,165 @@
+package namespace
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// TestCache_Prefix tests that keys are prefixed with the namespace and version.
+func TestCache_Prefix(t *testing.T) {
+	underlying := &sample.Cache{}
+	cache := New(underlying, "svc")
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, err := underlying.Get(ctx, "svc:v0:key"); err != nil || val != "value" {
+		t.Errorf("Expected the prefixed key to be stored, got %v and %v", val, err)
+	}
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected value, got %v and %v", val, err)
+	}
+
+	// Other namespaces don't see the key
+	if _, err := New(underlying, "other").Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, got %v", err)
+	}
+}
+
+// TestCache_Bump tests that bumping the version invalidates the namespace.
+func TestCache_Bump(t *testing.T) {
+	underlying := &sample.Cache{}
+	store := &MemoryVersionStore{}
+	cache := New(underlying, "svc", WithVersionStore(store))
+	other := New(underlying, "svc", WithVersionStore(store))
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "v0"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	version, err := cache.Bump(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("Expected version 1, got %d", version)
+	}
+
+	// Every cache sharing the store misses the old entries
+	for _, c := range []*Cache{cache, other} {
+		if _, err := c.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("Expected ErrCacheMiss, got %v", err)
+		}
+	}
+
+	// New entries go to the new version
+	if err := other.Set(ctx, "key", "v1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, err := underlying.Get(ctx, "svc:v1:key"); err != nil || val != "v1" {
+		t.Errorf("Expected v1, got %v and %v", val, err)
+	}
+}
+
+// TestCache_VersionTTL tests that a version is reused for the VersionTTL.
+func TestCache_VersionTTL(t *testing.T) {
+	store := &MemoryVersionStore{}
+	cache := New(&sample.Cache{}, "svc", WithVersionStore(store), WithVersionTTL(20*time.Millisecond))
+	ctx := context.Background()
+
+	if version, _ := cache.Version(ctx); version != 0 {
+		t.Fatalf("Expected version 0, got %d", version)
+	}
+
+	// A bump of another instance is seen once the version is read again
+	if _, err := store.Bump(ctx, "svc"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if version, _ := cache.Version(ctx); version != 0 {
+		t.Errorf("Expected the reused version 0, got %d", version)
+	}
+	time.Sleep(25 * time.Millisecond)
+	if version, _ := cache.Version(ctx); version != 1 {
+		t.Errorf("Expected version 1, got %d", version)
+	}
+
+	// A bump of this instance is seen immediately
+	if _, err := cache.Bump(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if version, _ := cache.Version(ctx); version != 2 {
+		t.Errorf("Expected version 2, got %d", version)
+	}
+}
+
+// slowVersionStore is a version store whose reads return the version read
+// before they are released.
+type slowVersionStore struct {
+	MemoryVersionStore
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (s *slowVersionStore) Version(ctx context.Context, namespace string) (int64, error) {
+	version, err := s.MemoryVersionStore.Version(ctx, namespace)
+	s.read <- struct{}{}
+	<-s.release
+	return version, err
+}
+
+// TestCache_VersionRace tests that a read started before a Bump doesn't replace the bumped version.
+func TestCache_VersionRace(t *testing.T) {
+	store := &slowVersionStore{read: make(chan struct{}), release: make(chan struct{})}
+	cache := New(&sample.Cache{}, "svc", WithVersionStore(store), WithVersionTTL(time.Minute))
+	ctx := context.Background()
+
+	// A read gets version 0, then a Bump completes before the read returns
+	versions := make(chan int64)
+	go func() {
+		version, _ := cache.Version(ctx)
+		versions <- version
+	}()
+	<-store.read
+	if version, err := cache.Bump(ctx); err != nil || version != 1 {
+		t.Fatalf("Expected version 1, got %d and %v", version, err)
+	}
+	close(store.release)
+
+	// The stale read returns the bumped version and doesn't replace it
+	if version := <-versions; version != 1 {
+		t.Errorf("Expected the stale read to return version 1, got %d", version)
+	}
+	if version, _ := cache.Version(ctx); version != 1 {
+		t.Errorf("Expected version 1 to be remembered, got %d", version)
+	}
+}
+
+// TestCache_Multi tests that batch operations use prefixed keys and return unprefixed ones.
+func TestCache_Multi(t *testing.T) {
+	underlying := &sample.Cache{}
+	cache := New(underlying, "svc")
+	ctx := context.Background()
+
+	if err := cache.SetMulti(ctx, map[string]any{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	vals, err := cache.GetMulti(ctx, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(vals) != 2 || vals["a"] != 1 || vals["b"] != 2 {
+		t.Errorf("Expected a and b, got %v", vals)
+	}
+	if err := cache.DeleteMulti(ctx, []string{"a", "b"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := underlying.Get(ctx, "svc:v0:a"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, got %v", err)
+	}
+}
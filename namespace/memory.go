@@ -0,0 +1,55 @@
+package namespace
+
+import (
+	"context"
+	"sync"
+)
+
+// Ensure that MemoryVersionStore implements the VersionStore interface at compile time.
+var _ VersionStore = (*MemoryVersionStore)(nil)
+
+// MemoryVersionStore is an in-process VersionStore, for namespaces that are
+// not shared with other processes.
+//
+// The zero value is an empty store ready to use.
+type MemoryVersionStore struct {
+	// mu guards versions
+	mu sync.Mutex
+
+	// versions holds the version of every namespace
+	versions map[string]int64
+}
+
+// Version returns the current version of the namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - namespace: The namespace
+//
+// Returns:
+//   - The current version, zero if it was never bumped
+//   - Always returns a nil error
+func (store *MemoryVersionStore) Version(ctx context.Context, namespace string) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.versions[namespace], nil
+}
+
+// Bump increments the version of the namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - namespace: The namespace
+//
+// Returns:
+//   - The new version
+//   - Always returns a nil error
+func (store *MemoryVersionStore) Bump(ctx context.Context, namespace string) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.versions == nil {
+		store.versions = make(map[string]int64)
+	}
+	store.versions[namespace]++
+	return store.versions[namespace], nil
+}
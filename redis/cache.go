@@ -14,9 +14,21 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// absentData is the string stored in Redis for the gouache.Absent sentinel.
+const absentData = "\x00gouache:absent\x00"
+
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.BatchCache interface at compile time.
+var _ gouache.BatchCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.ScanCache interface at compile time.
+var _ gouache.ScanCache = (*Cache)(nil)
+
 // Cache is an implementation of gouache.Cache using Redis as the storage backend.
 // It provides methods for storing, retrieving, and deleting cached values with
 // support for custom serialization/deserialization and configurable TTL.
@@ -28,13 +40,22 @@ type Cache struct {
 	// If not provided, entries will not expire by default.
 	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
 
+	// Jitter is an optional function to spread the time-to-live of cache entries,
+	// so that entries stored together don't expire together. It is applied to
+	// every positive TTL, see the ttl package for built-in policies.
+	Jitter func(key string, ttl time.Duration) time.Duration
+
 	// Marshal is an optional function to serialize objects into strings.
-	// If not provided, default type conversions are used for basic types.
+	// If not provided, the Codec or default type conversions for basic types are used.
 	Marshal func(key string, obj any) (string, error)
 
 	// Unmarshal is an optional function to deserialize strings into objects.
-	// If not provided, raw strings are returned.
+	// If not provided, the Codec is used, or raw strings are returned.
 	Unmarshal func(key string, data string) (any, error)
+
+	// Codec is an optional codec serializing every value, see the codec package
+	// for built-in codecs. The Marshal and Unmarshal functions take precedence.
+	Codec gouache.Codec
 }
 
 // Get retrieves a value from the Redis cache by its key.
@@ -61,18 +82,8 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 		return nil, err
 	}
 
-	// If no unmarshal function is defined, return raw data
-	if cache.Unmarshal == nil {
-		return data, nil
-	}
-
-	// Use custom unmarshal function to decode the data
-	obj, err := cache.Unmarshal(key, data)
-	if err != nil {
-		return nil, err
-	}
-
-	return obj, nil
+	// Decode the raw data
+	return cache.unmarshal(key, data)
 }
 
 // Set stores a value in the Redis cache under the specified key.
@@ -87,49 +98,334 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 // Returns:
 //   - An error if the operation fails, including when Marshal is nil for non-string values
 func (cache *Cache) Set(ctx context.Context, key string, val any) error {
-	// Initialize TTL to zero (no expiration)
-	ttl := time.Duration(0)
-
-	// Check if a custom TTL function is configured
-	if cache.TTL != nil {
-		var err error
-		// Use the TTL function to determine expiration duration
-		ttl, err = cache.TTL(ctx, key, val)
+	// Determine the TTL of the entry
+	ttl, err := cache.ttl(ctx, key, val)
+	if err != nil {
+		return err
+	}
+
+	// Encode the value
+	data, err := cache.marshal(key, val)
+	if err != nil {
+		return err
+	}
+
+	// Store the data in Redis
+	return cache.Cache.Set(ctx, key, data, ttl).Err()
+}
+
+// Delete removes a value from the Redis cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	// Delegate deletion to the underlying Redis client instance
+	return cache.Cache.Del(ctx, key).Err()
+}
+
+// GetMulti retrieves the values for the given keys using a pipeline of GET
+// commands, sent in a single round trip per node. Unlike MGET, the keys may
+// belong to different hash slots of a Redis Cluster.
+// Keys that do not exist are omitted from the returned map.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys to their cached values
+//   - An error if the operation fails
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	vals := make(map[string]any, len(keys))
+
+	// Nothing to send
+	if len(keys) == 0 {
+		return vals, nil
+	}
+
+	// Fetch all values in one pipeline
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := cache.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, key)
+		}
+		return nil
+	})
+
+	// The pipeline error is the first failed command, missing keys aren't failures
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	for i, cmd := range cmds {
+		// GET replies with nil for keys that do not exist
+		data, err := cmd.Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Decode the raw data
+		obj, err := cache.unmarshal(keys[i], data)
+		if err != nil {
+			return nil, err
+		}
+		vals[keys[i]] = obj
+	}
+	return vals, nil
+}
+
+// SetMulti stores every key/value pair of the given map using a pipeline,
+// so that all SET commands are sent in a single round trip.
+// The TTL of each entry is determined the same way as in Set.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - vals: The key/value pairs to store
+//
+// Returns:
+//   - An error if encoding any value or the pipeline fails
+func (cache *Cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	// Nothing to send
+	if len(vals) == 0 {
+		return nil
+	}
+
+	// Compute TTLs and encode values before touching Redis,
+	// so that a bad value doesn't leave the batch half written
+	type entry struct {
+		data string
+		ttl  time.Duration
+	}
+	entries := make(map[string]entry, len(vals))
+	for key, val := range vals {
+		ttl, err := cache.ttl(ctx, key, val)
+		if err != nil {
+			return err
+		}
+		data, err := cache.marshal(key, val)
 		if err != nil {
 			return err
 		}
+		entries[key] = entry{data: data, ttl: ttl}
 	}
 
-	// Check if the value is already a string
-	if data, ok := val.(string); ok {
-		// Directly store strings without marshaling
-		return cache.Cache.Set(ctx, key, data, ttl).Err()
+	// Send all SET commands in one pipeline
+	_, err := cache.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, e := range entries {
+			pipe.Set(ctx, key, e.data, e.ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// DeleteMulti removes the values for the given keys using a pipeline of DEL
+// commands, sent in a single round trip per node. Unlike a multi-key DEL, the
+// keys may belong to different hash slots of a Redis Cluster.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	// Nothing to send
+	if len(keys) == 0 {
+		return nil
 	}
+	_, err := cache.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
 
-	// For non-string values, ensure a marshal function is available
-	if cache.Marshal == nil {
-		return errors.New("gouache: Marshal is nil")
+// SetWithTTL stores a value in the Redis cache under the specified key with
+// the given TTL. The TTL function, if configured, is not consulted, the Jitter
+// function is.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store, either as string or any other type requiring marshaling
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if the operation fails, including when Marshal is nil for non-string values
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	// Redis treats a zero expiration as no expiration
+	if ttl < 0 {
+		ttl = 0
 	}
+	ttl = cache.jitter(key, ttl)
 
-	// Marshal the value into string using the custom marshal function
-	data, err := cache.Marshal(key, val)
+	// Encode the value
+	data, err := cache.marshal(key, val)
 	if err != nil {
 		return err
 	}
 
-	// Store the marshaled data in Redis
+	// Store the data in Redis
 	return cache.Cache.Set(ctx, key, data, ttl).Err()
 }
 
-// Delete removes a value from the Redis cache by its key.
+// GetWithTTL retrieves a value and its remaining time-to-live from the Redis
+// cache, sending GET and PTTL in a single pipeline.
+// It returns gouache.ErrCacheMiss if the key does not exist.
 //
 // Parameters:
 //   - ctx: Context for the Redis operation
-//   - key: The key of the value to delete
+//   - key: The key to retrieve the value for
 //
 // Returns:
-//   - An error if the operation fails
-func (cache *Cache) Delete(ctx context.Context, key string) error {
-	// Delegate deletion to the underlying Redis client instance
-	return cache.Cache.Del(ctx, key).Err()
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	// Send GET and PTTL in one round trip, the pipeline error is
+	// redis.Nil on a miss, so inspect the individual commands instead
+	var getCmd *redis.StringCmd
+	var ttlCmd *redis.DurationCmd
+	_, _ = cache.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		getCmd = pipe.Get(ctx, key)
+		ttlCmd = pipe.PTTL(ctx, key)
+		return nil
+	})
+
+	// Handle case where entry is not found
+	data, err := getCmd.Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, 0, gouache.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ttl, err := ttlCmd.Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case ttl == -2:
+		// The key expired between GET and PTTL
+		return nil, 0, gouache.ErrCacheMiss
+	case ttl < 0:
+		// The key exists but has no associated expire
+		ttl = 0
+	}
+
+	// Decode the raw data
+	obj, err := cache.unmarshal(key, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return obj, ttl, nil
+}
+
+// Scan calls fn for every key of the Redis database, iterating with the SCAN
+// command. Every key of the database is listed, so it should only hold the
+// entries of this cache. With a cluster client, only the keys of the node
+// serving the command are listed.
+//
+// Parameters:
+//   - ctx: Context for the Redis operations
+//   - fn: The function called with every key
+//
+// Returns:
+//   - The error returned by fn, or an error if the operation fails
+func (cache *Cache) Scan(ctx context.Context, fn func(key string) error) error {
+	it := cache.Cache.Scan(ctx, 0, "", 0).Iterator()
+	for it.Next(ctx) {
+		if err := fn(it.Val()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// ttl determines the time-to-live duration for a cache entry, jittered if a
+// Jitter function is configured.
+// It returns zero (no expiration) if no TTL function is configured.
+func (cache *Cache) ttl(ctx context.Context, key string, val any) (time.Duration, error) {
+	if cache.TTL == nil {
+		return 0, nil
+	}
+	ttl, err := cache.TTL(ctx, key, val)
+	if err != nil {
+		return 0, err
+	}
+	return cache.jitter(key, ttl), nil
+}
+
+// jitter applies the Jitter function, if configured, to a positive TTL.
+func (cache *Cache) jitter(key string, ttl time.Duration) time.Duration {
+	if cache.Jitter == nil || ttl <= 0 {
+		return ttl
+	}
+	return cache.Jitter(key, ttl)
+}
+
+// marshal encodes a value into the string stored in Redis.
+// Strings are stored as-is, gouache.Absent as a marker string,
+// other values require the Marshal function or the Codec.
+func (cache *Cache) marshal(key string, val any) (string, error) {
+	// Store the absent sentinel as a marker string
+	if gouache.IsAbsent(val) {
+		return absentData, nil
+	}
+
+	// Let the codec encode every value
+	if cache.Marshal == nil && cache.Codec != nil {
+		data, err := cache.Codec.Marshal(key, val)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	// Directly store strings without marshaling
+	if data, ok := val.(string); ok {
+		return data, nil
+	}
+
+	// For non-string values, ensure a marshal function is available
+	if cache.Marshal == nil {
+		return "", errors.New("gouache: Marshal is nil")
+	}
+
+	// Marshal the value into string using the custom marshal function
+	return cache.Marshal(key, val)
+}
+
+// unmarshal decodes the string stored in Redis into a value.
+// The marker string is decoded as gouache.Absent, otherwise the raw string
+// is returned if neither an Unmarshal function nor a Codec is configured.
+func (cache *Cache) unmarshal(key string, data string) (any, error) {
+	// Restore the absent sentinel from its marker string
+	if data == absentData {
+		return gouache.Absent, nil
+	}
+
+	// Let the codec decode every value
+	if cache.Unmarshal == nil && cache.Codec != nil {
+		return cache.Codec.Unmarshal(key, []byte(data))
+	}
+
+	// If no unmarshal function is defined, return raw data
+	if cache.Unmarshal == nil {
+		return data, nil
+	}
+
+	// Use custom unmarshal function to decode the data
+	return cache.Unmarshal(key, data)
 }
@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/go-leo/gouache/invalidation"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure that PubSubTransport implements the invalidation.Transport interface at compile time.
+var _ invalidation.Transport = (*PubSubTransport)(nil)
+
+// Subscriber is implemented by the Redis clients that support Pub/Sub, such
+// as *redis.Client, *redis.ClusterClient and redis.UniversalClient.
+type Subscriber interface {
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// PubSubTransport is an invalidation.Transport using a Redis Pub/Sub channel.
+//
+// go-redis reconnects a broken subscription on its own, messages published
+// in the meantime are lost.
+type PubSubTransport struct {
+	// Client is the Redis client used to publish messages.
+	Client redis.Cmdable
+
+	// Subscriber is the Redis client used to subscribe to the channel.
+	// If not provided, Client is used if it implements Subscriber.
+	Subscriber Subscriber
+
+	// Channel is the name of the Pub/Sub channel.
+	Channel string
+}
+
+// Publish sends a message on the channel with PUBLISH.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - data: The message
+//
+// Returns:
+//   - An error if the operation fails
+func (t *PubSubTransport) Publish(ctx context.Context, data []byte) error {
+	return t.Client.Publish(ctx, t.Channel, data).Err()
+}
+
+// Subscribe subscribes to the channel and calls the handler with every
+// message, from a dedicated goroutine, until the returned Closer is closed.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - handler: The function receiving the messages
+//
+// Returns:
+//   - A Closer ending the subscription
+//   - An error if the client doesn't support Pub/Sub or the subscription fails
+func (t *PubSubTransport) Subscribe(ctx context.Context, handler func(data []byte)) (io.Closer, error) {
+	// Find a client able to subscribe
+	subscriber := t.Subscriber
+	if subscriber == nil {
+		var ok bool
+		if subscriber, ok = t.Client.(Subscriber); !ok {
+			return nil, errors.New("gouache: redis client does not support pub/sub")
+		}
+	}
+
+	// Wait for the subscription to be confirmed
+	pubsub := subscriber.Subscribe(ctx, t.Channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	// Deliver the messages until the subscription is closed
+	ch := pubsub.Channel()
+	go func() {
+		for msg := range ch {
+			handler([]byte(msg.Payload))
+		}
+	}()
+	return pubsub, nil
+}
@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-leo/gouache/namespace"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure that VersionStore implements the namespace.VersionStore interface at compile time.
+var _ namespace.VersionStore = (*VersionStore)(nil)
+
+// VersionStore is a namespace.VersionStore stored in Redis, shared by every
+// instance. The version of a namespace is a counter incremented with INCR.
+type VersionStore struct {
+	// Client is the Redis client used to store the versions.
+	Client redis.Cmdable
+
+	// Prefix is prepended to the Redis keys of the versions.
+	// If not provided, "gouache:" is used.
+	Prefix string
+}
+
+// Version returns the current version of the namespace with GET.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - ns: The namespace
+//
+// Returns:
+//   - The current version, zero if it was never bumped
+//   - An error if the operation fails
+func (store *VersionStore) Version(ctx context.Context, ns string) (int64, error) {
+	version, err := store.Client.Get(ctx, store.key(ns)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return version, err
+}
+
+// Bump increments the version of the namespace with INCR.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - ns: The namespace
+//
+// Returns:
+//   - The new version
+//   - An error if the operation fails
+func (store *VersionStore) Bump(ctx context.Context, ns string) (int64, error) {
+	return store.Client.Incr(ctx, store.key(ns)).Result()
+}
+
+// key returns the Redis key of the version of a namespace.
+func (store *VersionStore) key(ns string) string {
+	prefix := store.Prefix
+	if prefix == "" {
+		prefix = "gouache:"
+	}
+	return prefix + "version:" + ns
+}
@@ -0,0 +1,555 @@
+package redis
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure that NearCache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*NearCache)(nil)
+
+// Ensure that NearCache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*NearCache)(nil)
+
+// invalidateChannel is the channel Redis publishes the tracking invalidation
+// messages on when they are redirected to a RESP2 connection.
+const invalidateChannel = "__redis__:invalidate"
+
+// nearOptions holds configuration options for the near cache.
+type nearOptions struct {
+	// Capacity is the maximum number of keys kept in the local map.
+	Capacity int
+
+	// LocalTTL is how long a value is kept in the local map at most.
+	LocalTTL time.Duration
+
+	// Broadcast enables the broadcasting tracking mode.
+	Broadcast bool
+
+	// Prefixes are the key prefixes tracked in broadcasting mode.
+	Prefixes []string
+
+	// ErrorHandler is called when the invalidation subscription fails.
+	ErrorHandler func(error)
+}
+
+// NearOption is a function that modifies the near cache options.
+type NearOption func(*nearOptions)
+
+// WithNearCapacity returns a NearOption that sets the maximum number of keys
+// kept in the local map. The least recently used keys are evicted first.
+//
+// Parameters:
+//   - n: The capacity of the local map, 10000 by default
+//
+// Returns:
+//   - A NearOption function that sets the Capacity
+func WithNearCapacity(n int) NearOption {
+	return func(o *nearOptions) {
+		o.Capacity = n
+	}
+}
+
+// WithNearLocalTTL returns a NearOption that sets how long a value is kept in
+// the local map at most, as a safety net for lost invalidations.
+//
+// Parameters:
+//   - dur: The maximum local lifetime, zero for no limit
+//
+// Returns:
+//   - A NearOption function that sets the LocalTTL
+func WithNearLocalTTL(dur time.Duration) NearOption {
+	return func(o *nearOptions) {
+		o.LocalTTL = dur
+	}
+}
+
+// WithNearBroadcast returns a NearOption that enables the broadcasting tracking
+// mode (BCAST): Redis sends an invalidation for every modified key matching
+// one of the prefixes, whether it was read or not. Without prefixes, every
+// key is tracked.
+//
+// Parameters:
+//   - prefixes: The tracked key prefixes
+//
+// Returns:
+//   - A NearOption function that enables the broadcasting mode
+func WithNearBroadcast(prefixes ...string) NearOption {
+	return func(o *nearOptions) {
+		o.Broadcast = true
+		o.Prefixes = prefixes
+	}
+}
+
+// WithNearErrorHandler returns a NearOption that sets a custom error handler
+// for errors of the invalidation subscription.
+//
+// Parameters:
+//   - f: A function to handle errors
+//
+// Returns:
+//   - A NearOption function that sets the ErrorHandler
+func WithNearErrorHandler(f func(error)) NearOption {
+	return func(o *nearOptions) {
+		o.ErrorHandler = f
+	}
+}
+
+// newNearOptions creates a new nearOptions instance with default values and
+// applies the provided options.
+//
+// Parameters:
+//   - opts: Variable number of NearOption functions to apply
+//
+// Returns:
+//   - A pointer to the configured nearOptions instance
+func newNearOptions(opts ...NearOption) *nearOptions {
+	options := &nearOptions{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the nearOptions instance.
+//
+// Parameters:
+//   - opts: Variable number of NearOption functions to apply
+//
+// Returns:
+//   - A pointer to the modified nearOptions instance
+func (o *nearOptions) Apply(opts ...NearOption) *nearOptions {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected nearOptions instance
+func (o *nearOptions) Correct() *nearOptions {
+	// Set default capacity to 10000 if not specified or invalid
+	if o.Capacity <= 0 {
+		o.Capacity = 10000
+	}
+
+	// Set default error handler if not specified
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = func(err error) {
+			slog.Error("redis.NearCache.receive", slog.String("err", err.Error()))
+		}
+	}
+	return o
+}
+
+// nearEntry is an entry of the local map.
+type nearEntry struct {
+	// key is the key of the entry
+	key string
+
+	// val is the decoded value
+	val any
+
+	// expireAt is the time after which the entry is dropped, zero for never
+	expireAt time.Time
+
+	// pending is true while the value is being read from Redis
+	pending bool
+}
+
+// NearCache is a redis.Cache with a local map of recently read keys in front
+// of it, kept consistent by Redis server-assisted client side caching
+// (CLIENT TRACKING).
+//
+// Invalidation messages are received on a dedicated subscriber connection.
+// In the default tracking mode, Redis remembers the keys read through the
+// near cache and sends an invalidation when one of them is modified. In the
+// broadcasting mode, Redis sends an invalidation for every modified key
+// matching the prefixes. The local map is flushed when the subscriber
+// reconnects, as invalidations may have been lost in the meantime.
+//
+// The near cache supports a single Redis server, not a cluster.
+type NearCache struct {
+	// options contains configuration options for the near cache
+	options *nearOptions
+
+	// cache is the Redis cache the values are read from and written to
+	cache *Cache
+
+	// subscriber is the client of the invalidation subscription
+	subscriber *redis.Client
+
+	// pubsub is the invalidation subscription
+	pubsub *redis.PubSub
+
+	// id is the client ID of the subscriber connection, the target of the
+	// tracking redirection
+	id atomic.Int64
+
+	// cancel stops the receiving goroutine
+	cancel context.CancelFunc
+
+	// done is closed when the receiving goroutine has stopped
+	done chan struct{}
+
+	// mu guards entries and lru
+	mu sync.Mutex
+
+	// entries are the elements of lru by key
+	entries map[string]*list.Element
+
+	// lru holds the *nearEntry values, most recently used first
+	lru *list.List
+}
+
+// NewNearCache creates a new near cache in front of a Redis cache and
+// subscribes to the invalidation messages.
+//
+// Parameters:
+//   - cache: The Redis cache, its client must connect to the same server as opt
+//   - opt: The options of the subscriber client, a dedicated client is created from a copy of them
+//   - opts: Variable number of NearOption functions to configure the near cache
+//
+// Returns:
+//   - A near cache, to be closed when no longer used
+//   - An error if the subscription fails
+func NewNearCache(cache *Cache, opt *redis.Options, opts ...NearOption) (*NearCache, error) {
+	near := &NearCache{
+		options: newNearOptions(opts...),
+		cache:   cache,
+		done:    make(chan struct{}),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+
+	// Redirected invalidations are delivered as Pub/Sub messages to RESP2
+	// connections only, set up every new subscriber connection as a target
+	subscriberOpt := *opt
+	subscriberOpt.Protocol = 2
+	subscriberOpt.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		if opt.OnConnect != nil {
+			if err := opt.OnConnect(ctx, cn); err != nil {
+				return err
+			}
+		}
+		return near.onConnect(ctx, cn)
+	}
+	near.subscriber = redis.NewClient(&subscriberOpt)
+
+	// Wait for the subscription to be confirmed
+	ctx, cancel := context.WithCancel(context.Background())
+	near.cancel = cancel
+	near.pubsub = near.subscriber.Subscribe(ctx, invalidateChannel)
+	if _, err := near.pubsub.Receive(ctx); err != nil {
+		cancel()
+		_ = near.pubsub.Close()
+		_ = near.subscriber.Close()
+		return nil, err
+	}
+
+	// Receive invalidations until closed
+	go near.receive(ctx)
+	return near, nil
+}
+
+// Get retrieves a value from the local map, or from Redis on a local miss.
+// Values read from Redis are tracked and kept in the local map until they
+// are invalidated.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (near *NearCache) Get(ctx context.Context, key string) (any, error) {
+	// Serve the value from the local map if possible
+	if val, ok := near.load(key); ok {
+		return val, nil
+	}
+
+	// Mark the key as being read, an invalidation arriving meanwhile removes the mark
+	marker := near.reserve(key)
+
+	// Read the value, with tracking of the key in the default mode
+	data, err := near.fetch(ctx, key)
+	if err != nil {
+		near.release(marker)
+		return nil, err
+	}
+
+	// Decode the raw data
+	val, err := near.cache.unmarshal(key, data)
+	if err != nil {
+		near.release(marker)
+		return nil, err
+	}
+
+	// Keep the value unless it was invalidated during the read
+	near.commit(marker, val)
+	return val, nil
+}
+
+// Set stores a value in Redis and evicts the local copy.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (near *NearCache) Set(ctx context.Context, key string, val any) error {
+	near.evict(key)
+	return near.cache.Set(ctx, key, val)
+}
+
+// Delete removes a value from Redis and evicts the local copy.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (near *NearCache) Delete(ctx context.Context, key string) error {
+	near.evict(key)
+	return near.cache.Delete(ctx, key)
+}
+
+// SetWithTTL stores a value in Redis with the given TTL and evicts the local copy.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if the operation fails
+func (near *NearCache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	near.evict(key)
+	return near.cache.SetWithTTL(ctx, key, val, ttl)
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from Redis.
+// The local map is bypassed, as it doesn't know the remaining TTL.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (near *NearCache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	return near.cache.GetWithTTL(ctx, key)
+}
+
+// Close ends the invalidation subscription and closes the subscriber client.
+//
+// Returns:
+//   - An error if closing the subscriber client fails
+func (near *NearCache) Close() error {
+	near.cancel()
+	_ = near.pubsub.Close()
+	<-near.done
+	near.flush()
+	return near.subscriber.Close()
+}
+
+// onConnect sets up a new subscriber connection as the target of the
+// tracking redirection, and flushes the local map, as invalidations sent
+// to the previous connection may have been lost.
+func (near *NearCache) onConnect(ctx context.Context, cn *redis.Conn) error {
+	id, err := cn.ClientID(ctx).Result()
+	if err != nil {
+		return err
+	}
+
+	// In broadcasting mode, the subscriber connection tracks the prefixes itself
+	if near.options.Broadcast {
+		args := []any{"CLIENT", "TRACKING", "ON", "REDIRECT", id, "BCAST"}
+		for _, prefix := range near.options.Prefixes {
+			args = append(args, "PREFIX", prefix)
+		}
+		if err := cn.Do(ctx, args...).Err(); err != nil {
+			return err
+		}
+	}
+
+	near.reconnected(id)
+	return nil
+}
+
+// reconnected records the client ID of a new subscriber connection and
+// flushes the local map.
+func (near *NearCache) reconnected(id int64) {
+	near.id.Store(id)
+	near.flush()
+}
+
+// fetch reads the raw data of a key from Redis. In the default tracking mode,
+// the read connection enables tracking and opts in for this read, in the same
+// round trip.
+func (near *NearCache) fetch(ctx context.Context, key string) (string, error) {
+	// In broadcasting mode, reads don't need to be tracked
+	if near.options.Broadcast {
+		data, err := near.cache.Cache.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return "", gouache.ErrCacheMiss
+		}
+		return data, err
+	}
+
+	// Send the tracking commands and GET on the same connection
+	var getCmd *redis.StringCmd
+	_, err := near.cache.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Do(ctx, "CLIENT", "TRACKING", "ON", "REDIRECT", near.id.Load(), "OPTIN")
+		pipe.Do(ctx, "CLIENT", "CACHING", "YES")
+		getCmd = pipe.Get(ctx, key)
+		return nil
+	})
+
+	// The pipeline error is the first failed command, refuse to serve
+	// untracked values, they could not be invalidated
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	// Handle case where entry is not found
+	data, err := getCmd.Result()
+	if errors.Is(err, redis.Nil) {
+		return "", gouache.ErrCacheMiss
+	}
+	return data, err
+}
+
+// receive handles the invalidation messages until the context is canceled.
+func (near *NearCache) receive(ctx context.Context) {
+	defer close(near.done)
+	for {
+		msg, err := near.pubsub.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			// A message without keys (the server was flushed) can't be
+			// parsed, and any other failure may lose invalidations
+			near.flush()
+			near.options.ErrorHandler(err)
+
+			// Don't spin while the server is unreachable
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+			continue
+		}
+
+		// Evict the invalidated keys
+		if msg, ok := msg.(*redis.Message); ok && msg.Channel == invalidateChannel {
+			if msg.PayloadSlice == nil {
+				near.evict(msg.Payload)
+			}
+			for _, key := range msg.PayloadSlice {
+				near.evict(key)
+			}
+		}
+	}
+}
+
+// load returns a value of the local map, if present and not expired.
+func (near *NearCache) load(key string) (any, bool) {
+	near.mu.Lock()
+	defer near.mu.Unlock()
+	elem, ok := near.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*nearEntry)
+	if entry.pending {
+		return nil, false
+	}
+	if !entry.expireAt.IsZero() && !time.Now().Before(entry.expireAt) {
+		near.remove(elem)
+		return nil, false
+	}
+	near.lru.MoveToFront(elem)
+	return entry.val, true
+}
+
+// reserve puts a pending entry for a key in the local map and returns it.
+func (near *NearCache) reserve(key string) *list.Element {
+	near.mu.Lock()
+	defer near.mu.Unlock()
+	if elem, ok := near.entries[key]; ok {
+		near.remove(elem)
+	}
+	elem := near.lru.PushFront(&nearEntry{key: key, pending: true})
+	near.entries[key] = elem
+
+	// Evict the least recently used keys beyond the capacity
+	for near.lru.Len() > near.options.Capacity {
+		near.remove(near.lru.Back())
+	}
+	return elem
+}
+
+// commit stores the value of a pending entry, unless it was evicted or
+// replaced meanwhile.
+func (near *NearCache) commit(marker *list.Element, val any) {
+	near.mu.Lock()
+	defer near.mu.Unlock()
+	entry := marker.Value.(*nearEntry)
+	if near.entries[entry.key] != marker {
+		return
+	}
+	entry.val = val
+	entry.pending = false
+	if near.options.LocalTTL > 0 {
+		entry.expireAt = time.Now().Add(near.options.LocalTTL)
+	}
+}
+
+// release removes a pending entry, unless it was evicted or replaced meanwhile.
+func (near *NearCache) release(marker *list.Element) {
+	near.mu.Lock()
+	defer near.mu.Unlock()
+	if near.entries[marker.Value.(*nearEntry).key] == marker {
+		near.remove(marker)
+	}
+}
+
+// evict removes a key from the local map.
+func (near *NearCache) evict(key string) {
+	near.mu.Lock()
+	defer near.mu.Unlock()
+	if elem, ok := near.entries[key]; ok {
+		near.remove(elem)
+	}
+}
+
+// flush removes all keys from the local map.
+func (near *NearCache) flush() {
+	near.mu.Lock()
+	defer near.mu.Unlock()
+	near.entries = make(map[string]*list.Element)
+	near.lru.Init()
+}
+
+// remove removes an element of the local map, the lock must be held.
+func (near *NearCache) remove(elem *list.Element) {
+	near.lru.Remove(elem)
+	delete(near.entries, elem.Value.(*nearEntry).key)
+}
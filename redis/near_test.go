@@ -0,0 +1,154 @@
+package redis
+
+import (
+	"container/list"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestNearCache returns a near cache without Redis, to test its local map.
+func newTestNearCache(opts ...NearOption) *NearCache {
+	return &NearCache{
+		options: newNearOptions(opts...),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// put stores a value in the local map as a read from Redis would.
+func put(near *NearCache, key string, val any) {
+	near.commit(near.reserve(key), val)
+}
+
+// TestNearCache_ReserveCommit tests that a value invalidated or replaced while it is read is not kept.
+func TestNearCache_ReserveCommit(t *testing.T) {
+	near := newTestNearCache()
+
+	// A pending value is not served
+	marker := near.reserve("key")
+	if _, ok := near.load("key"); ok {
+		t.Error("Expected the pending entry not to be served")
+	}
+	near.commit(marker, "value")
+	if val, ok := near.load("key"); !ok || val != "value" {
+		t.Errorf("Expected the committed value, got %v, %v", val, ok)
+	}
+
+	// An invalidation arriving during the read drops the value
+	marker = near.reserve("key")
+	near.evict("key")
+	near.commit(marker, "stale")
+	if val, ok := near.load("key"); ok {
+		t.Errorf("Expected the invalidated value to be dropped, got %v", val)
+	}
+
+	// A concurrent read replaces the reservation of the first one
+	first := near.reserve("key")
+	second := near.reserve("key")
+	near.commit(first, "stale")
+	if val, ok := near.load("key"); ok {
+		t.Errorf("Expected the replaced reservation to be dropped, got %v", val)
+	}
+	near.commit(second, "fresh")
+	if val, ok := near.load("key"); !ok || val != "fresh" {
+		t.Errorf("Expected the value of the last read, got %v, %v", val, ok)
+	}
+
+	// A failed read releases its reservation only
+	marker = near.reserve("other")
+	near.release(first)
+	near.release(marker)
+	if _, ok := near.entries["other"]; ok {
+		t.Error("Expected the released reservation to be removed")
+	}
+	if val, ok := near.load("key"); !ok || val != "fresh" {
+		t.Errorf("Expected the value to survive a stale release, got %v, %v", val, ok)
+	}
+}
+
+// TestNearCache_Capacity tests that the least recently used keys are evicted beyond the capacity.
+func TestNearCache_Capacity(t *testing.T) {
+	near := newTestNearCache(WithNearCapacity(2))
+	put(near, "a", 1)
+	put(near, "b", 2)
+
+	// Reading a makes b the least recently used key
+	if _, ok := near.load("a"); !ok {
+		t.Fatal("Expected a to be present")
+	}
+	put(near, "c", 3)
+	if _, ok := near.load("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := near.load(key); !ok {
+			t.Errorf("Expected %s to be present", key)
+		}
+	}
+	if near.lru.Len() != 2 || len(near.entries) != 2 {
+		t.Errorf("Expected 2 entries, got %d, %d", near.lru.Len(), len(near.entries))
+	}
+}
+
+// TestNearCache_LocalTTL tests that values are dropped from the local map once the local TTL passed.
+func TestNearCache_LocalTTL(t *testing.T) {
+	near := newTestNearCache(WithNearLocalTTL(20 * time.Millisecond))
+	put(near, "key", "value")
+	if _, ok := near.load("key"); !ok {
+		t.Fatal("Expected the value before the local TTL")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := near.load("key"); ok {
+		t.Error("Expected the value to be dropped after the local TTL")
+	}
+	if _, ok := near.entries["key"]; ok {
+		t.Error("Expected the expired entry to be removed")
+	}
+
+	// Without local TTL, values are kept until invalidated
+	near = newTestNearCache()
+	put(near, "key", "value")
+	if entry := near.entries["key"].Value.(*nearEntry); !entry.expireAt.IsZero() {
+		t.Errorf("Expected no local expiration, got %v", entry.expireAt)
+	}
+}
+
+// TestNearCache_Reconnect tests that the local map is flushed when the subscriber reconnects.
+func TestNearCache_Reconnect(t *testing.T) {
+	near := newTestNearCache()
+	put(near, "a", 1)
+	marker := near.reserve("b")
+
+	near.reconnected(42)
+	if near.id.Load() != 42 {
+		t.Errorf("Expected the new client ID, got %d", near.id.Load())
+	}
+	if near.lru.Len() != 0 || len(near.entries) != 0 {
+		t.Errorf("Expected the local map to be flushed, got %d entries", near.lru.Len())
+	}
+
+	// A read started before the reconnection is not kept
+	near.commit(marker, 2)
+	if _, ok := near.load("b"); ok {
+		t.Error("Expected the value read before the reconnection to be dropped")
+	}
+}
+
+// TestNearCache_GetError tests that a failed read releases its reservation.
+func TestNearCache_GetError(t *testing.T) {
+	near := newTestNearCache(WithNearBroadcast())
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	defer client.Close()
+	near.cache = &Cache{Cache: client}
+
+	if _, err := near.Get(context.Background(), "key"); err == nil {
+		t.Fatal("Expected an error without a Redis server")
+	}
+	if near.lru.Len() != 0 || len(near.entries) != 0 {
+		t.Errorf("Expected no entry left, got %d", near.lru.Len())
+	}
+}
@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-leo/gouache/tagging"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure that TagIndex implements the tagging.Index interface at compile time.
+var _ tagging.Index = (*TagIndex)(nil)
+
+// TagIndex is a tagging.Index stored in Redis, shared by every instance.
+//
+// The keys carrying a tag are stored in a sorted set, scored by their
+// expiration time in milliseconds as given by tagging.Cache, TTL and grace
+// included, so that expired keys can be skipped and pruned. The tags of every
+// key are stored in a set expiring with the key.
+type TagIndex struct {
+	// Client is the Redis client used to store the index.
+	Client redis.Cmdable
+
+	// Prefix is prepended to the Redis keys of the index.
+	// If not provided, "gouache:" is used.
+	Prefix string
+}
+
+// Add records that the key carries the tags, until expireAt, replacing the
+// tags the key carried before, and prunes the expired keys of the tags, in a
+// single pipeline after reading the previous tags.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key of the entry
+//   - tags: The tags of the entry
+//   - expireAt: The expiration time of the entry, zero for never
+//
+// Returns:
+//   - An error if the operation fails
+func (index *TagIndex) Add(ctx context.Context, key string, tags []string, expireAt time.Time) error {
+	// Without tags, the key only loses its previous tags
+	if len(tags) == 0 {
+		return index.Remove(ctx, key)
+	}
+
+	// Read the previous tags of the key
+	previous, err := index.Client.SMembers(ctx, index.keyKey(key)).Result()
+	if err != nil {
+		return err
+	}
+
+	// Entries without expiration get an infinite score
+	score := math.Inf(1)
+	if !expireAt.IsZero() {
+		score = float64(expireAt.UnixMilli())
+	}
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	_, err = index.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		// Forget the previous tags of the key
+		for _, tag := range previous {
+			pipe.ZRem(ctx, index.tagKey(tag), key)
+		}
+		pipe.Del(ctx, index.keyKey(key))
+
+		// Record the key under every tag, dropping expired keys
+		for _, tag := range tags {
+			pipe.ZAdd(ctx, index.tagKey(tag), redis.Z{Score: score, Member: key})
+			pipe.ZRemRangeByScore(ctx, index.tagKey(tag), "-inf", "("+now)
+		}
+
+		// Record the tags of the key, expiring with it
+		members := make([]any, len(tags))
+		for i, tag := range tags {
+			members[i] = tag
+		}
+		pipe.SAdd(ctx, index.keyKey(key), members...)
+		if !expireAt.IsZero() {
+			pipe.PExpireAt(ctx, index.keyKey(key), expireAt)
+		}
+		return nil
+	})
+	return err
+}
+
+// Keys returns the unexpired keys carrying the tag with ZRANGEBYSCORE.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - tag: The tag
+//
+// Returns:
+//   - The keys carrying the tag
+//   - An error if the operation fails
+func (index *TagIndex) Keys(ctx context.Context, tag string) ([]string, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return index.Client.ZRangeByScore(ctx, index.tagKey(tag), &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+}
+
+// Untag removes the keys from the tag with ZREM.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - tag: The tag
+//   - keys: The keys to remove
+//
+// Returns:
+//   - An error if the operation fails
+func (index *TagIndex) Untag(ctx context.Context, tag string, keys []string) error {
+	// ZREM requires at least one member
+	if len(keys) == 0 {
+		return nil
+	}
+	members := make([]any, len(keys))
+	for i, key := range keys {
+		members[i] = key
+	}
+	return index.Client.ZRem(ctx, index.tagKey(tag), members...).Err()
+}
+
+// Remove removes the key from all its tags.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key to remove
+//
+// Returns:
+//   - An error if the operation fails
+func (index *TagIndex) Remove(ctx context.Context, key string) error {
+	tags, err := index.Client.SMembers(ctx, index.keyKey(key)).Result()
+	if err != nil {
+		return err
+	}
+	_, err = index.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			pipe.ZRem(ctx, index.tagKey(tag), key)
+		}
+		pipe.Del(ctx, index.keyKey(key))
+		return nil
+	})
+	return err
+}
+
+// tagKey returns the Redis key of the sorted set of a tag.
+func (index *TagIndex) tagKey(tag string) string {
+	return index.prefix() + "tag:" + tag
+}
+
+// keyKey returns the Redis key of the set of tags of a key.
+func (index *TagIndex) keyKey(key string) string {
+	return index.prefix() + "tags:" + key
+}
+
+// prefix returns the configured prefix, or the default one.
+func (index *TagIndex) prefix() string {
+	if index.Prefix == "" {
+		return "gouache:"
+	}
+	return index.Prefix
+}
@@ -7,6 +7,8 @@ package sample
 import (
 	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-leo/gouache"
 )
@@ -14,16 +16,53 @@ import (
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.BatchCache interface at compile time.
+var _ gouache.BatchCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.StatsCache interface at compile time.
+var _ gouache.StatsCache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.ScanCache interface at compile time.
+var _ gouache.ScanCache = (*Cache)(nil)
+
 // Cache is a simple in-memory cache implementation using sync.Map.
 // It provides thread-safe operations for storing, retrieving, and deleting cached values.
+//
+// Entries stored with SetWithTTL expire lazily: an expired entry is removed
+// the next time it is read, or by the purge run every PurgeEvery writes
+// with a TTL, or by DeleteExpired.
 type Cache struct {
 	// cache is the underlying sync.Map used for storage.
 	// sync.Map provides concurrent-safe operations without external dependencies.
 	cache sync.Map
+
+	// counters counts the operations for Stats.
+	counters gouache.Counters
+
+	// ttlSets counts the writes with a TTL, to purge the expired entries
+	// every PurgeEvery of them.
+	ttlSets atomic.Int64
+}
+
+// PurgeEvery is the number of writes with a TTL after which the expired
+// entries are purged, so that the expired entries never read again don't
+// accumulate.
+const PurgeEvery = 1024
+
+// entry is a cached value together with its expiration time.
+type entry struct {
+	// val is the cached value.
+	val any
+
+	// expireAt is the time at which the entry expires, zero if it never expires.
+	expireAt time.Time
 }
 
 // Get retrieves a value from the cache by its key.
-// It returns gouache.ErrCacheMiss if the key does not exist.
+// It returns gouache.ErrCacheMiss if the key does not exist or has expired.
 //
 // Parameters:
 //   - ctx: Context for the operation (not used in this implementation)
@@ -33,8 +72,8 @@ type Cache struct {
 //   - The cached value or nil if not found
 //   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
 func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
-	// Attempt to load the value from sync.Map
-	val, ok := cache.cache.Load(key)
+	// Attempt to load the entry
+	e, ok := cache.load(key)
 
 	// Return cache miss error if key doesn't exist
 	if !ok {
@@ -42,10 +81,11 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 	}
 
 	// Return the found value
-	return val, nil
+	return e.val, nil
 }
 
 // Set stores a value in the cache under the specified key.
+// The entry never expires.
 //
 // Parameters:
 //   - ctx: Context for the operation (not used in this implementation)
@@ -56,7 +96,8 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 //   - Always returns nil as sync.Map.Store doesn't return errors
 func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 	// Store the value in sync.Map
-	cache.cache.Store(key, val)
+	cache.cache.Store(key, &entry{val: val})
+	cache.counters.AddSets(1)
 
 	// sync.Map.Store doesn't return errors, so always return nil
 	return nil
@@ -73,7 +114,191 @@ func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 func (cache *Cache) Delete(ctx context.Context, key string) error {
 	// Delete the value from sync.Map
 	cache.cache.Delete(key)
+	cache.counters.AddDeletes(1)
 
 	// sync.Map.Delete doesn't return errors, so always return nil
 	return nil
 }
+
+// GetMulti retrieves the values for the given keys from the cache.
+// Keys that do not exist or have expired are omitted from the returned map.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys to their cached values
+//   - Always returns a nil error as sync.Map.Load doesn't return errors
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	vals := make(map[string]any, len(keys))
+	for _, key := range keys {
+		// Load every key and collect the ones that exist
+		if e, ok := cache.load(key); ok {
+			vals[key] = e.val
+		}
+	}
+	return vals, nil
+}
+
+// SetMulti stores every key/value pair of the given map in the cache.
+// The entries never expire.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - vals: The key/value pairs to store
+//
+// Returns:
+//   - Always returns nil as sync.Map.Store doesn't return errors
+func (cache *Cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	for key, val := range vals {
+		cache.cache.Store(key, &entry{val: val})
+	}
+	cache.counters.AddSets(int64(len(vals)))
+	return nil
+}
+
+// DeleteMulti removes the values for the given keys from the cache.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - Always returns nil as sync.Map.Delete doesn't return errors
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		cache.cache.Delete(key)
+	}
+	cache.counters.AddDeletes(int64(len(keys)))
+	return nil
+}
+
+// SetWithTTL stores a value in the cache under the specified key,
+// the entry expires once the ttl has passed.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - Always returns nil as sync.Map.Store doesn't return errors
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	e := &entry{val: val}
+
+	// Compute the expiration time, if any
+	if ttl > 0 {
+		e.expireAt = time.Now().Add(ttl)
+	}
+
+	// Store the entry in sync.Map
+	cache.cache.Store(key, e)
+	cache.counters.AddSets(1)
+
+	// Purge the expired entries every PurgeEvery writes with a TTL
+	if ttl > 0 && cache.ttlSets.Add(1)%PurgeEvery == 0 {
+		cache.DeleteExpired()
+	}
+	return nil
+}
+
+// DeleteExpired removes every expired entry from the cache, and counts them
+// as evictions.
+func (cache *Cache) DeleteExpired() {
+	now := time.Now()
+	cache.cache.Range(func(key, val any) bool {
+		// Remove the expired entries, unless they have been replaced in the meantime
+		if e := val.(*entry); !e.expireAt.IsZero() && !now.Before(e.expireAt) && cache.cache.CompareAndDelete(key, e) {
+			cache.counters.AddEvictions(1)
+		}
+		return true
+	})
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the cache.
+// It returns gouache.ErrCacheMiss if the key does not exist or has expired.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	// Attempt to load the entry
+	e, ok := cache.load(key)
+	if !ok {
+		return nil, 0, gouache.ErrCacheMiss
+	}
+
+	// Entries without expiration report a zero TTL
+	if e.expireAt.IsZero() {
+		return e.val, 0, nil
+	}
+	return e.val, time.Until(e.expireAt), nil
+}
+
+// Stats returns the counters of the cache.
+// The entries are counted by iterating the map, including the expired entries
+// that haven't been read nor purged since they expired, and the bytes are unknown.
+//
+// Returns:
+//   - A snapshot of the counters
+func (cache *Cache) Stats() gouache.Stats {
+	stats := cache.counters.Stats()
+	cache.cache.Range(func(key, val any) bool {
+		stats.Entries++
+		return true
+	})
+	return stats
+}
+
+// Scan calls fn for every key of the cache, skipping the expired entries.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - fn: The function called with every key
+//
+// Returns:
+//   - The error returned by fn
+func (cache *Cache) Scan(ctx context.Context, fn func(key string) error) error {
+	var err error
+	now := time.Now()
+	cache.cache.Range(func(key, val any) bool {
+		// Skip the expired entries without removing them
+		if e := val.(*entry); !e.expireAt.IsZero() && !now.Before(e.expireAt) {
+			return true
+		}
+		err = fn(key.(string))
+		return err == nil
+	})
+	return err
+}
+
+// load returns the live entry stored under key, and counts the lookup.
+// An expired entry is removed from the map and reported as not found.
+func (cache *Cache) load(key string) (*entry, bool) {
+	val, ok := cache.cache.Load(key)
+	if !ok {
+		cache.counters.AddMisses(1)
+		return nil, false
+	}
+	e := val.(*entry)
+
+	// Entries without expiration are always live
+	if e.expireAt.IsZero() || time.Now().Before(e.expireAt) {
+		cache.counters.AddHits(1)
+		return e, true
+	}
+
+	// Remove the expired entry, unless it has been replaced in the meantime
+	if cache.cache.CompareAndDelete(key, e) {
+		cache.counters.AddEvictions(1)
+	}
+	cache.counters.AddMisses(1)
+	return nil, false
+}
@@ -2,8 +2,11 @@ package sample
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/go-leo/gouache"
 )
@@ -173,3 +176,161 @@ func TestCache_ConcurrentAccess(t *testing.T) {
 		<-done
 	}
 }
+
+// TestCache_Multi tests the batch operations of the Cache implementation.
+func TestCache_Multi(t *testing.T) {
+	// Create a new cache instance
+	cache := &Cache{}
+	ctx := context.Background()
+
+	// Test setting several values at once
+	err := cache.SetMulti(ctx, map[string]any{"key-1": "value-1", "key-2": "value-2"})
+	if err != nil {
+		t.Fatalf("Unexpected error when setting values: %v", err)
+	}
+
+	// Test getting existing and non-existing keys at once
+	vals, err := cache.GetMulti(ctx, []string{"key-1", "key-2", "key-3"})
+	if err != nil {
+		t.Fatalf("Unexpected error when getting values: %v", err)
+	}
+	if len(vals) != 2 || vals["key-1"] != "value-1" || vals["key-2"] != "value-2" {
+		t.Errorf("Expected key-1 and key-2 only, but got %v", vals)
+	}
+
+	// Test deleting several values at once
+	err = cache.DeleteMulti(ctx, []string{"key-1", "key-2"})
+	if err != nil {
+		t.Fatalf("Unexpected error when deleting values: %v", err)
+	}
+	vals, err = cache.GetMulti(ctx, []string{"key-1", "key-2"})
+	if err != nil {
+		t.Fatalf("Unexpected error when getting values: %v", err)
+	}
+	if len(vals) != 0 {
+		t.Errorf("Expected no values after deletion, but got %v", vals)
+	}
+}
+
+// TestCache_TTL tests the per-entry expiration of the Cache implementation.
+func TestCache_TTL(t *testing.T) {
+	// Create a new cache instance
+	cache := &Cache{}
+	ctx := context.Background()
+
+	// Store one entry that expires and one that doesn't
+	if err := cache.SetWithTTL(ctx, "short", "value", 20*time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error when setting value: %v", err)
+	}
+	if err := cache.SetWithTTL(ctx, "forever", "value", 0); err != nil {
+		t.Fatalf("Unexpected error when setting value: %v", err)
+	}
+
+	// The remaining TTL is reported for the expiring entry
+	_, ttl, err := cache.GetWithTTL(ctx, "short")
+	if err != nil {
+		t.Fatalf("Unexpected error when getting value: %v", err)
+	}
+	if ttl <= 0 || ttl > 20*time.Millisecond {
+		t.Errorf("Expected a remaining TTL within 20ms, but got %v", ttl)
+	}
+
+	// No TTL is reported for the entry without expiration
+	_, ttl, err = cache.GetWithTTL(ctx, "forever")
+	if err != nil {
+		t.Fatalf("Unexpected error when getting value: %v", err)
+	}
+	if ttl != 0 {
+		t.Errorf("Expected zero TTL, but got %v", ttl)
+	}
+
+	// Wait for expiration
+	time.Sleep(30 * time.Millisecond)
+
+	// The expired entry is a cache miss, the other one is still there
+	if _, err := cache.Get(ctx, "short"); err != gouache.ErrCacheMiss {
+		t.Errorf("Expected ErrCacheMiss for expired key, but got: %v", err)
+	}
+	if _, err := cache.Get(ctx, "forever"); err != nil {
+		t.Errorf("Unexpected error when getting value: %v", err)
+	}
+}
+
+// TestCache_DeleteExpired tests that the expired entries never read again are purged.
+func TestCache_DeleteExpired(t *testing.T) {
+	cache := &Cache{}
+	ctx := context.Background()
+
+	_ = cache.SetWithTTL(ctx, "forever", "value", 0)
+	for i := 0; i < PurgeEvery-1; i++ {
+		_ = cache.SetWithTTL(ctx, fmt.Sprintf("key%d", i), i, time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// The next write with a TTL purges the expired entries
+	_ = cache.SetWithTTL(ctx, "live", "value", time.Minute)
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Evictions != PurgeEvery-1 {
+		t.Errorf("Expected 2 entries and %d evictions, got %+v", PurgeEvery-1, stats)
+	}
+
+	// DeleteExpired purges on demand
+	_ = cache.SetWithTTL(ctx, "short", "value", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	cache.DeleteExpired()
+	if stats := cache.Stats(); stats.Entries != 2 {
+		t.Errorf("Expected 2 entries, got %+v", stats)
+	}
+}
+
+// TestCache_Stats tests that the operations and entries are counted.
+func TestCache_Stats(t *testing.T) {
+	ctx := context.Background()
+	cache := &Cache{}
+
+	_ = cache.Set(ctx, "a", 1)
+	_ = cache.SetWithTTL(ctx, "short", 2, time.Millisecond)
+	_ = cache.SetMulti(ctx, map[string]any{"b": 3, "c": 4})
+	_, _ = cache.Get(ctx, "a")
+	_, _ = cache.GetMulti(ctx, []string{"b", "missing"})
+	_ = cache.Delete(ctx, "c")
+
+	// The expired entry is a miss and an eviction
+	time.Sleep(5 * time.Millisecond)
+	_, _ = cache.Get(ctx, "short")
+
+	expected := gouache.Stats{Hits: 2, Misses: 2, Sets: 4, Deletes: 1, Evictions: 1, Entries: 2}
+	if stats := cache.Stats(); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+// TestCache_Scan tests that Scan lists the live keys and stops on the first error.
+func TestCache_Scan(t *testing.T) {
+	cache := &Cache{}
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "a", "a")
+	_ = cache.Set(ctx, "b", "b")
+	_ = cache.SetWithTTL(ctx, "short", "a", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	var keys []string
+	err := cache.Scan(ctx, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	sort.Strings(keys)
+	if err != nil || len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Expected keys [a b], got %v, %v", keys, err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = cache.Scan(ctx, func(key string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected Scan to stop with the error of fn, got %v after %d calls", err, calls)
+	}
+}
@@ -0,0 +1,18 @@
+package gouache
+
+import "context"
+
+// ScanCache is an optional interface that a Cache can implement to list its
+// keys, e.g. to migrate its entries to the new buckets of a sharded cache.
+type ScanCache interface {
+	// Scan calls fn for every key of the cache, until fn returns an error.
+	// Keys stored or deleted during the scan may or may not be listed.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - fn: The function called with every key
+	//
+	// Returns:
+	//   - The error returned by fn, or an error if the operation fails
+	Scan(ctx context.Context, fn func(key string) error) error
+}
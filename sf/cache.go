@@ -9,6 +9,7 @@ package sf
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-leo/gouache"
 	"golang.org/x/sync/singleflight"
@@ -17,6 +18,9 @@ import (
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
 // Cache is a cache implementation that wraps another cache and uses singleflight
 // to prevent duplicate operations for the same key.
 //
@@ -29,6 +33,26 @@ type Cache struct {
 
 	// group is the singleflight group used to deduplicate Get operations.
 	group singleflight.Group
+
+	// ttlGroup is the singleflight group used to deduplicate GetWithTTL operations.
+	ttlGroup singleflight.Group
+}
+
+// Middleware returns a gouache.Middleware deduplicating the reads of the
+// next cache with singleflight.
+//
+// Returns:
+//   - A middleware wrapping the next cache into a Cache
+func Middleware() gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		return &Cache{Cache: next}
+	}
+}
+
+// ttlResult is the shared result of a deduplicated GetWithTTL operation.
+type ttlResult struct {
+	val any
+	ttl time.Duration
 }
 
 // Get retrieves a value from the cache by its key.
@@ -87,4 +111,47 @@ func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 func (cache *Cache) Delete(ctx context.Context, key string) error {
 	// Delegate directly to the underlying cache
 	return cache.Cache.Delete(ctx, key)
-}
\ No newline at end of file
+}
+
+// SetWithTTL stores a value in the cache under the specified key with the given TTL.
+//
+// This operation is passed through directly to the underlying cache without
+// any singleflight protection. If the underlying cache doesn't implement
+// gouache.TTLCache, the value is stored with Set.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	// Delegate directly to the underlying cache
+	return gouache.SetWithTTL(ctx, cache.Cache, key, val, ttl)
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the cache.
+//
+// Like Get, concurrent calls for the same key are deduplicated so that only
+// one of them reaches the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if the operation fails
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	// Use singleflight to ensure only one GetWithTTL operation for this key runs at a time
+	res, err, _ := cache.ttlGroup.Do(key, func() (any, error) {
+		// Delegate to the underlying cache
+		val, ttl, err := gouache.GetWithTTL(ctx, cache.Cache, key)
+		return ttlResult{val: val, ttl: ttl}, err
+	})
+	result := res.(ttlResult)
+	return result.val, result.ttl, err
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
 )
 
 // mockCache is a simple in-memory cache implementation for testing purposes.
@@ -307,3 +308,28 @@ func TestSF_Cache_Get_Singleflight(t *testing.T) {
 		t.Errorf("Expected %v, but got %v", value, firstResult)
 	}
 }
+
+// TestSF_Cache_TTL tests that the singleflight cache passes TTLs through to the underlying cache.
+func TestSF_Cache_TTL(t *testing.T) {
+	sfCache := &Cache{Cache: &sample.Cache{}}
+	ctx := context.Background()
+
+	if err := sfCache.SetWithTTL(ctx, "test-key", "test-value", time.Minute); err != nil {
+		t.Fatalf("Failed to set value with TTL: %v", err)
+	}
+	result, ttl, err := sfCache.GetWithTTL(ctx, "test-key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "test-value" {
+		t.Errorf("Expected %v, got %v", "test-value", result)
+	}
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected a remaining TTL within a minute, got %v", ttl)
+	}
+
+	// Misses are passed through as well
+	if _, _, err := sfCache.GetWithTTL(ctx, "non-existent-key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, got %v", err)
+	}
+}
@@ -10,15 +10,29 @@ package gouache
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"hash"
-	"hash/fnv"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-leo/gouache"
+	"golang.org/x/sync/errgroup"
 )
 
 // Ensure that cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*cache)(nil)
 
+// Ensure that cache implements the gouache.BatchCache interface at compile time.
+var _ gouache.BatchCache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.StatsCache interface at compile time.
+var _ gouache.StatsCache = (*cache)(nil)
+
 // HashFactory is a function type that creates a new hash.Hash instance
 // for a given context and key. This allows customization of the hashing
 // algorithm used for sharding.
@@ -30,24 +44,95 @@ type cache struct {
 	// Options contains configuration options for the cache
 	Options *options
 
+	// layout holds the buckets, switched atomically when resharding
+	layout atomic.Pointer[layout]
+}
+
+// layout is a bucket list of a sharded cache, together with the previous one
+// while resharding.
+type layout struct {
 	// Buckets is a slice of underlying cache implementations that store
 	// the actual cached data. Each entry is assigned to a bucket based
 	// on a hash of its key.
 	Buckets []gouache.Cache
+
+	// Old is the previous bucket list while resharding, nil otherwise.
+	// Reads missing in Buckets fall back to Old, writes go to both.
+	Old []gouache.Cache
+
+	// health is the health of the Buckets, nil without health checker
+	health []*health
 }
 
 // options holds configuration options for the sharded cache.
 type options struct {
+	// Hasher hashes the keys without allocating. It takes precedence over
+	// the HashFactory.
+	Hasher Hasher
+
 	// HashFactory is a function that creates hash instances used for
 	// determining which bucket a key should be stored in.
 	HashFactory HashFactory
+
+	// Strategy maps the hash of a key to its bucket.
+	Strategy Strategy
+
+	// CopyForward tells whether the values found in the old buckets while
+	// resharding are copied to their new buckets.
+	CopyForward bool
+
+	// ErrorHandler is called with a *CopyError when copying a value forward
+	// or repairing a replica fails.
+	ErrorHandler func(error)
+
+	// HealthChecker checks the health of the buckets, the keys of unhealthy
+	// buckets are rerouted.
+	HealthChecker HealthChecker
+
+	// HealthInterval is the minimum interval between two checks of a bucket.
+	HealthInterval time.Duration
+
+	// Replicas is the number of buckets storing every key.
+	Replicas int
+
+	// ReadQuorum is the number of replicas that must answer a read.
+	ReadQuorum int
+
+	// WriteQuorum is the number of replicas that must acknowledge a write.
+	WriteQuorum int
+
+	// RacingReads tells whether the replicas are read in parallel rather
+	// than in order.
+	RacingReads bool
+
+	// ReadRepair tells whether the replicas disagreeing with the value read
+	// are repaired.
+	ReadRepair bool
 }
 
 // Option is a function that modifies the cache options.
 type Option func(*options)
 
-// WithHashFactory returns an Option that sets a custom HashFactory function.
+// WithHasher returns an Option that sets the Hasher of the keys, replacing
+// the HashFactory. The default Hasher is FNV32a.
+//
+// Parameters:
+//   - hasher: A function hashing a key without allocating
+//
+// Returns:
+//   - An Option function that sets the Hasher
+func WithHasher(hasher Hasher) Option {
+	return func(o *options) {
+		o.Hasher = hasher
+		o.HashFactory = nil
+	}
+}
+
+// WithHashFactory returns an Option that sets a custom HashFactory function,
+// replacing the Hasher.
 // This allows users to specify a different hashing algorithm for sharding.
+// Creating a hash for every key allocates, see PooledHashFactory to reuse
+// them.
 //
 // Parameters:
 //   - hashFactory: A function that creates hash instances for key distribution
@@ -57,6 +142,157 @@ type Option func(*options)
 func WithHashFactory(hashFactory HashFactory) Option {
 	return func(o *options) {
 		o.HashFactory = hashFactory
+		o.Hasher = nil
+	}
+}
+
+// WithStrategy returns an Option that sets the Strategy mapping the hash of a
+// key to its bucket. The default Modulo strategy remaps almost every key when
+// the bucket list is resized, whereas the consistent strategies Jump,
+// Rendezvous and Ring only move about 1/n of the keys.
+//
+// Parameters:
+//   - strategy: The strategy picking the bucket of a key
+//
+// Returns:
+//   - An Option function that sets the Strategy
+func WithStrategy(strategy Strategy) Option {
+	return func(o *options) {
+		o.Strategy = strategy
+	}
+}
+
+// WithWeights returns an Option that sets the Rendezvous strategy with the
+// given weights, so that every bucket gets a share of the keys proportional
+// to its weight, e.g. to the memory of its Redis node.
+//
+// Parameters:
+//   - weights: The weights of the buckets by index, 1 for missing weights
+//
+// Returns:
+//   - An Option function that sets the Strategy
+func WithWeights(weights ...float64) Option {
+	return func(o *options) {
+		o.Strategy = Rendezvous{Weights: weights}
+	}
+}
+
+// WithHealthChecker returns an Option that checks the health of the buckets.
+// A bucket is checked in the background at most once per interval while keys
+// are routed to it. The keys of an unhealthy bucket are rerouted to the next
+// healthy candidate in the order of the strategy: the next bucket on the ring
+// of a Ring, the next score of Rendezvous, the next index of Modulo and Jump,
+// and rendezvous order for other strategies. They come back once a check
+// succeeds.
+//
+// Parameters:
+//   - checker: The function checking a bucket
+//   - interval: The minimum interval between two checks of a bucket, and
+//     the timeout of a check, 1s if zero or less
+//
+// Returns:
+//   - An Option function that sets the HealthChecker
+func WithHealthChecker(checker HealthChecker, interval time.Duration) Option {
+	return func(o *options) {
+		o.HealthChecker = checker
+		o.HealthInterval = interval
+	}
+}
+
+// WithReplicas returns an Option that stores every key in several buckets:
+// the bucket picked by the strategy, then the next healthy candidates in the
+// order of the strategy, as for rerouting (see WithHealthChecker). With more
+// than one replica, the batch operations run the single key operations in
+// parallel.
+//
+// Parameters:
+//   - replicas: The number of buckets storing every key, 1 by default
+//
+// Returns:
+//   - An Option function that sets the Replicas
+func WithReplicas(replicas int) Option {
+	return func(o *options) {
+		o.Replicas = replicas
+	}
+}
+
+// WithQuorum returns an Option that sets the number of replicas that must
+// answer a read, with a hit or a miss, and acknowledge a write for the
+// operation to succeed. A read returns the value of the first replica, in
+// replica order, that has the key among the answers. A failed write isn't
+// rolled back from the replicas that acknowledged it.
+//
+// Parameters:
+//   - read: The read quorum, 1 if zero or less
+//   - write: The write quorum, a majority of the replicas if zero or less
+//
+// Returns:
+//   - An Option function that sets the ReadQuorum and WriteQuorum
+func WithQuorum(read int, write int) Option {
+	return func(o *options) {
+		o.ReadQuorum = read
+		o.WriteQuorum = write
+	}
+}
+
+// WithRacingReads returns an Option that reads every replica in parallel and
+// returns once the read quorum answered, rather than reading the replicas in
+// order until it answered.
+//
+// Parameters:
+//   - racing: Whether to race the replicas
+//
+// Returns:
+//   - An Option function that sets RacingReads
+func WithRacingReads(racing bool) Option {
+	return func(o *options) {
+		o.RacingReads = racing
+	}
+}
+
+// WithReadRepair returns an Option that stores the value read in the
+// answering replicas that missed the key or had another value. Failed repairs
+// are reported to the ErrorHandler.
+//
+// Parameters:
+//   - repair: Whether to repair the replicas
+//
+// Returns:
+//   - An Option function that sets ReadRepair
+func WithReadRepair(repair bool) Option {
+	return func(o *options) {
+		o.ReadRepair = repair
+	}
+}
+
+// WithCopyForward returns an Option that copies the values read from the old
+// buckets while resharding to their new buckets, so that the hot keys are
+// migrated by the reads. It is disabled by default.
+//
+// Parameters:
+//   - copyForward: Whether to copy the values forward
+//
+// Returns:
+//   - An Option function that sets CopyForward
+func WithCopyForward(copyForward bool) Option {
+	return func(o *options) {
+		o.CopyForward = copyForward
+	}
+}
+
+// WithErrorHandler returns an Option that sets the function called when
+// copying a value forward or repairing a replica fails, with a *CopyError
+// telling which of the two failed. The read itself succeeds. If not provided,
+// errors are logged with slog, under a message naming the operation.
+//
+// Parameters:
+//   - handler: The function receiving the errors
+//
+// Returns:
+//   - An Option function that sets the ErrorHandler
+func WithErrorHandler(handler func(error)) Option {
+	return func(o *options) {
+		o.ErrorHandler = handler
 	}
 }
 
@@ -88,16 +324,49 @@ func (o *options) Apply(opts ...Option) *options {
 }
 
 // Correct ensures that all options have valid default values.
-// If HashFactory is nil, it sets a default FNV-32a hash factory.
+// If both Hasher and HashFactory are nil, it sets the FNV32a Hasher.
+// If Strategy is nil, it sets the Modulo strategy.
+// If ErrorHandler is nil, it logs the errors with slog.
+// If HealthInterval is zero or less, it sets one second.
+// Replicas are at least one, and the quorums between one and Replicas.
 //
 // Returns:
 //   - A pointer to the corrected options instance
 func (o *options) Correct() *options {
-	if o.HashFactory == nil {
-		o.HashFactory = func(ctx context.Context, key string) (hash.Hash, error) {
-			return fnv.New32a(), nil
+	if o.Hasher == nil && o.HashFactory == nil {
+		o.Hasher = FNV32a
+	}
+	if o.Strategy == nil {
+		o.Strategy = Modulo{}
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = func(err error) {
+			var copyErr *CopyError
+			if !errors.As(err, &copyErr) {
+				slog.Error("sharded.cache.copy", slog.String("err", err.Error()))
+				return
+			}
+			slog.Error("sharded.cache."+copyErr.Op, slog.String("key", copyErr.Key), slog.String("err", copyErr.Err.Error()))
 		}
 	}
+	if o.HealthInterval <= 0 {
+		o.HealthInterval = time.Second
+	}
+	if o.Replicas < 1 {
+		o.Replicas = 1
+	}
+	if o.ReadQuorum < 1 {
+		o.ReadQuorum = 1
+	}
+	if o.WriteQuorum < 1 {
+		o.WriteQuorum = o.Replicas/2 + 1
+	}
+	if o.ReadQuorum > o.Replicas {
+		o.ReadQuorum = o.Replicas
+	}
+	if o.WriteQuorum > o.Replicas {
+		o.WriteQuorum = o.Replicas
+	}
 	return o
 }
 
@@ -108,7 +377,8 @@ func (o *options) Correct() *options {
 //   - opts: Variable number of Option functions to configure the cache
 //
 // Returns:
-//   - A gouache.Cache implementation that distributes entries across buckets
+//   - A gouache.Cache implementation that distributes entries across buckets,
+//     which also implements Resharder
 //
 // Panics:
 //   - If the buckets slice is empty
@@ -116,11 +386,42 @@ func New(buckets []gouache.Cache, opts ...Option) gouache.Cache {
 	if len(buckets) == 0 {
 		panic("gouache: buckets is empty")
 	}
-	return &cache{Options: newOptions(opts...), Buckets: buckets}
+	cache := &cache{Options: newOptions(opts...)}
+	cache.layout.Store(&layout{Buckets: buckets, health: cache.newHealth(len(buckets))})
+	return cache
+}
+
+// Middleware returns a gouache.Middleware sharding the next cache into n
+// buckets, each one created by wrapping the next cache with the bucket
+// middleware. For example, sharding singleflight over a shared backend
+// spreads the lock contention over n groups:
+//
+//	gouache.Chain(redisCache, sharded.Middleware(16, sf.Middleware()))
+//
+// Parameters:
+//   - n: The number of buckets
+//   - bucket: The middleware creating every bucket from the next cache
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A middleware wrapping the next cache with New
+//
+// Panics:
+//   - If n is zero or less, when the middleware is applied
+func Middleware(n int, bucket gouache.Middleware, opts ...Option) gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		buckets := make([]gouache.Cache, 0, n)
+		for i := 0; i < n; i++ {
+			buckets = append(buckets, bucket(next))
+		}
+		return New(buckets, opts...)
+	}
 }
 
 // Get retrieves a value from the cache by its key.
 // The key is hashed to determine which bucket contains the value.
+// While resharding, a key missing from its new bucket is read from its old
+// bucket.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -130,15 +431,25 @@ func New(buckets []gouache.Cache, opts ...Option) gouache.Cache {
 //   - The cached value or nil if not found
 //   - An error if the operation fails
 func (cache *cache) Get(ctx context.Context, key string) (any, error) {
-	bucket, err := cache.bucket(ctx, key)
+	if cache.Options.Replicas > 1 {
+		val, _, err := cache.getReplicated(ctx, key)
+		return val, err
+	}
+	bucket, old, err := cache.owners(ctx, key)
 	if err != nil {
 		return nil, err
 	}
-	return bucket.Get(ctx, key)
+	val, err := bucket.Get(ctx, key)
+	if old == nil || !errors.Is(err, gouache.ErrCacheMiss) {
+		return val, err
+	}
+	val, _, err = cache.fallback(ctx, old, key, bucket)
+	return val, err
 }
 
 // Set stores a value in the cache under the specified key.
 // The key is hashed to determine which bucket should store the value.
+// While resharding, the value is stored in its old bucket too.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -148,15 +459,24 @@ func (cache *cache) Get(ctx context.Context, key string) (any, error) {
 // Returns:
 //   - An error if the operation fails
 func (cache *cache) Set(ctx context.Context, key string, val any) error {
-	bucket, err := cache.bucket(ctx, key)
+	if cache.Options.Replicas > 1 {
+		return cache.writeReplicated(ctx, key, func(ctx context.Context, bucket gouache.Cache) error {
+			return bucket.Set(ctx, key, val)
+		})
+	}
+	bucket, old, err := cache.owners(ctx, key)
 	if err != nil {
 		return err
 	}
-	return bucket.Set(ctx, key, val)
+	if err := bucket.Set(ctx, key, val); err != nil || old == nil {
+		return err
+	}
+	return old.Set(ctx, key, val)
 }
 
 // Delete removes a value from the cache by its key.
 // The key is hashed to determine which bucket contains the value to delete.
+// While resharding, the value is removed from its old bucket too.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -165,55 +485,394 @@ func (cache *cache) Set(ctx context.Context, key string, val any) error {
 // Returns:
 //   - An error if the operation fails
 func (cache *cache) Delete(ctx context.Context, key string) error {
-	bucket, err := cache.bucket(ctx, key)
+	if cache.Options.Replicas > 1 {
+		return cache.writeReplicated(ctx, key, func(ctx context.Context, bucket gouache.Cache) error {
+			return bucket.Delete(ctx, key)
+		})
+	}
+	bucket, old, err := cache.owners(ctx, key)
 	if err != nil {
 		return err
 	}
-	return bucket.Delete(ctx, key)
+	if err := bucket.Delete(ctx, key); err != nil || old == nil {
+		return err
+	}
+	return old.Delete(ctx, key)
 }
 
-// bucket determines which bucket should handle operations for a given key.
-// It uses the configured HashFactory to hash the key and distribute it
-// across the available buckets.
+// SetWithTTL stores a value with the given TTL in the bucket responsible
+// for the key. If the bucket doesn't implement gouache.TTLCache, the value
+// is stored with Set. While resharding, the value is stored in its old
+// bucket too.
 //
 // Parameters:
 //   - ctx: Context for the operation
-//   - key: The key to determine the bucket for
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	if cache.Options.Replicas > 1 {
+		return cache.writeReplicated(ctx, key, func(ctx context.Context, bucket gouache.Cache) error {
+			return gouache.SetWithTTL(ctx, bucket, key, val, ttl)
+		})
+	}
+	bucket, old, err := cache.owners(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := gouache.SetWithTTL(ctx, bucket, key, val, ttl); err != nil || old == nil {
+		return err
+	}
+	return gouache.SetWithTTL(ctx, old, key, val, ttl)
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the
+// bucket responsible for the key. While resharding, a key missing from its
+// new bucket is read from its old bucket.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if the operation fails
+func (cache *cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	if cache.Options.Replicas > 1 {
+		return cache.getReplicated(ctx, key)
+	}
+	bucket, old, err := cache.owners(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	val, ttl, err := gouache.GetWithTTL(ctx, bucket, key)
+	if old == nil || !errors.Is(err, gouache.ErrCacheMiss) {
+		return val, ttl, err
+	}
+	return cache.fallback(ctx, old, key, bucket)
+}
+
+// GetMulti retrieves the values for the given keys.
+// The keys are grouped by bucket and every bucket is queried in parallel,
+// using the bucket's native batch operation if it has one. While resharding,
+// the keys missing from their new bucket are read from their old bucket.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys to their cached values
+//   - An error if any bucket fails
+func (cache *cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	if cache.Options.Replicas > 1 {
+		return cache.getMultiReplicated(ctx, keys)
+	}
+
+	// Split the keys by bucket
+	l := cache.layout.Load()
+	groups, oldGroups, err := cache.groupKeys(ctx, l, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Query every bucket in parallel and merge the results
+	var mu sync.Mutex
+	vals := make(map[string]any, len(keys))
+	err = parallel(ctx, l.Buckets, groups, func(ctx context.Context, bucket gouache.Cache, keys []string) error {
+		found, err := gouache.GetMulti(ctx, bucket, keys)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for key, val := range found {
+			vals[key] = val
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the missing keys from their old bucket
+	err = parallel(ctx, l.Old, oldGroups, func(ctx context.Context, old gouache.Cache, keys []string) error {
+		for _, key := range keys {
+			mu.Lock()
+			_, ok := vals[key]
+			mu.Unlock()
+			if ok {
+				continue
+			}
+
+			sum, err := cache.sum(ctx, key)
+			if err != nil {
+				return err
+			}
+			val, _, err := cache.fallback(ctx, old, key, l.Buckets[cache.route(sum, l)])
+			if errors.Is(err, gouache.ErrCacheMiss) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			vals[key] = val
+			mu.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// SetMulti stores every key/value pair of the given map.
+// The pairs are grouped by bucket and every bucket is written in parallel,
+// using the bucket's native batch operation if it has one. While resharding,
+// the pairs are stored in their old bucket too.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The key/value pairs to store
+//
+// Returns:
+//   - An error if any bucket fails
+func (cache *cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	// Split the pairs by bucket
+	keys := make([]string, 0, len(vals))
+	for key := range vals {
+		keys = append(keys, key)
+	}
+	if cache.Options.Replicas > 1 {
+		return eachKey(ctx, keys, func(ctx context.Context, key string) error {
+			return cache.Set(ctx, key, vals[key])
+		})
+	}
+	l := cache.layout.Load()
+	groups, oldGroups, err := cache.groupKeys(ctx, l, keys)
+	if err != nil {
+		return err
+	}
+
+	// Write every bucket in parallel, the new buckets first
+	set := func(ctx context.Context, bucket gouache.Cache, keys []string) error {
+		group := make(map[string]any, len(keys))
+		for _, key := range keys {
+			group[key] = vals[key]
+		}
+		return gouache.SetMulti(ctx, bucket, group)
+	}
+	if err := parallel(ctx, l.Buckets, groups, set); err != nil {
+		return err
+	}
+	return parallel(ctx, l.Old, oldGroups, set)
+}
+
+// DeleteMulti removes the values for the given keys.
+// The keys are grouped by bucket and every bucket is cleared in parallel,
+// using the bucket's native batch operation if it has one. While resharding,
+// the values are removed from their old bucket too.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if any bucket fails
+func (cache *cache) DeleteMulti(ctx context.Context, keys []string) error {
+	if cache.Options.Replicas > 1 {
+		return eachKey(ctx, keys, cache.Delete)
+	}
+
+	// Split the keys by bucket
+	l := cache.layout.Load()
+	groups, oldGroups, err := cache.groupKeys(ctx, l, keys)
+	if err != nil {
+		return err
+	}
+
+	// Delete from every bucket in parallel, the new buckets first
+	del := func(ctx context.Context, bucket gouache.Cache, keys []string) error {
+		return gouache.DeleteMulti(ctx, bucket, keys)
+	}
+	if err := parallel(ctx, l.Buckets, groups, del); err != nil {
+		return err
+	}
+	return parallel(ctx, l.Old, oldGroups, del)
+}
+
+// Stats returns the sum of the counters of the buckets, including the old
+// buckets while resharding.
+// Buckets that don't implement gouache.StatsCache are not counted.
+//
+// Returns:
+//   - The aggregated counters
+func (cache *cache) Stats() gouache.Stats {
+	var stats gouache.Stats
+	for _, bucket := range cache.layout.Load().all() {
+		if statsCache, ok := bucket.(gouache.StatsCache); ok {
+			stats = stats.Add(statsCache.Stats())
+		}
+	}
+	return stats
+}
+
+// groupKeys splits the given keys by the bucket responsible for them.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - l: The layout of the buckets
+//   - keys: The keys to group
+//
+// Returns:
+//   - A map of every involved bucket index to its keys
+//   - While resharding, a map of every involved old bucket index to its keys,
+//     without the keys whose old bucket is also their bucket
+//   - An error if a bucket cannot be determined
+func (cache *cache) groupKeys(ctx context.Context, l *layout, keys []string) (map[int][]string, map[int][]string, error) {
+	groups := make(map[int][]string)
+	var oldGroups map[int][]string
+	if l.Old != nil {
+		oldGroups = make(map[int][]string)
+	}
+	for _, key := range keys {
+		sum, err := cache.sum(ctx, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		index := cache.route(sum, l)
+		groups[index] = append(groups[index], key)
+		if l.Old == nil {
+			continue
+		}
+		if oldIndex := cache.pick(sum, l.Old); !sameBucket(l.Buckets[index], l.Old[oldIndex]) {
+			oldGroups[oldIndex] = append(oldGroups[oldIndex], key)
+		}
+	}
+	return groups, oldGroups, nil
+}
+
+// parallel calls f for every group of keys in parallel.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - buckets: The buckets indexed by the groups
+//   - groups: A map of bucket indexes to their keys
+//   - f: The function called with every bucket and its keys
+//
+// Returns:
+//   - The first error returned by f
+func parallel(ctx context.Context, buckets []gouache.Cache, groups map[int][]string, f func(ctx context.Context, bucket gouache.Cache, keys []string) error) error {
+	eg, ctx := errgroup.WithContext(ctx)
+	for index, keys := range groups {
+		bucket, keys := buckets[index], keys
+		eg.Go(func() error {
+			return f(ctx, bucket, keys)
+		})
+	}
+	return eg.Wait()
+}
+
+// owners determines which bucket should handle operations for a given key,
+// skipping unhealthy buckets, and which bucket handled them before while
+// resharding.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to determine the buckets for
 //
 // Returns:
 //   - The gouache.Cache bucket that should handle operations for the key
+//   - The old bucket of the key while resharding, nil otherwise or if the
+//     old bucket is also the bucket of the key
+//   - An error if the bucket index cannot be determined
+func (cache *cache) owners(ctx context.Context, key string) (gouache.Cache, gouache.Cache, error) {
+	sum, err := cache.sum(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	l := cache.layout.Load()
+	bucket := l.Buckets[cache.route(sum, l)]
+	if l.Old == nil {
+		return bucket, nil, nil
+	}
+	if old := l.Old[cache.pick(sum, l.Old)]; !sameBucket(bucket, old) {
+		return bucket, old, nil
+	}
+	return bucket, nil, nil
+}
+
+// pick determines the index of the bucket that should handle operations
+// for a given key hash, letting the configured Strategy distribute it across
+// the available buckets.
+//
+// Parameters:
+//   - sum: The hash of the key
+//   - buckets: The buckets to pick from
+//
+// Returns:
+//   - The index into buckets of the bucket that should handle the key
+func (cache *cache) pick(sum uint64, buckets []gouache.Cache) int {
+	return cache.Options.Strategy.Pick(sum, len(buckets))
+}
+
+// sum hashes a key with the configured Hasher, or HashFactory. The key and
+// the sum go through pooled buffers, and the hashes of a PooledHashFactory
+// are put back in their pool.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to hash
+//
+// Returns:
+//   - The hash of the key
 //   - An error if the hash factory or write operation fails
-func (cache *cache) bucket(ctx context.Context, key string) (gouache.Cache, error) {
+func (cache *cache) sum(ctx context.Context, key string) (uint64, error) {
+	// Hash the key without allocating
+	if cache.Options.Hasher != nil {
+		return cache.Options.Hasher(key), nil
+	}
+
 	// Create a new hash instance using the configured HashFactory
 	h, err := cache.Options.HashFactory(ctx, key)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	if pooled, ok := h.(*pooledHash); ok {
+		defer pooled.release()
+		h = pooled.Hash
 	}
 
 	// Write the key to the hash
-	if _, err := h.Write([]byte(key)); err != nil {
-		return nil, err
+	buf := buffers.Get().(*[]byte)
+	defer buffers.Put(buf)
+	*buf = append((*buf)[:0], key...)
+	if _, err := h.Write(*buf); err != nil {
+		return 0, err
 	}
 
-	// Determine the bucket based on the hash size
+	// Determine the sum based on the hash size
 	switch h.Size() {
 	case 4:
 		// For 32-bit hashes, use the hash's Sum32 method
-		sum32 := h.(hash.Hash32).Sum32()
-		return cache.Buckets[sum32%uint32(len(cache.Buckets))], nil
+		return uint64(h.(hash.Hash32).Sum32()), nil
 	case 8:
 		// For 64-bit hashes, use the hash's Sum64 method
-		sum64 := h.(hash.Hash64).Sum64()
-		return cache.Buckets[sum64%uint64(len(cache.Buckets))], nil
+		return h.(hash.Hash64).Sum64(), nil
 	default:
 		// For other hash sizes, use the raw bytes
-		sum := h.Sum(nil)
-		// If the hash is less than 4 bytes, use the first bucket
+		sum := h.Sum((*buf)[:0])
+		// If the hash is less than 4 bytes, use a zero sum
 		if len(sum) < 4 {
-			return cache.Buckets[0], nil
+			return 0, nil
 		}
-		// Extract a 32-bit value from the hash and use it to determine the bucket
-		sum32 := int(binary.BigEndian.Uint32(sum[:4]))
-		return cache.Buckets[sum32%len(cache.Buckets)], nil
+		// Extract a 32-bit value from the hash
+		return uint64(binary.BigEndian.Uint32(sum[:4])), nil
 	}
 }
@@ -2,11 +2,14 @@ package gouache
 
 import (
 	"context"
+	"fmt"
 	"hash"
 	"hash/fnv"
 	"testing"
+	"time"
 
 	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
 )
 
 // mockCache is a simple in-memory cache implementation for testing purposes.
@@ -207,3 +210,126 @@ func TestShardedCache_BucketDistribution(t *testing.T) {
 		t.Errorf("Expected total keys to be %d, but got %d", len(keys), bucket1Count+bucket2Count)
 	}
 }
+
+// TestShardedCache_Multi tests the batch operations of the sharded cache.
+func TestShardedCache_Multi(t *testing.T) {
+	buckets := []gouache.Cache{newMockCache(), newMockCache(), newMockCache()}
+	cache := New(buckets)
+	ctx := context.Background()
+
+	// Set several values spread across the buckets
+	vals := make(map[string]any)
+	for i := 0; i < 30; i++ {
+		vals[fmt.Sprintf("key-%d", i)] = i
+	}
+	if err := gouache.SetMulti(ctx, cache, vals); err != nil {
+		t.Fatalf("Unexpected error when setting values: %v", err)
+	}
+
+	// Every value must be stored in the bucket that Get would consult
+	for key, val := range vals {
+		result, err := cache.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Unexpected error when getting %s: %v", key, err)
+		}
+		if result != val {
+			t.Errorf("Expected %v for %s, but got %v", val, key, result)
+		}
+	}
+
+	// Get the values back in one batch, including a missing key
+	keys := []string{"missing"}
+	for key := range vals {
+		keys = append(keys, key)
+	}
+	found, err := gouache.GetMulti(ctx, cache, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error when getting values: %v", err)
+	}
+	if len(found) != len(vals) {
+		t.Errorf("Expected %d values, but got %d", len(vals), len(found))
+	}
+
+	// Delete the values in one batch
+	if err := gouache.DeleteMulti(ctx, cache, keys); err != nil {
+		t.Fatalf("Unexpected error when deleting values: %v", err)
+	}
+	for _, bucket := range buckets {
+		if n := len(bucket.(*mockCache).data); n != 0 {
+			t.Errorf("Expected empty bucket after deletion, but got %d entries", n)
+		}
+	}
+}
+
+// TestShardedCache_TTL tests that the sharded cache passes TTLs through to its buckets.
+func TestShardedCache_TTL(t *testing.T) {
+	buckets := []gouache.Cache{&sample.Cache{}, &sample.Cache{}}
+	cache := New(buckets)
+	ctx := context.Background()
+
+	if err := gouache.SetWithTTL(ctx, cache, "test-key", "test-value", time.Minute); err != nil {
+		t.Fatalf("Failed to set value with TTL: %v", err)
+	}
+	result, ttl, err := gouache.GetWithTTL(ctx, cache, "test-key")
+	if err != nil {
+		t.Fatalf("Unexpected error when getting value: %v", err)
+	}
+	if result != "test-value" {
+		t.Errorf("Expected %v, but got %v", "test-value", result)
+	}
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected a remaining TTL within a minute, but got %v", ttl)
+	}
+}
+
+// TestShardedCache_Stats tests that the stats of the buckets are aggregated.
+func TestShardedCache_Stats(t *testing.T) {
+	buckets := []gouache.Cache{&sample.Cache{}, &sample.Cache{}, newMockCache()}
+	cache := New(buckets)
+	ctx := context.Background()
+
+	for i := 0; i < 30; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+		_, _ = cache.Get(ctx, fmt.Sprintf("key%d", i))
+	}
+
+	// Only the buckets implementing gouache.StatsCache are counted
+	counted := buckets[0].(gouache.StatsCache).Stats().Add(buckets[1].(gouache.StatsCache).Stats())
+	stats := cache.(gouache.StatsCache).Stats()
+	if stats != counted {
+		t.Errorf("Expected %+v, got %+v", counted, stats)
+	}
+	if stats.Sets != stats.Hits || stats.Entries != stats.Sets || stats.Sets == 0 || stats.Sets == 30 {
+		t.Errorf("Expected the operations of the sample buckets only, got %+v", stats)
+	}
+}
+
+// TestMiddleware tests that every bucket wraps the next cache.
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	next := &sample.Cache{}
+	created := 0
+	bucket := func(next gouache.Cache) gouache.Cache {
+		created++
+		return &mockBucket{Cache: next}
+	}
+	cache := gouache.Chain(next, Middleware(4, bucket))
+	if created != 4 {
+		t.Errorf("Expected 4 buckets, got %d", created)
+	}
+
+	// Every bucket shares the next cache
+	for i := 0; i < 10; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	for i := 0; i < 10; i++ {
+		if val, err := next.Get(ctx, fmt.Sprintf("key%d", i)); err != nil || val != i {
+			t.Errorf("Expected %d in the next cache, got %v, %v", i, val, err)
+		}
+	}
+}
+
+// mockBucket is a bucket wrapping another cache.
+type mockBucket struct {
+	gouache.Cache
+}
@@ -0,0 +1,75 @@
+package gouache
+
+import (
+	"context"
+	"hash"
+	"sync"
+)
+
+// Hasher hashes a key without allocating, it's the fast path of the
+// sharded cache. FNV32a and FNV64a are built-in hashers.
+type Hasher func(key string) uint64
+
+// FNV32a is a Hasher computing the 32-bit FNV-1a hash of a key, the same as
+// hash/fnv.New32a. It is the default Hasher.
+func FNV32a(key string) uint64 {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return uint64(h)
+}
+
+// FNV64a is a Hasher computing the 64-bit FNV-1a hash of a key, the same as
+// hash/fnv.New64a.
+func FNV64a(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// PooledHashFactory returns a HashFactory reusing the hashes created by
+// newHash: the sharded cache resets a hash and puts it back in a pool once
+// the key is hashed, so that hashing doesn't allocate.
+//
+// Parameters:
+//   - newHash: The function creating a hash, e.g. sha256.New
+//
+// Returns:
+//   - A HashFactory taking the hashes from a pool
+func PooledHashFactory(newHash func() hash.Hash) HashFactory {
+	pool := &sync.Pool{}
+	pool.New = func() any {
+		return &pooledHash{Hash: newHash(), pool: pool}
+	}
+	return func(ctx context.Context, key string) (hash.Hash, error) {
+		return pool.Get().(*pooledHash), nil
+	}
+}
+
+// pooledHash is a hash of a PooledHashFactory, together with its pool.
+type pooledHash struct {
+	hash.Hash
+
+	// pool is the pool of the hash
+	pool *sync.Pool
+}
+
+// release resets the hash and puts it back in its pool.
+func (h *pooledHash) release() {
+	h.Reset()
+	h.pool.Put(h)
+}
+
+// buffers holds the buffers converting the keys to bytes and receiving the
+// sums on the HashFactory path.
+var buffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 64)
+		return &buf
+	},
+}
@@ -0,0 +1,100 @@
+package gouache
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"testing"
+)
+
+// TestFNV tests that the hashers compute the same hashes as hash/fnv.
+func TestFNV(t *testing.T) {
+	for _, key := range []string{"", "a", "user:1", "a much longer key with spaces"} {
+		h32, h64 := fnv.New32a(), fnv.New64a()
+		_, _ = h32.Write([]byte(key))
+		_, _ = h64.Write([]byte(key))
+		if FNV32a(key) != uint64(h32.Sum32()) || FNV64a(key) != h64.Sum64() {
+			t.Errorf("Expected the hash/fnv hashes of %q", key)
+		}
+	}
+}
+
+// TestPooledHashFactory tests that pooled hashes sum the keys like new ones.
+func TestPooledHashFactory(t *testing.T) {
+	ctx := context.Background()
+	for _, newHash := range []func() hash.Hash{
+		func() hash.Hash { return fnv.New32a() },
+		func() hash.Hash { return fnv.New64a() },
+		sha256.New,
+	} {
+		pooled := &cache{Options: newOptions(WithHashFactory(PooledHashFactory(newHash)))}
+		plain := &cache{Options: newOptions(WithHashFactory(func(ctx context.Context, key string) (hash.Hash, error) {
+			return newHash(), nil
+		}))}
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("key%d", i)
+			expected, _ := plain.sum(ctx, key)
+			if sum, err := pooled.sum(ctx, key); err != nil || sum != expected {
+				t.Fatalf("Expected %d for %s, got %d, %v", expected, key, sum, err)
+			}
+		}
+	}
+}
+
+// TestSum_Allocs tests that routing a key with the default Hasher doesn't allocate.
+func TestSum_Allocs(t *testing.T) {
+	ctx := context.Background()
+	c := New(newBuckets(8), WithStrategy(Jump{})).(*cache)
+	allocs := testing.AllocsPerRun(1000, func() {
+		_, _, _ = c.owners(ctx, "user:12345")
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocation, got %v", allocs)
+	}
+
+	// The default Hasher keeps the buckets of the former FNV-32a hash factory
+	factory := &cache{Options: newOptions(WithHashFactory(func(ctx context.Context, key string) (hash.Hash, error) {
+		return fnv.New32a(), nil
+	}))}
+	expected, _ := factory.sum(ctx, "user:12345")
+	if sum, _ := c.sum(ctx, "user:12345"); sum != expected {
+		t.Errorf("Expected %d, got %d", expected, sum)
+	}
+}
+
+// BenchmarkSum measures hashing a key with every hashing option.
+func BenchmarkSum(b *testing.B) {
+	ctx := context.Background()
+	benchmarks := []struct {
+		name string
+		opt  Option
+	}{
+		{"FNV32a", WithHasher(FNV32a)},
+		{"FNV64a", WithHasher(FNV64a)},
+		{"PooledHashFactory", WithHashFactory(PooledHashFactory(func() hash.Hash { return fnv.New64a() }))},
+		{"HashFactory", WithHashFactory(func(ctx context.Context, key string) (hash.Hash, error) {
+			return fnv.New64a(), nil
+		})},
+	}
+	for _, bm := range benchmarks {
+		c := &cache{Options: newOptions(bm.opt)}
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = c.sum(ctx, "user:12345")
+			}
+		})
+	}
+}
+
+// BenchmarkOwners measures routing a key to its bucket.
+func BenchmarkOwners(b *testing.B) {
+	ctx := context.Background()
+	c := New(newBuckets(16), WithStrategy(Jump{})).(*cache)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = c.owners(ctx, "user:12345")
+	}
+}
@@ -0,0 +1,88 @@
+package gouache
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-leo/gouache"
+)
+
+// HealthChecker checks whether a bucket is healthy, e.g. by pinging its
+// Redis node:
+//
+//	func(ctx context.Context, bucket gouache.Cache) error {
+//		return bucket.(*redis.Cache).Cache.Ping(ctx).Err()
+//	}
+type HealthChecker func(ctx context.Context, bucket gouache.Cache) error
+
+// health is the health of a bucket, checked in the background at most once
+// per interval while the bucket is routed to.
+type health struct {
+	// down tells whether the last check failed
+	down atomic.Bool
+
+	// checkedAt is the time of the last check in Unix nanoseconds
+	checkedAt atomic.Int64
+
+	// checking tells whether a check is running
+	checking atomic.Bool
+}
+
+// newHealth returns the health of n buckets, nil without health checker.
+func (cache *cache) newHealth(n int) []*health {
+	if cache.Options.HealthChecker == nil {
+		return nil
+	}
+	healths := make([]*health, 0, n)
+	for i := 0; i < n; i++ {
+		healths = append(healths, &health{})
+	}
+	return healths
+}
+
+// healthy tells whether a bucket of a layout is healthy, buckets are healthy
+// until a check fails. It starts a check in the background if the last one
+// is older than the interval.
+//
+// Parameters:
+//   - l: The layout of the bucket
+//   - index: The index of the bucket
+//
+// Returns:
+//   - Whether the bucket is healthy
+func (cache *cache) healthy(l *layout, index int) bool {
+	if l.health == nil {
+		return true
+	}
+	h := l.health[index]
+	interval := cache.Options.HealthInterval
+	if time.Now().UnixNano()-h.checkedAt.Load() >= int64(interval) && h.checking.CompareAndSwap(false, true) {
+		go cache.check(h, l.Buckets[index], index)
+	}
+	return !h.down.Load()
+}
+
+// check runs the health checker on a bucket, and logs the changes of its
+// health.
+//
+// Parameters:
+//   - h: The health of the bucket
+//   - bucket: The bucket to check
+//   - index: The index of the bucket
+func (cache *cache) check(h *health, bucket gouache.Cache, index int) {
+	defer h.checking.Store(false)
+	ctx, cancel := context.WithTimeout(context.Background(), cache.Options.HealthInterval)
+	defer cancel()
+
+	err := cache.Options.HealthChecker(ctx, bucket)
+	h.checkedAt.Store(time.Now().UnixNano())
+	if down := err != nil; h.down.Swap(down) != down {
+		if down {
+			slog.Warn("sharded.cache.check", slog.Int("bucket", index), slog.String("err", err.Error()))
+		} else {
+			slog.Info("sharded.cache.check", slog.Int("bucket", index), slog.Bool("healthy", true))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package gouache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+)
+
+// eventually fails the test if cond doesn't become true within a second.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatal("Condition not met within a second")
+}
+
+// TestHealthChecker tests that the keys of an unhealthy bucket are rerouted until it's healthy again.
+func TestHealthChecker(t *testing.T) {
+	ctx := context.Background()
+	buckets := newBuckets(4)
+	var down atomic.Bool
+	checker := func(ctx context.Context, bucket gouache.Cache) error {
+		if down.Load() && bucket == buckets[0] {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	cache := New(buckets, WithHealthChecker(checker, 5*time.Millisecond))
+	router := cache.(Router)
+
+	// Find keys owned by the first bucket and by the others
+	var owned, others []string
+	for i := 0; len(owned) < 10 || len(others) < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		route, _ := router.Route(ctx, key)
+		if route.Owner == 0 {
+			owned = append(owned, key)
+		} else {
+			others = append(others, key)
+		}
+	}
+
+	// The keys of the first bucket are rerouted once it's checked down
+	down.Store(true)
+	eventually(t, func() bool {
+		route, _ := router.Route(ctx, owned[0])
+		return route.Bucket != 0
+	})
+	for _, key := range owned {
+		route, _ := router.Route(ctx, key)
+		if route.Owner != 0 || route.Bucket == 0 || route.Healthy[0] || route.Old != -1 {
+			t.Errorf("Expected %s to be rerouted, got %+v", key, route)
+		}
+		_ = cache.Set(ctx, key, key)
+	}
+	if n := entries(buckets[:1]); n != 0 {
+		t.Errorf("Expected no entry in the unhealthy bucket, got %d", n)
+	}
+	for _, key := range others {
+		if route, _ := router.Route(ctx, key); route.Bucket != route.Owner {
+			t.Errorf("Expected %s to stay in place, got %+v", key, route)
+		}
+	}
+
+	// The keys come back once the bucket is healthy
+	down.Store(false)
+	eventually(t, func() bool {
+		route, _ := router.Route(ctx, owned[0])
+		return route.Bucket == 0
+	})
+}
@@ -0,0 +1,328 @@
+package gouache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"golang.org/x/sync/errgroup"
+)
+
+// keyParallelism is the maximum number of keys of a batch operation handled
+// at the same time with replication.
+const keyParallelism = 32
+
+// answer is the answer of a replica to a read, a hit or a miss.
+type answer struct {
+	// replica is the index of the replica among the replicas of the key
+	replica int
+
+	// val is the value read, nil on a miss
+	val any
+
+	// ttl is the remaining time-to-live of the value
+	ttl time.Duration
+
+	// hit tells whether the replica has the key
+	hit bool
+}
+
+// replicas determines the buckets storing a key, and its old bucket while
+// resharding.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to determine the buckets for
+//
+// Returns:
+//   - The replicas of the key, starting with the bucket serving it
+//   - The old bucket of the key while resharding, nil otherwise or if the
+//     old bucket is also a replica
+//   - An error if the key cannot be hashed
+func (cache *cache) replicas(ctx context.Context, key string) ([]gouache.Cache, gouache.Cache, error) {
+	sum, err := cache.sum(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	l := cache.layout.Load()
+	indexes := cache.replicaIndexes(sum, l)
+	buckets := make([]gouache.Cache, 0, len(indexes))
+	for _, index := range indexes {
+		buckets = append(buckets, l.Buckets[index])
+	}
+	if l.Old == nil {
+		return buckets, nil, nil
+	}
+	old := l.Old[cache.pick(sum, l.Old)]
+	for _, bucket := range buckets {
+		if sameBucket(bucket, old) {
+			return buckets, nil, nil
+		}
+	}
+	return buckets, old, nil
+}
+
+// getReplicated reads a key from its replicas until the read quorum
+// answered, in order or racing them. The first replica having the key among
+// the answers wins, and the answering replicas disagreeing with it are
+// repaired if ReadRepair is enabled. While resharding, a key missing from
+// every answer is read from its old bucket.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if fewer replicas than the read quorum answered, or
+//     gouache.ErrCacheMiss if key doesn't exist
+func (cache *cache) getReplicated(ctx context.Context, key string) (any, time.Duration, error) {
+	buckets, old, err := cache.replicas(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	quorum := cache.Options.ReadQuorum
+	if quorum > len(buckets) {
+		quorum = len(buckets)
+	}
+
+	// Collect the answers of the replicas
+	var answers []answer
+	var errs []error
+	if cache.Options.RacingReads {
+		answers, errs = raceReplicas(ctx, buckets, key, quorum)
+	} else {
+		answers, errs = readReplicas(ctx, buckets, key, quorum)
+	}
+	if len(answers) < quorum {
+		return nil, 0, fmt.Errorf("gouache: %d of %d replicas answered, %d required: %w", len(answers), len(buckets), quorum, errors.Join(errs...))
+	}
+
+	// The first replica having the key wins
+	best := -1
+	for i, a := range answers {
+		if a.hit && (best < 0 || a.replica < answers[best].replica) {
+			best = i
+		}
+	}
+	if best < 0 {
+		if old != nil {
+			return cache.fallback(ctx, old, key, buckets...)
+		}
+		return nil, 0, gouache.ErrCacheMiss
+	}
+	if cache.Options.ReadRepair {
+		cache.repair(ctx, buckets, answers, answers[best], key)
+	}
+	return answers[best].val, answers[best].ttl, nil
+}
+
+// readReplicas reads a key from the replicas in order, until the quorum
+// answered.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - buckets: The replicas of the key
+//   - key: The key to retrieve the value for
+//   - quorum: The number of answers to collect
+//
+// Returns:
+//   - The answers, hits and misses
+//   - The errors of the replicas that failed
+func readReplicas(ctx context.Context, buckets []gouache.Cache, key string, quorum int) ([]answer, []error) {
+	var answers []answer
+	var errs []error
+	for i, bucket := range buckets {
+		if len(answers) == quorum {
+			break
+		}
+		val, ttl, err := gouache.GetWithTTL(ctx, bucket, key)
+		switch {
+		case err == nil:
+			answers = append(answers, answer{replica: i, val: val, ttl: ttl, hit: true})
+		case errors.Is(err, gouache.ErrCacheMiss):
+			answers = append(answers, answer{replica: i})
+		default:
+			errs = append(errs, err)
+		}
+	}
+	return answers, errs
+}
+
+// raceReplicas reads a key from every replica in parallel, until the quorum
+// answered. The reads still running are canceled.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - buckets: The replicas of the key
+//   - key: The key to retrieve the value for
+//   - quorum: The number of answers to collect
+//
+// Returns:
+//   - The answers, hits and misses
+//   - The errors of the replicas that failed
+func raceReplicas(ctx context.Context, buckets []gouache.Cache, key string, quorum int) ([]answer, []error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		answer answer
+		err    error
+	}
+	results := make(chan result, len(buckets))
+	for i, bucket := range buckets {
+		go func(i int, bucket gouache.Cache) {
+			val, ttl, err := gouache.GetWithTTL(ctx, bucket, key)
+			switch {
+			case err == nil:
+				results <- result{answer: answer{replica: i, val: val, ttl: ttl, hit: true}}
+			case errors.Is(err, gouache.ErrCacheMiss):
+				results <- result{answer: answer{replica: i}}
+			default:
+				results <- result{err: err}
+			}
+		}(i, bucket)
+	}
+
+	var answers []answer
+	var errs []error
+	for range buckets {
+		if len(answers) == quorum {
+			break
+		}
+		if r := <-results; r.err != nil {
+			errs = append(errs, r.err)
+		} else {
+			answers = append(answers, r.answer)
+		}
+	}
+	return answers, errs
+}
+
+// repair stores the value read in the answering replicas that missed the key
+// or had another value. Failed repairs are reported to the ErrorHandler as
+// a *CopyError.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - buckets: The replicas of the key
+//   - answers: The answers of the replicas
+//   - best: The answer read
+//   - key: The key to repair
+func (cache *cache) repair(ctx context.Context, buckets []gouache.Cache, answers []answer, best answer, key string) {
+	for _, a := range answers {
+		if a.hit && reflect.DeepEqual(a.val, best.val) {
+			continue
+		}
+		if err := copyEntry(ctx, buckets[best.replica], buckets[a.replica], key, best.val, best.ttl); err != nil {
+			cache.Options.ErrorHandler(&CopyError{Op: OpReadRepair, Key: key, Err: err})
+		}
+	}
+}
+
+// writeReplicated writes a key to every replica in parallel, and succeeds
+// once the write quorum acknowledged. While resharding, the key is written to
+// its old bucket too.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to write
+//   - write: The function writing a bucket
+//
+// Returns:
+//   - An error if fewer replicas than the write quorum acknowledged, or the
+//     old bucket fails
+func (cache *cache) writeReplicated(ctx context.Context, key string, write func(ctx context.Context, bucket gouache.Cache) error) error {
+	buckets, old, err := cache.replicas(ctx, key)
+	if err != nil {
+		return err
+	}
+	quorum := cache.Options.WriteQuorum
+	if quorum > len(buckets) {
+		quorum = len(buckets)
+	}
+
+	// Write every replica in parallel
+	errs := make([]error, len(buckets))
+	var wg sync.WaitGroup
+	for i, bucket := range buckets {
+		wg.Add(1)
+		go func(i int, bucket gouache.Cache) {
+			defer wg.Done()
+			errs[i] = write(ctx, bucket)
+		}(i, bucket)
+	}
+	wg.Wait()
+
+	acks := 0
+	for _, err := range errs {
+		if err == nil {
+			acks++
+		}
+	}
+	if acks < quorum {
+		return fmt.Errorf("gouache: %d of %d replicas acknowledged, %d required: %w", acks, len(buckets), quorum, errors.Join(errs...))
+	}
+	if old == nil {
+		return nil
+	}
+	return write(ctx, old)
+}
+
+// getMultiReplicated reads the given keys from their replicas in parallel.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys to their cached values
+//   - An error if a read fails
+func (cache *cache) getMultiReplicated(ctx context.Context, keys []string) (map[string]any, error) {
+	var mu sync.Mutex
+	vals := make(map[string]any, len(keys))
+	err := eachKey(ctx, keys, func(ctx context.Context, key string) error {
+		val, _, err := cache.getReplicated(ctx, key)
+		if errors.Is(err, gouache.ErrCacheMiss) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		vals[key] = val
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// eachKey calls f for every key in parallel, for at most keyParallelism keys
+// at the same time.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys
+//   - f: The function called with every key
+//
+// Returns:
+//   - The first error returned by f
+func eachKey(ctx context.Context, keys []string, f func(ctx context.Context, key string) error) error {
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(keyParallelism)
+	for _, key := range keys {
+		key := key
+		eg.Go(func() error {
+			return f(ctx, key)
+		})
+	}
+	return eg.Wait()
+}
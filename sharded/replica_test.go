@@ -0,0 +1,280 @@
+package gouache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+)
+
+// failingCache is a cache whose operations fail while down is set.
+type failingCache struct {
+	gouache.Cache
+	down atomic.Bool
+}
+
+func (c *failingCache) Get(ctx context.Context, key string) (any, error) {
+	if c.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return c.Cache.Get(ctx, key)
+}
+
+func (c *failingCache) Set(ctx context.Context, key string, val any) error {
+	if c.down.Load() {
+		return errors.New("connection refused")
+	}
+	return c.Cache.Set(ctx, key, val)
+}
+
+func (c *failingCache) Delete(ctx context.Context, key string) error {
+	if c.down.Load() {
+		return errors.New("connection refused")
+	}
+	return c.Cache.Delete(ctx, key)
+}
+
+// newFailingBuckets returns n failing buckets over sample buckets.
+func newFailingBuckets(n int) ([]gouache.Cache, []*failingCache) {
+	buckets := make([]gouache.Cache, 0, n)
+	failings := make([]*failingCache, 0, n)
+	for _, bucket := range newBuckets(n) {
+		failing := &failingCache{Cache: bucket}
+		buckets = append(buckets, failing)
+		failings = append(failings, failing)
+	}
+	return buckets, failings
+}
+
+// replicasOf returns the replicas of a key.
+func replicasOf(t *testing.T, cache gouache.Cache, key string) []int {
+	t.Helper()
+	route, err := cache.(Router).Route(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Route failed: %v", err)
+	}
+	return route.Replicas
+}
+
+// TestReplicas tests that every key is written to distinct replicas.
+func TestReplicas(t *testing.T) {
+	ctx := context.Background()
+	buckets := newBuckets(5)
+	cache := New(buckets, WithReplicas(3))
+	for i := 0; i < 50; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	if n := entries(buckets); n != 150 {
+		t.Errorf("Expected 150 entries, got %d", n)
+	}
+
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key%d", i)
+		replicas := replicasOf(t, cache, key)
+		if len(replicas) != 3 || replicas[0] == replicas[1] || replicas[0] == replicas[2] || replicas[1] == replicas[2] {
+			t.Fatalf("Expected 3 distinct replicas for %s, got %v", key, replicas)
+		}
+		for _, replica := range replicas {
+			if val, err := buckets[replica].Get(ctx, key); err != nil || val != i {
+				t.Errorf("Expected %s in bucket %d, got %v, %v", key, replica, val, err)
+			}
+		}
+	}
+
+	// The batch operations go through the replicas too
+	vals, err := cache.(gouache.BatchCache).GetMulti(ctx, []string{"key1", "key2", "missing"})
+	if err != nil || len(vals) != 2 {
+		t.Errorf("Expected 2 values, got %v, %v", vals, err)
+	}
+	_ = cache.(gouache.BatchCache).DeleteMulti(ctx, []string{"key1", "key2"})
+	if n := entries(buckets); n != 144 {
+		t.Errorf("Expected 144 entries, got %d", n)
+	}
+}
+
+// TestReplicas_WriteQuorum tests that writes succeed once the write quorum acknowledged.
+func TestReplicas_WriteQuorum(t *testing.T) {
+	ctx := context.Background()
+	buckets, failings := newFailingBuckets(3)
+	cache := New(buckets, WithReplicas(3))
+
+	// The default write quorum is a majority
+	failings[0].down.Store(true)
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Errorf("Expected the write to succeed with 2 of 3 replicas, got %v", err)
+	}
+	failings[1].down.Store(true)
+	if err := cache.Set(ctx, "key", "value"); err == nil {
+		t.Error("Expected the write to fail with 1 of 3 replicas")
+	}
+}
+
+// TestReplicas_ReadQuorum tests that reads succeed once the read quorum answered, in order or racing.
+func TestReplicas_ReadQuorum(t *testing.T) {
+	ctx := context.Background()
+	for _, racing := range []bool{false, true} {
+		buckets, failings := newFailingBuckets(3)
+		cache := New(buckets, WithReplicas(3), WithQuorum(2, 3), WithRacingReads(racing))
+		_ = cache.Set(ctx, "key", "value")
+		replicas := replicasOf(t, cache, "key")
+
+		// The first replica is down, the others answer
+		failings[replicas[0]].down.Store(true)
+		if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+			t.Errorf("racing %v: expected the value, got %v, %v", racing, val, err)
+		}
+
+		// Only one replica answers
+		failings[replicas[1]].down.Store(true)
+		if _, err := cache.Get(ctx, "key"); err == nil || errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("racing %v: expected the read to fail, got %v", racing, err)
+		}
+	}
+}
+
+// TestReplicas_ReadRepair tests that the replicas disagreeing with the value read are repaired.
+func TestReplicas_ReadRepair(t *testing.T) {
+	ctx := context.Background()
+	buckets := newBuckets(4)
+	cache := New(buckets, WithReplicas(3), WithQuorum(3, 0), WithReadRepair(true))
+	_ = cache.Set(ctx, "key", "value")
+	replicas := replicasOf(t, cache, "key")
+
+	// One replica lost the key, another has a stale value
+	_ = buckets[replicas[1]].Delete(ctx, "key")
+	_ = buckets[replicas[2]].Set(ctx, "key", "stale")
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Fatalf("Expected the value of the first replica, got %v, %v", val, err)
+	}
+	for _, replica := range replicas {
+		if val, err := buckets[replica].Get(ctx, "key"); err != nil || val != "value" {
+			t.Errorf("Expected bucket %d to be repaired, got %v, %v", replica, val, err)
+		}
+	}
+}
+
+// TestEachKey tests that the keys are handled in parallel up to keyParallelism.
+func TestEachKey(t *testing.T) {
+	keys := make([]string, 0, 4*keyParallelism)
+	for i := 0; i < cap(keys); i++ {
+		keys = append(keys, fmt.Sprintf("key%d", i))
+	}
+	var running, peak, calls atomic.Int32
+	err := eachKey(context.Background(), keys, func(ctx context.Context, key string) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		calls.Add(1)
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	if err != nil || int(calls.Load()) != len(keys) {
+		t.Errorf("Expected %d calls, got %d, %v", len(keys), calls.Load(), err)
+	}
+	if p := peak.Load(); p > keyParallelism || p < 2 {
+		t.Errorf("Expected at most %d keys at the same time, got %d", keyParallelism, p)
+	}
+}
+
+// TestReplicas_Strategy tests that the replicas follow the order of the strategy.
+func TestReplicas_Strategy(t *testing.T) {
+	ring := &Ring{}
+	cache := New(newBuckets(5), WithStrategy(ring), WithReplicas(3))
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key%d", i)
+		route, err := cache.(Router).Route(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Route failed: %v", err)
+		}
+
+		// Every replica is the next bucket on the ring after the previous ones
+		expected := []int{route.Owner}
+		for len(expected) < 3 {
+			expected = append(expected, ring.next(route.Hash, 5, func(i int) bool {
+				for _, replica := range expected {
+					if replica == i {
+						return true
+					}
+				}
+				return false
+			}))
+		}
+		if fmt.Sprint(route.Replicas) != fmt.Sprint(expected) {
+			t.Errorf("Expected the replicas %v for %s, got %v", expected, key, route.Replicas)
+		}
+	}
+}
+
+// readOnlyCache is a cache whose writes fail while readOnly is set.
+type readOnlyCache struct {
+	gouache.Cache
+	readOnly atomic.Bool
+}
+
+func (c *readOnlyCache) Set(ctx context.Context, key string, val any) error {
+	if c.readOnly.Load() {
+		return errors.New("read only")
+	}
+	return c.Cache.Set(ctx, key, val)
+}
+
+// TestCopyError tests that failed read repairs and copies forward are reported with their operation.
+func TestCopyError(t *testing.T) {
+	ctx := context.Background()
+	newReadOnlyBuckets := func(n int) ([]gouache.Cache, []*readOnlyCache) {
+		var buckets []gouache.Cache
+		var readOnlys []*readOnlyCache
+		for _, bucket := range newBuckets(n) {
+			readOnly := &readOnlyCache{Cache: bucket}
+			buckets = append(buckets, readOnly)
+			readOnlys = append(readOnlys, readOnly)
+		}
+		return buckets, readOnlys
+	}
+	var errs []error
+	handler := WithErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+
+	// A replica that can't be repaired
+	buckets, readOnlys := newReadOnlyBuckets(3)
+	cache := New(buckets, WithReplicas(3), WithQuorum(3, 0), WithReadRepair(true), handler)
+	_ = cache.Set(ctx, "key", "value")
+	replica := replicasOf(t, cache, "key")[1]
+	_ = buckets[replica].Delete(ctx, "key")
+	readOnlys[replica].readOnly.Store(true)
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Fatalf("Expected the value, got %v, %v", val, err)
+	}
+
+	// A new bucket that can't receive the copy
+	buckets, readOnlys = newReadOnlyBuckets(1)
+	readOnlys[0].readOnly.Store(true)
+	cache = New(newBuckets(1), WithCopyForward(true), handler)
+	_ = cache.Set(ctx, "key", "value")
+	_ = cache.(Resharder).Reshard(buckets)
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Fatalf("Expected the value, got %v, %v", val, err)
+	}
+
+	var ops []string
+	for _, err := range errs {
+		var copyErr *CopyError
+		if !errors.As(err, &copyErr) || copyErr.Key != "key" {
+			t.Fatalf("Expected a *CopyError of key, got %v", err)
+		}
+		ops = append(ops, copyErr.Op)
+	}
+	if fmt.Sprint(ops) != fmt.Sprint([]string{OpReadRepair, OpCopyForward}) {
+		t.Errorf("Expected a failed read repair and copy forward, got %v", ops)
+	}
+}
@@ -0,0 +1,287 @@
+package gouache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"golang.org/x/sync/errgroup"
+)
+
+// Ensure that cache implements the Resharder interface at compile time.
+var _ Resharder = (*cache)(nil)
+
+// errNotResharding is returned when no resharding is in progress.
+var errNotResharding = errors.New("gouache: not resharding")
+
+// Resharder is implemented by the caches returned by New, to move their
+// entries to another bucket list without downtime, e.g. from 8 to 16 Redis
+// buckets:
+//
+//	resharder := cache.(sharded.Resharder)
+//	if err := resharder.Reshard(buckets); err != nil {
+//		return err
+//	}
+//	go func() {
+//		if err := resharder.Migrate(ctx); err != nil {
+//			slog.Error("resharding", slog.String("err", err.Error()))
+//		}
+//	}()
+//
+// While resharding, a key missing from its new bucket is read from its old
+// bucket, and writes and deletes go to both. Moving a key races with the
+// writes of the same key: a value stored while it is moved may be replaced
+// by the moved one.
+type Resharder interface {
+	// Reshard starts resharding to the given buckets, which become the
+	// buckets of the keys. The current buckets become the old buckets.
+	//
+	// Parameters:
+	//   - buckets: The new buckets, which may include current buckets
+	//
+	// Returns:
+	//   - An error if the buckets slice is empty or a resharding is in progress
+	Reshard(buckets []gouache.Cache) error
+
+	// Migrate moves every key of the old buckets to its new bucket, then
+	// switches to the new buckets like Commit. Every old bucket must
+	// implement gouache.ScanCache. A moved key is deleted from its old
+	// bucket, keeping its remaining time-to-live if the old bucket reports it.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//
+	// Returns:
+	//   - An error if no resharding is in progress, an old bucket cannot be
+	//     scanned, or moving a key fails
+	Migrate(ctx context.Context) error
+
+	// Commit atomically switches to the new buckets, the old buckets are no
+	// longer read nor written. Without Migrate, it should be called once
+	// the entries left in the old buckets can be lost, e.g. once they expired.
+	//
+	// Returns:
+	//   - An error if no resharding is in progress
+	Commit() error
+}
+
+// Reshard starts resharding to the given buckets.
+//
+// Parameters:
+//   - buckets: The new buckets, which may include current buckets
+//
+// Returns:
+//   - An error if the buckets slice is empty or a resharding is in progress
+func (cache *cache) Reshard(buckets []gouache.Cache) error {
+	if len(buckets) == 0 {
+		return errors.New("gouache: buckets is empty")
+	}
+	l := cache.layout.Load()
+	if l.Old != nil || !cache.layout.CompareAndSwap(l, &layout{Buckets: buckets, Old: l.Buckets, health: cache.newHealth(len(buckets))}) {
+		return errors.New("gouache: already resharding")
+	}
+	return nil
+}
+
+// Migrate moves every key of the old buckets to its new bucket, scanning the
+// old buckets in parallel, then switches to the new buckets.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//
+// Returns:
+//   - An error if no resharding is in progress, an old bucket cannot be
+//     scanned, or moving a key fails
+func (cache *cache) Migrate(ctx context.Context) error {
+	l := cache.layout.Load()
+	if l.Old == nil {
+		return errNotResharding
+	}
+
+	// Every old bucket must list its keys
+	scanners := make([]gouache.ScanCache, 0, len(l.Old))
+	for i, old := range l.Old {
+		scanner, ok := old.(gouache.ScanCache)
+		if !ok {
+			return fmt.Errorf("gouache: bucket %d cannot be scanned", i)
+		}
+		scanners = append(scanners, scanner)
+	}
+
+	// Move the keys of every old bucket in parallel
+	eg, egCtx := errgroup.WithContext(ctx)
+	for i, scanner := range scanners {
+		old, scanner := l.Old[i], scanner
+		eg.Go(func() error {
+			return scanner.Scan(egCtx, func(key string) error {
+				return cache.move(egCtx, l, old, key)
+			})
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	// Switch to the new buckets, unless it was committed in the meantime
+	if !cache.layout.CompareAndSwap(l, &layout{Buckets: l.Buckets, health: l.health}) {
+		return errNotResharding
+	}
+	return nil
+}
+
+// Commit atomically switches to the new buckets.
+//
+// Returns:
+//   - An error if no resharding is in progress
+func (cache *cache) Commit() error {
+	l := cache.layout.Load()
+	if l.Old == nil || !cache.layout.CompareAndSwap(l, &layout{Buckets: l.Buckets, health: l.health}) {
+		return errNotResharding
+	}
+	return nil
+}
+
+// move copies a key of an old bucket to its new buckets, its replicas with
+// replication, and deletes it from the old bucket unless it's one of them.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - l: The layout being migrated
+//   - old: The old bucket holding the key
+//   - key: The key to move
+//
+// Returns:
+//   - An error if a bucket fails
+func (cache *cache) move(ctx context.Context, l *layout, old gouache.Cache, key string) error {
+	sum, err := cache.sum(ctx, key)
+	if err != nil {
+		return err
+	}
+	var buckets []gouache.Cache
+	kept := false
+	for _, index := range cache.replicaIndexes(sum, l) {
+		if bucket := l.Buckets[index]; sameBucket(bucket, old) {
+			kept = true
+		} else {
+			buckets = append(buckets, bucket)
+		}
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	// Keys expired or deleted since they were listed are skipped
+	val, ttl, err := gouache.GetWithTTL(ctx, old, key)
+	if errors.Is(err, gouache.ErrCacheMiss) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		if err := copyEntry(ctx, old, bucket, key, val, ttl); err != nil {
+			return err
+		}
+	}
+	if kept {
+		return nil
+	}
+	return old.Delete(ctx, key)
+}
+
+// fallback reads a key missing from its buckets in its old bucket, and
+// copies the value to its buckets if CopyForward is enabled. A failed copy is
+// reported to the ErrorHandler as a *CopyError.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - old: The old bucket of the key
+//   - key: The key to retrieve the value for
+//   - buckets: The buckets of the key, its replicas with replication
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *cache) fallback(ctx context.Context, old gouache.Cache, key string, buckets ...gouache.Cache) (any, time.Duration, error) {
+	val, ttl, err := gouache.GetWithTTL(ctx, old, key)
+	if err != nil || !cache.Options.CopyForward {
+		return val, ttl, err
+	}
+	for _, bucket := range buckets {
+		if err := copyEntry(ctx, old, bucket, key, val, ttl); err != nil {
+			cache.Options.ErrorHandler(&CopyError{Op: OpCopyForward, Key: key, Err: err})
+		}
+	}
+	return val, ttl, nil
+}
+
+// The operations reported in a CopyError.
+const (
+	// OpReadRepair is the operation storing the value read in a replica that
+	// missed the key or had another value, see WithReadRepair.
+	OpReadRepair = "read_repair"
+
+	// OpCopyForward is the operation copying a value found in an old bucket
+	// while resharding to its new bucket, see WithCopyForward.
+	OpCopyForward = "copy_forward"
+)
+
+// CopyError is the error passed to the ErrorHandler when a read fails to copy
+// a value between buckets. The read itself succeeds.
+type CopyError struct {
+	// Op is the operation that failed, OpReadRepair or OpCopyForward.
+	Op string
+
+	// Key is the key of the value.
+	Key string
+
+	// Err is the error of the bucket the value was copied to.
+	Err error
+}
+
+// Error returns a description of the failed copy.
+func (e *CopyError) Error() string {
+	return fmt.Sprintf("gouache: %s of key %q: %v", e.Op, e.Key, e.Err)
+}
+
+// Unwrap returns the error of the bucket.
+func (e *CopyError) Unwrap() error {
+	return e.Err
+}
+
+// copyEntry stores a value read from an old bucket in the new bucket of its
+// key. The remaining time-to-live is kept if the old bucket reports it,
+// otherwise the new bucket's own expiration policy applies.
+func copyEntry(ctx context.Context, old gouache.Cache, bucket gouache.Cache, key string, val any, ttl time.Duration) error {
+	if _, ok := old.(gouache.TTLCache); ok {
+		return gouache.SetWithTTL(ctx, bucket, key, val, ttl)
+	}
+	return bucket.Set(ctx, key, val)
+}
+
+// all returns the buckets of the layout, followed by the old buckets that are
+// not also buckets.
+func (l *layout) all() []gouache.Cache {
+	buckets := append([]gouache.Cache(nil), l.Buckets...)
+	for _, old := range l.Old {
+		kept := false
+		for _, bucket := range l.Buckets {
+			kept = kept || sameBucket(bucket, old)
+		}
+		if !kept {
+			buckets = append(buckets, old)
+		}
+	}
+	return buckets
+}
+
+// sameBucket reports whether two buckets are the same cache, e.g. a bucket
+// kept by a resharding. Buckets of incomparable types are never the same.
+func sameBucket(a gouache.Cache, b gouache.Cache) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
@@ -0,0 +1,161 @@
+package gouache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// newBuckets returns n empty sample buckets.
+func newBuckets(n int) []gouache.Cache {
+	buckets := make([]gouache.Cache, 0, n)
+	for i := 0; i < n; i++ {
+		buckets = append(buckets, &sample.Cache{})
+	}
+	return buckets
+}
+
+// entries returns the number of entries of the given buckets.
+func entries(buckets []gouache.Cache) int64 {
+	var n int64
+	for _, bucket := range buckets {
+		n += bucket.(gouache.StatsCache).Stats().Entries
+	}
+	return n
+}
+
+// TestReshard tests that reads fall back to the old buckets, and writes and deletes go to both.
+func TestReshard(t *testing.T) {
+	ctx := context.Background()
+	old, buckets := newBuckets(2), newBuckets(4)
+	cache := New(old)
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	if err := cache.(Resharder).Reshard(buckets); err != nil {
+		t.Fatalf("Reshard failed: %v", err)
+	}
+
+	// Every key is read from the old buckets
+	for i := 0; i < 100; i++ {
+		if val, err := cache.Get(ctx, fmt.Sprintf("key%d", i)); err != nil || val != i {
+			t.Errorf("Expected key%d to be %d, got %v, %v", i, i, val, err)
+		}
+	}
+	vals, err := cache.(gouache.BatchCache).GetMulti(ctx, []string{"key1", "key2", "missing"})
+	if err != nil || len(vals) != 2 || vals["key1"] != 1 || vals["key2"] != 2 {
+		t.Errorf("Expected key1 and key2, got %v, %v", vals, err)
+	}
+	if n := entries(buckets); n != 0 {
+		t.Errorf("Expected no value to be copied forward, got %d", n)
+	}
+
+	// Writes and deletes go to both layouts
+	_ = cache.Set(ctx, "new", "value")
+	_ = cache.Delete(ctx, "key1")
+	_ = cache.(gouache.BatchCache).DeleteMulti(ctx, []string{"key2"})
+	if n := entries(old); n != 99 {
+		t.Errorf("Expected 99 entries in the old buckets, got %d", n)
+	}
+	if n := entries(buckets); n != 1 {
+		t.Errorf("Expected 1 entry in the new buckets, got %d", n)
+	}
+	if _, err := cache.Get(ctx, "key1"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected key1 to be deleted, got %v", err)
+	}
+
+	// After the switch, only the new buckets are read
+	if err := cache.(Resharder).Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if val, err := cache.Get(ctx, "new"); err != nil || val != "value" {
+		t.Errorf("Expected the new key, got %v, %v", val, err)
+	}
+	if _, err := cache.Get(ctx, "key3"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected key3 to be left in the old buckets, got %v", err)
+	}
+}
+
+// TestReshard_CopyForward tests that the values read from the old buckets are copied with their TTL.
+func TestReshard_CopyForward(t *testing.T) {
+	ctx := context.Background()
+	buckets := newBuckets(4)
+	cache := New(newBuckets(2), WithCopyForward(true))
+	_ = cache.(gouache.TTLCache).SetWithTTL(ctx, "key", "value", time.Minute)
+	_ = cache.Set(ctx, "other", "value")
+	_ = cache.(Resharder).Reshard(buckets)
+
+	_, _ = cache.Get(ctx, "key")
+	_, _ = cache.(gouache.BatchCache).GetMulti(ctx, []string{"other"})
+	if n := entries(buckets); n != 2 {
+		t.Fatalf("Expected 2 entries copied forward, got %d", n)
+	}
+
+	_ = cache.(Resharder).Commit()
+	val, ttl, err := cache.(gouache.TTLCache).GetWithTTL(ctx, "key")
+	if err != nil || val != "value" || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected the value with its TTL, got %v, %v, %v", val, ttl, err)
+	}
+}
+
+// TestReshard_Migrate tests that the migration moves every key, then switches to the new buckets.
+func TestReshard_Migrate(t *testing.T) {
+	ctx := context.Background()
+	old := newBuckets(2)
+	cache := New(old, WithStrategy(Jump{}))
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+
+	// Grow from 2 to 4 buckets, keeping the current ones
+	buckets := append(append([]gouache.Cache(nil), old...), newBuckets(2)...)
+	_ = cache.(Resharder).Reshard(buckets)
+	if err := cache.(Resharder).Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// Every key was moved once, about half of them to the added buckets
+	if n := entries(buckets); n != 100 {
+		t.Errorf("Expected 100 entries, got %d", n)
+	}
+	if n := entries(buckets[2:]); n < 30 || n > 70 {
+		t.Errorf("Expected about 50 entries in the added buckets, got %d", n)
+	}
+	for i := 0; i < 100; i++ {
+		if val, err := cache.Get(ctx, fmt.Sprintf("key%d", i)); err != nil || val != i {
+			t.Errorf("Expected key%d to be %d, got %v, %v", i, i, val, err)
+		}
+	}
+	if err := cache.(Resharder).Commit(); err == nil {
+		t.Error("Expected Commit to fail after the migration")
+	}
+}
+
+// TestReshard_Errors tests the misuses of resharding.
+func TestReshard_Errors(t *testing.T) {
+	ctx := context.Background()
+	resharder := New([]gouache.Cache{newMockCache()}).(Resharder)
+
+	if err := resharder.Migrate(ctx); err == nil {
+		t.Error("Expected Migrate to fail without resharding")
+	}
+	if err := resharder.Reshard(nil); err == nil {
+		t.Error("Expected Reshard to fail without buckets")
+	}
+	if err := resharder.Reshard(newBuckets(2)); err != nil {
+		t.Fatalf("Reshard failed: %v", err)
+	}
+	if err := resharder.Reshard(newBuckets(3)); err == nil {
+		t.Error("Expected Reshard to fail while resharding")
+	}
+
+	// The old bucket cannot be scanned
+	if err := resharder.Migrate(ctx); err == nil {
+		t.Error("Expected Migrate to fail on a bucket that cannot be scanned")
+	}
+}
@@ -0,0 +1,162 @@
+package gouache
+
+import "context"
+
+// Ensure that cache implements the Router interface at compile time.
+var _ Router = (*cache)(nil)
+
+// Router is implemented by the caches returned by New, to debug the routing
+// of the keys:
+//
+//	route, err := cache.(sharded.Router).Route(ctx, key)
+type Router interface {
+	// Route returns the routing decision of a key.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key to route
+	//
+	// Returns:
+	//   - The routing decision
+	//   - An error if the key cannot be hashed
+	Route(ctx context.Context, key string) (Route, error)
+}
+
+// Route is the routing decision of a key.
+type Route struct {
+	// Hash is the hash of the key.
+	Hash uint64
+
+	// Owner is the index of the bucket picked by the strategy.
+	Owner int
+
+	// Bucket is the index of the bucket serving the key, another one than
+	// Owner if the owner is unhealthy.
+	Bucket int
+
+	// Replicas are the indexes of the buckets storing the key, starting
+	// with Bucket.
+	Replicas []int
+
+	// Old is the index of the old bucket of the key while resharding,
+	// -1 otherwise.
+	Old int
+
+	// Healthy tells whether every bucket is healthy, by index.
+	Healthy []bool
+}
+
+// Route returns the routing decision of a key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to route
+//
+// Returns:
+//   - The routing decision
+//   - An error if the key cannot be hashed
+func (cache *cache) Route(ctx context.Context, key string) (Route, error) {
+	sum, err := cache.sum(ctx, key)
+	if err != nil {
+		return Route{}, err
+	}
+	l := cache.layout.Load()
+	route := Route{
+		Hash:     sum,
+		Owner:    cache.pick(sum, l.Buckets),
+		Bucket:   cache.route(sum, l),
+		Replicas: cache.replicaIndexes(sum, l),
+		Old:      -1,
+		Healthy:  make([]bool, 0, len(l.Buckets)),
+	}
+	if l.Old != nil {
+		route.Old = cache.pick(sum, l.Old)
+	}
+	for i := range l.Buckets {
+		route.Healthy = append(route.Healthy, cache.healthy(l, i))
+	}
+	return route, nil
+}
+
+// route determines the index of the bucket serving a key hash: the bucket
+// picked by the strategy if it's healthy, otherwise the next healthy
+// candidate in the order of the strategy, see successor. If every bucket is
+// unhealthy, the picked bucket is kept.
+//
+// Parameters:
+//   - sum: The hash of the key
+//   - l: The layout of the buckets
+//
+// Returns:
+//   - The index into the buckets of the layout
+func (cache *cache) route(sum uint64, l *layout) int {
+	index := cache.pick(sum, l.Buckets)
+	if cache.healthy(l, index) {
+		return index
+	}
+
+	// Reroute to the next healthy candidate
+	next := cache.next(sum, len(l.Buckets), func(i int) bool {
+		return !cache.healthy(l, i)
+	})
+	if next < 0 {
+		return index
+	}
+	return next
+}
+
+// replicaIndexes determines the indexes of the buckets storing a key hash:
+// the bucket serving it, then the next healthy candidates in the order of
+// the strategy, e.g. the successive buckets of a Ring. Unhealthy candidates
+// complete the replicas if there aren't enough healthy ones.
+//
+// Parameters:
+//   - sum: The hash of the key
+//   - l: The layout of the buckets
+//
+// Returns:
+//   - The indexes into the buckets of the layout, at most Replicas
+func (cache *cache) replicaIndexes(sum uint64, l *layout) []int {
+	n := cache.Options.Replicas
+	if n > len(l.Buckets) {
+		n = len(l.Buckets)
+	}
+	indexes := append(make([]int, 0, n), cache.route(sum, l))
+	chosen := func(i int) bool {
+		for _, index := range indexes {
+			if index == i {
+				return true
+			}
+		}
+		return false
+	}
+
+	for len(indexes) < n {
+		next := cache.next(sum, len(l.Buckets), func(i int) bool {
+			return chosen(i) || !cache.healthy(l, i)
+		})
+		if next < 0 {
+			next = cache.next(sum, len(l.Buckets), chosen)
+		}
+		indexes = append(indexes, next)
+	}
+	return indexes
+}
+
+// next returns the first candidate bucket of a key hash that isn't skipped,
+// in the order of the strategy if it implements successor, otherwise in
+// rendezvous order.
+//
+// Parameters:
+//   - sum: The hash of the key
+//   - n: The number of buckets
+//   - skip: The function telling whether a bucket is skipped
+//
+// Returns:
+//   - The index of the bucket, -1 if every bucket is skipped
+func (cache *cache) next(sum uint64, n int, skip func(i int) bool) int {
+	if strategy, ok := cache.Options.Strategy.(successor); ok {
+		return strategy.next(sum, n, skip)
+	}
+	return Rendezvous{}.best(sum, n, skip)
+}
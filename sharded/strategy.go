@@ -0,0 +1,284 @@
+package gouache
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Ensure that Modulo implements the Strategy interface at compile time.
+var _ Strategy = Modulo{}
+
+// Ensure that Jump implements the Strategy interface at compile time.
+var _ Strategy = Jump{}
+
+// Ensure that Rendezvous implements the Strategy interface at compile time.
+var _ Strategy = Rendezvous{}
+
+// Ensure that Ring implements the Strategy interface at compile time.
+var _ Strategy = (*Ring)(nil)
+
+// Ensure that Modulo implements the successor interface at compile time.
+var _ successor = Modulo{}
+
+// Ensure that Jump implements the successor interface at compile time.
+var _ successor = Jump{}
+
+// Ensure that Rendezvous implements the successor interface at compile time.
+var _ successor = Rendezvous{}
+
+// Ensure that Ring implements the successor interface at compile time.
+var _ successor = (*Ring)(nil)
+
+// Strategy maps the hash of a key to one of the buckets.
+//
+// The buckets are identified by their index. With a consistent strategy,
+// adding or removing a bucket at the end of the list moves about 1/n of the
+// keys, whereas Modulo moves almost all of them.
+type Strategy interface {
+	// Pick returns the index of the bucket owning a key.
+	//
+	// Parameters:
+	//   - hash: The hash of the key
+	//   - n: The number of buckets, at least one
+	//
+	// Returns:
+	//   - The index of the bucket, between 0 and n-1
+	Pick(hash uint64, n int) int
+}
+
+// successor is implemented by the strategies that order the other buckets
+// after the one they pick, to place the replicas of a key and to reroute it
+// around unhealthy buckets. Other strategies fall back to rendezvous order.
+type successor interface {
+	// next returns the first bucket of a key that isn't skipped, in the order
+	// of preference of the strategy, starting with the bucket it picks.
+	//
+	// Parameters:
+	//   - hash: The hash of the key
+	//   - n: The number of buckets, at least one
+	//   - skip: An optional function telling whether a bucket is skipped
+	//
+	// Returns:
+	//   - The index of the bucket, -1 if every bucket is skipped
+	next(hash uint64, n int, skip func(i int) bool) int
+}
+
+// Modulo is the default Strategy, it picks the bucket hash % n.
+// Resizing the bucket list remaps almost every key.
+type Modulo struct{}
+
+// Pick returns hash % n.
+func (Modulo) Pick(hash uint64, n int) int {
+	return int(hash % uint64(n))
+}
+
+// next returns the first bucket not skipped from hash % n on, in index order.
+func (m Modulo) next(hash uint64, n int, skip func(i int) bool) int {
+	return following(m.Pick(hash, n), n, skip)
+}
+
+// Jump is a Strategy using the jump consistent hash of Lamping and Veach.
+// It needs no memory and spreads the keys evenly, but only supports adding
+// and removing buckets at the end of the list.
+type Jump struct{}
+
+// Pick returns the jump consistent hash of the key among n buckets.
+func (Jump) Pick(hash uint64, n int) int {
+	key := mix(hash)
+	b, j := int64(-1), int64(0)
+	for j < int64(n) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// next returns the first bucket not skipped from the jump consistent hash of
+// the key on, in index order.
+func (j Jump) next(hash uint64, n int, skip func(i int) bool) int {
+	return following(j.Pick(hash, n), n, skip)
+}
+
+// Rendezvous is a Strategy using rendezvous, or highest random weight,
+// hashing: every bucket scores the key, and the highest score wins. It costs
+// O(n) per key.
+//
+// With Weights, every bucket gets a share of the keys proportional to its
+// weight, its score being -weight/ln(u) for a uniform u derived from the key.
+// Changing a weight only moves keys from or to that bucket.
+type Rendezvous struct {
+	// Weights are the weights of the buckets by index. Buckets without a
+	// weight have a weight of 1, buckets with a weight of zero or less get
+	// no key unless every bucket does.
+	Weights []float64
+}
+
+// Pick returns the bucket with the highest score for the key.
+func (r Rendezvous) Pick(hash uint64, n int) int {
+	return r.best(hash, n, nil)
+}
+
+// next returns the bucket with the highest score for the key, among the
+// buckets not skipped.
+func (r Rendezvous) next(hash uint64, n int, skip func(i int) bool) int {
+	return r.best(hash, n, skip)
+}
+
+// best returns the bucket with the highest score for the key, among the
+// buckets not skipped.
+//
+// Parameters:
+//   - hash: The hash of the key
+//   - n: The number of buckets
+//   - skip: An optional function telling whether a bucket is skipped
+//
+// Returns:
+//   - The index of the bucket, -1 if every bucket is skipped
+func (r Rendezvous) best(hash uint64, n int, skip func(i int) bool) int {
+	best, bestScore, bestWeighted := -1, uint64(0), 0.0
+	for i := 0; i < n; i++ {
+		if skip != nil && skip(i) {
+			continue
+		}
+		score := score(hash, i)
+		if r.Weights == nil {
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+			continue
+		}
+		if weighted := r.weighted(score, i); best < 0 || weighted > bestWeighted {
+			best, bestWeighted = i, weighted
+		}
+	}
+	return best
+}
+
+// weighted returns the weighted score -weight/ln(u) of a bucket, u being its
+// score mapped into (0, 1).
+func (r Rendezvous) weighted(score uint64, bucket int) float64 {
+	weight := 1.0
+	if bucket < len(r.Weights) {
+		weight = r.Weights[bucket]
+	}
+	if weight <= 0 {
+		return 0
+	}
+	u := (float64(score>>11) + 0.5) / (1 << 53)
+	return weight / -math.Log(u)
+}
+
+// Ring is a Strategy using a consistent hash ring: every bucket is placed on
+// the ring at VirtualNodes points, and a key belongs to the bucket of the
+// first point after its hash. More virtual nodes spread the keys more evenly
+// at the cost of memory.
+//
+// The ring of every bucket count is built once and kept, so a Ring must be
+// shared by pointer. The zero value is ready to use.
+type Ring struct {
+	// VirtualNodes is the number of points of every bucket, 160 if not provided.
+	VirtualNodes int
+
+	// rings holds the points of the ring by bucket count
+	rings sync.Map
+}
+
+// point is a point of a ring.
+type point struct {
+	// pos is the position on the ring
+	pos uint64
+
+	// bucket is the index of the bucket of the point
+	bucket int
+}
+
+// Pick returns the bucket of the first point of the ring after the key.
+func (ring *Ring) Pick(hash uint64, n int) int {
+	points := ring.points(n)
+	pos := mix(hash)
+	i := sort.Search(len(points), func(i int) bool {
+		return points[i].pos >= pos
+	})
+	if i == len(points) {
+		// Wrap around the ring
+		i = 0
+	}
+	return points[i].bucket
+}
+
+// next returns the bucket of the first point of the ring after the key that
+// isn't skipped, walking the ring clockwise.
+func (ring *Ring) next(hash uint64, n int, skip func(i int) bool) int {
+	points := ring.points(n)
+	pos := mix(hash)
+	start := sort.Search(len(points), func(i int) bool {
+		return points[i].pos >= pos
+	})
+	for k := 0; k < len(points); k++ {
+		// Wrap around the ring
+		bucket := points[(start+k)%len(points)].bucket
+		if skip == nil || !skip(bucket) {
+			return bucket
+		}
+	}
+	return -1
+}
+
+// points returns the sorted points of the ring of n buckets, building it once.
+func (ring *Ring) points(n int) []point {
+	if points, ok := ring.rings.Load(n); ok {
+		return points.([]point)
+	}
+
+	vnodes := ring.VirtualNodes
+	if vnodes <= 0 {
+		vnodes = 160
+	}
+	points := make([]point, 0, n*vnodes)
+	for i := 0; i < n; i++ {
+		for v := 0; v < vnodes; v++ {
+			points = append(points, point{pos: mix(uint64(i)<<32 | uint64(v)), bucket: i})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].pos < points[j].pos
+	})
+
+	actual, _ := ring.rings.LoadOrStore(n, points)
+	return actual.([]point)
+}
+
+// following returns the first bucket not skipped from first on, in index
+// order wrapping around.
+//
+// Parameters:
+//   - first: The index of the first candidate
+//   - n: The number of buckets
+//   - skip: An optional function telling whether a bucket is skipped
+//
+// Returns:
+//   - The index of the bucket, -1 if every bucket is skipped
+func following(first int, n int, skip func(i int) bool) int {
+	for k := 0; k < n; k++ {
+		if i := (first + k) % n; skip == nil || !skip(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// score returns the rendezvous score of a bucket for a key hash.
+func score(hash uint64, bucket int) uint64 {
+	return mix(hash ^ mix(uint64(bucket)+1))
+}
+
+// mix is the finalizer of SplitMix64, it spreads the bits of a hash, so that
+// 32-bit hashes cover the whole 64-bit space.
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,144 @@
+package gouache
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"testing"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// keyHashes returns the FNV-1a hashes of n keys.
+func keyHashes(n int) []uint64 {
+	hashes := make([]uint64, n)
+	for i := range hashes {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(fmt.Sprintf("key%d", i)))
+		hashes[i] = h.Sum64()
+	}
+	return hashes
+}
+
+// TestStrategy_Balance tests that every strategy spreads the keys evenly.
+func TestStrategy_Balance(t *testing.T) {
+	hashes := keyHashes(80000)
+	for _, strategy := range []Strategy{Modulo{}, Jump{}, Rendezvous{}, &Ring{}} {
+		counts := make([]int, 8)
+		for _, hash := range hashes {
+			counts[strategy.Pick(hash, len(counts))]++
+		}
+		for i, count := range counts {
+			// Every bucket should get 10000 keys, within 15%
+			if count < 8500 || count > 11500 {
+				t.Errorf("%T: expected about 10000 keys in bucket %d, got %d", strategy, i, count)
+			}
+		}
+	}
+}
+
+// TestStrategy_Resize tests that the consistent strategies only move about 1/n of the keys.
+func TestStrategy_Resize(t *testing.T) {
+	hashes := keyHashes(20000)
+	moved := func(strategy Strategy, from, to int) float64 {
+		count := 0
+		for _, hash := range hashes {
+			if strategy.Pick(hash, from) != strategy.Pick(hash, to) {
+				count++
+			}
+		}
+		return float64(count) / float64(len(hashes))
+	}
+
+	for _, strategy := range []Strategy{Jump{}, Rendezvous{}, &Ring{}} {
+		// Growing from 8 to 9 buckets should move about 1/9 of the keys
+		if ratio := moved(strategy, 8, 9); ratio < 0.08 || ratio > 0.15 {
+			t.Errorf("%T: expected about 11%% of the keys to move, got %.1f%%", strategy, ratio*100)
+		}
+	}
+	if ratio := moved(Modulo{}, 8, 9); ratio < 0.8 {
+		t.Errorf("Modulo: expected most keys to move, got %.1f%%", ratio*100)
+	}
+}
+
+// TestStrategy_Keys tests that a key keeps its bucket when a bucket is added.
+func TestStrategy_Keys(t *testing.T) {
+	ctx := context.Background()
+	old := []gouache.Cache{&sample.Cache{}, &sample.Cache{}, &sample.Cache{}}
+	ring := &Ring{VirtualNodes: 50}
+	cache := New(old, WithStrategy(ring))
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+
+	// Keys that were not moved to the new bucket are still found
+	grown := New(append(old, &sample.Cache{}), WithStrategy(ring))
+	found := 0
+	for i := 0; i < 100; i++ {
+		if val, err := grown.Get(ctx, fmt.Sprintf("key%d", i)); err == nil && val == i {
+			found++
+		}
+	}
+	if found < 60 || found == 100 {
+		t.Errorf("Expected about 75 keys to stay in place, got %d", found)
+	}
+}
+
+// TestRendezvous_Weights tests that the buckets get shares of the keys proportional to their weights.
+func TestRendezvous_Weights(t *testing.T) {
+	hashes := keyHashes(40000)
+	weighted := Rendezvous{Weights: []float64{1, 3, 0}}
+	counts := make([]int, 3)
+	for _, hash := range hashes {
+		counts[weighted.Pick(hash, len(counts))]++
+	}
+	if counts[0] < 8500 || counts[0] > 11500 || counts[1] < 28500 || counts[1] > 31500 || counts[2] != 0 {
+		t.Errorf("Expected about 10000, 30000 and 0 keys, got %v", counts)
+	}
+
+	// Equal weights pick the same buckets as no weights
+	equal := Rendezvous{Weights: []float64{2, 2, 2, 2}}
+	for _, hash := range hashes {
+		if equal.Pick(hash, 4) != (Rendezvous{}).Pick(hash, 4) {
+			t.Fatalf("Expected equal weights to pick the unweighted bucket of %d", hash)
+		}
+	}
+}
+
+// TestStrategy_Next tests that the candidates of every strategy start with the picked bucket and follow its order.
+func TestStrategy_Next(t *testing.T) {
+	ring := &Ring{}
+	for _, hash := range keyHashes(100) {
+		for _, strategy := range []successor{Modulo{}, Jump{}, Rendezvous{}, ring} {
+			if next, pick := strategy.next(hash, 8, nil), strategy.(Strategy).Pick(hash, 8); next != pick {
+				t.Fatalf("%T: expected the picked bucket %d first, got %d", strategy, pick, next)
+			}
+			if next := strategy.next(hash, 8, func(i int) bool { return true }); next != -1 {
+				t.Fatalf("%T: expected no candidate, got %d", strategy, next)
+			}
+		}
+
+		// Modulo and Jump continue with the next indexes
+		pick := (Jump{}).Pick(hash, 8)
+		if next := (Jump{}).next(hash, 8, func(i int) bool { return i == pick }); next != (pick+1)%8 {
+			t.Errorf("Jump: expected %d after %d, got %d", (pick+1)%8, pick, next)
+		}
+
+		// Ring continues with the bucket of the next point owned by another bucket
+		points := ring.points(8)
+		pos := mix(hash)
+		start := sort.Search(len(points), func(i int) bool { return points[i].pos >= pos })
+		owner := points[start%len(points)].bucket
+		expected := -1
+		for k := 1; expected < 0; k++ {
+			if bucket := points[(start+k)%len(points)].bucket; bucket != owner {
+				expected = bucket
+			}
+		}
+		if next := ring.next(hash, 8, func(i int) bool { return i == owner }); next != expected {
+			t.Errorf("Ring: expected %d after %d, got %d", expected, owner, next)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package gouache
+
+import "sync/atomic"
+
+// Stats holds the counters of a cache since it was created.
+type Stats struct {
+	// Hits is the number of lookups that found the key.
+	Hits int64
+
+	// Misses is the number of lookups that didn't find the key.
+	Misses int64
+
+	// Sets is the number of stored values.
+	Sets int64
+
+	// Deletes is the number of keys requested for deletion, whether they
+	// existed or not. Deletions failing with another error aren't counted.
+	Deletes int64
+
+	// Evictions is the number of entries removed by the cache itself,
+	// because they expired or to make room for others.
+	Evictions int64
+
+	// Entries is the current number of entries.
+	Entries int64
+
+	// Bytes is the current size of the entries in bytes, zero if unknown.
+	Bytes int64
+}
+
+// Add returns the sum of two stats, e.g. of the buckets of a sharded cache.
+//
+// Parameters:
+//   - other: The stats to add
+//
+// Returns:
+//   - The sum of every counter
+func (stats Stats) Add(other Stats) Stats {
+	return Stats{
+		Hits:      stats.Hits + other.Hits,
+		Misses:    stats.Misses + other.Misses,
+		Sets:      stats.Sets + other.Sets,
+		Deletes:   stats.Deletes + other.Deletes,
+		Evictions: stats.Evictions + other.Evictions,
+		Entries:   stats.Entries + other.Entries,
+		Bytes:     stats.Bytes + other.Bytes,
+	}
+}
+
+// HitRatio returns the ratio of hits to lookups.
+//
+// Returns:
+//   - The hit ratio, zero if there was no lookup
+func (stats Stats) HitRatio() float64 {
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		return float64(stats.Hits) / float64(lookups)
+	}
+	return 0
+}
+
+// StatsCache is an optional interface that a Cache can implement to report
+// its counters, e.g. to debug hit ratios without a metrics stack.
+//
+// Implementations report zero for the values they can't track: the caches
+// storing Go values, like the lru and gocache ones, don't know the size of
+// their entries in bytes, and a bigcache Cache only tracks its bytes and the
+// evictions of BigCache when created with its New function.
+type StatsCache interface {
+	// Stats returns the counters of the cache.
+	//
+	// Returns:
+	//   - A snapshot of the counters
+	Stats() Stats
+}
+
+// Counters counts the operations of a cache, for the implementations of
+// StatsCache. The entries and bytes are left to the implementations.
+//
+// The zero value is ready to use.
+type Counters struct {
+	hits      atomic.Int64
+	misses    atomic.Int64
+	sets      atomic.Int64
+	deletes   atomic.Int64
+	evictions atomic.Int64
+}
+
+// AddHits adds n lookups that found the key.
+func (counters *Counters) AddHits(n int64) {
+	counters.hits.Add(n)
+}
+
+// AddMisses adds n lookups that didn't find the key.
+func (counters *Counters) AddMisses(n int64) {
+	counters.misses.Add(n)
+}
+
+// AddSets adds n stored values.
+func (counters *Counters) AddSets(n int64) {
+	counters.sets.Add(n)
+}
+
+// AddDeletes adds n deleted keys.
+func (counters *Counters) AddDeletes(n int64) {
+	counters.deletes.Add(n)
+}
+
+// AddEvictions adds n entries removed by the cache itself.
+func (counters *Counters) AddEvictions(n int64) {
+	counters.evictions.Add(n)
+}
+
+// Stats returns a snapshot of the counters, without entries nor bytes.
+//
+// Returns:
+//   - The counted operations
+func (counters *Counters) Stats() Stats {
+	return Stats{
+		Hits:      counters.hits.Load(),
+		Misses:    counters.misses.Load(),
+		Sets:      counters.sets.Load(),
+		Deletes:   counters.deletes.Load(),
+		Evictions: counters.evictions.Load(),
+	}
+}
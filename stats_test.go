@@ -0,0 +1,29 @@
+package gouache_test
+
+import (
+	"testing"
+
+	"github.com/go-leo/gouache"
+)
+
+// TestStats tests that stats are added and their hit ratio computed.
+func TestStats(t *testing.T) {
+	var counters gouache.Counters
+	counters.AddHits(3)
+	counters.AddMisses(1)
+	counters.AddSets(2)
+	counters.AddDeletes(1)
+	counters.AddEvictions(1)
+
+	stats := counters.Stats().Add(gouache.Stats{Hits: 1, Entries: 5, Bytes: 10})
+	expected := gouache.Stats{Hits: 4, Misses: 1, Sets: 2, Deletes: 1, Evictions: 1, Entries: 5, Bytes: 10}
+	if stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+	if ratio := stats.HitRatio(); ratio != 0.8 {
+		t.Errorf("Expected a hit ratio of 0.8, got %v", ratio)
+	}
+	if ratio := (gouache.Stats{}).HitRatio(); ratio != 0 {
+		t.Errorf("Expected a zero hit ratio without lookups, got %v", ratio)
+	}
+}
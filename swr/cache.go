@@ -0,0 +1,453 @@
+// Package swr (Stale While Revalidate) provides a read-through cache that
+// serves stale values while refreshing them in the background.
+//
+// This package implements the gouache.Cache interface by wrapping an existing
+// cache. Every value is stored together with a soft expiry time:
+// 1. Before the soft expiry the value is fresh and returned as-is
+// 2. After the soft expiry the value is stale, it is still returned immediately
+// and a single background refresh is triggered for the key
+// 3. After the hard TTL the entry is gone from the cache, and the caller waits
+// for the value to be loaded
+package swr
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/loader"
+	"golang.org/x/sync/singleflight"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Gopher is a function type that executes a given function asynchronously.
+// It's used to run background refreshes.
+type Gopher func(f func()) error
+
+// Entry is the value stored in the underlying cache, a cached value together
+// with the time after which it is stale. Caches that serialize values need
+// a Marshal/Unmarshal pair that round-trips Entry.
+type Entry struct {
+	// Value is the cached value.
+	Value any
+
+	// SoftExpiry is the time after which the value is stale and refreshed.
+	SoftExpiry time.Time
+}
+
+// options holds configuration options for the stale-while-revalidate cache.
+type options struct {
+	// SoftTTL is how long a loaded value is fresh.
+	SoftTTL time.Duration
+
+	// HardTTL is how long a loaded value stays in the cache, stale or not.
+	HardTTL time.Duration
+
+	// RefreshTimeout is the timeout for the background refresh operation.
+	RefreshTimeout time.Duration
+
+	// ErrorHandler is called when an error occurs during a background refresh.
+	ErrorHandler func(error)
+
+	// Gopher is responsible for executing functions asynchronously.
+	Gopher Gopher
+
+	// NegativeTTL is the time-to-live of the gouache.Absent entries remembering
+	// keys the Loader doesn't have, zero disables negative caching.
+	NegativeTTL time.Duration
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithSoftTTL returns an Option that sets how long a loaded value is fresh.
+//
+// Parameters:
+//   - dur: The duration after which a value is stale
+//
+// Returns:
+//   - An Option function that sets the SoftTTL
+func WithSoftTTL(dur time.Duration) Option {
+	return func(o *options) {
+		o.SoftTTL = dur
+	}
+}
+
+// WithHardTTL returns an Option that sets how long a loaded value stays in
+// the cache. Once it has passed, the caller must wait for the value to be
+// loaded again.
+//
+// Parameters:
+//   - dur: The duration after which a value is removed from the cache
+//
+// Returns:
+//   - An Option function that sets the HardTTL
+func WithHardTTL(dur time.Duration) Option {
+	return func(o *options) {
+		o.HardTTL = dur
+	}
+}
+
+// WithRefreshTimeout returns an Option that sets the timeout for the
+// background refresh operation.
+//
+// Parameters:
+//   - dur: The timeout duration for the background refresh operation
+//
+// Returns:
+//   - An Option function that sets the RefreshTimeout
+func WithRefreshTimeout(dur time.Duration) Option {
+	return func(o *options) {
+		o.RefreshTimeout = dur
+	}
+}
+
+// WithErrorHandler returns an Option that sets a custom error handler for
+// errors that occur during a background refresh.
+//
+// Parameters:
+//   - f: A function to handle errors
+//
+// Returns:
+//   - An Option function that sets the ErrorHandler
+func WithErrorHandler(f func(error)) Option {
+	return func(o *options) {
+		o.ErrorHandler = f
+	}
+}
+
+// WithGopher returns an Option that sets a custom Gopher function for
+// executing background refreshes.
+//
+// Parameters:
+//   - gopher: A function that executes other functions asynchronously
+//
+// Returns:
+//   - An Option function that sets the Gopher
+func WithGopher(gopher Gopher) Option {
+	return func(o *options) {
+		o.Gopher = gopher
+	}
+}
+
+// WithNegativeTTL returns an Option that enables negative caching.
+// When the Loader returns gouache.ErrNotFound for a key, gouache.Absent is
+// stored in the cache for the given duration, and lookups of the key return
+// gouache.ErrNotFound without calling the Loader until it expires. Without
+// negative caching the entry of the key is removed instead.
+//
+// Parameters:
+//   - dur: The time-to-live of absent entries, zero or less to disable negative caching
+//
+// Returns:
+//   - An Option function that sets the NegativeTTL
+func WithNegativeTTL(dur time.Duration) Option {
+	return func(o *options) {
+		o.NegativeTTL = dur
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Set default soft TTL to 1m if not specified or invalid
+	if o.SoftTTL <= 0 {
+		o.SoftTTL = time.Minute
+	}
+
+	// Keep stale values for as long again as they are fresh if the hard TTL
+	// is not specified or doesn't leave room for stale values
+	if o.HardTTL <= o.SoftTTL {
+		o.HardTTL = 2 * o.SoftTTL
+	}
+
+	// Set default refresh timeout to 10s if not specified or invalid
+	if o.RefreshTimeout <= 0 {
+		o.RefreshTimeout = 10 * time.Second
+	}
+
+	// Set default error handler if not specified
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = func(err error) {
+			slog.Error("swr.Cache.Get", slog.String("err", err.Error()))
+		}
+	}
+
+	// Set default Gopher if not specified
+	if o.Gopher == nil {
+		o.Gopher = func(f func()) error {
+			go f()
+			return nil
+		}
+	}
+	return o
+}
+
+// Cache is a read-through cache that serves stale values while refreshing
+// them in the background.
+type Cache struct {
+	// options contains configuration options for the cache
+	options *options
+
+	// cache is the underlying cache implementation
+	cache gouache.Cache
+
+	// loader loads the values missing from the cache
+	loader loader.Loader
+
+	// group is the singleflight group used to coalesce loads of the same key.
+	group singleflight.Group
+
+	// refreshing holds the keys with a background refresh in progress.
+	refreshing sync.Map
+}
+
+// New creates a new stale-while-revalidate cache with the specified cache,
+// loader and options.
+//
+// Parameters:
+//   - c: The underlying cache implementation, it should implement gouache.TTLCache
+//     so that entries are removed after the hard TTL
+//   - l: The function loading values missing from the cache
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A stale-while-revalidate cache
+func New(c gouache.Cache, l loader.Loader, opts ...Option) *Cache {
+	return &Cache{options: newOptions(opts...), cache: c, loader: l}
+}
+
+// Middleware returns a gouache.Middleware refreshing the stale entries of the next cache in the background.
+//
+// Parameters:
+//   - l: The loader of the missing values
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A middleware wrapping the next cache with New
+func Middleware(l loader.Loader, opts ...Option) gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		return New(next, l, opts...)
+	}
+}
+
+// Get retrieves a value from the cache by its key.
+//
+// A fresh value is returned as-is. A stale value is returned immediately and
+// refreshed in the background, at most one refresh runs per key at a time.
+// A missing value is loaded before returning, concurrent loads of the same
+// key are coalesced.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached or loaded value
+//   - An error if the operation or the load fails
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	// Try to get the entry from cache first
+	val, err := cache.cache.Get(ctx, key)
+
+	// If cache miss, wait for the value to be loaded
+	if errors.Is(err, gouache.ErrCacheMiss) {
+		val, err, _ := cache.group.Do(key, func() (any, error) {
+			return cache.load(ctx, key)
+		})
+		return val, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys remembered as absent are not loaded again until they expire
+	if gouache.IsAbsent(val) {
+		return nil, gouache.ErrNotFound
+	}
+
+	// Unwrap the entry
+	entry, err := toEntry(key, val)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh stale values in the background
+	if !time.Now().Before(entry.SoftExpiry) {
+		cache.refresh(ctx, key)
+	}
+	return entry.Value, nil
+}
+
+// Set stores a fresh value in the cache under the specified key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	return cache.store(ctx, key, val)
+}
+
+// Delete removes a value from the cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	return cache.cache.Delete(ctx, key)
+}
+
+// refresh reloads the value of a key in the background, unless a refresh
+// for the key is already in progress.
+//
+// Parameters:
+//   - ctx: Context of the operation that found the stale value
+//   - key: The key to refresh
+func (cache *Cache) refresh(ctx context.Context, key string) {
+	// Only one refresh per key at a time
+	if _, loaded := cache.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+
+	err := cache.options.Gopher(func() {
+		defer cache.refreshing.Delete(key)
+
+		// Create a new context without the original cancellation
+		ctx := context.WithoutCancel(ctx)
+
+		// Add timeout to the context
+		ctx, cancel := context.WithTimeout(ctx, cache.options.RefreshTimeout)
+		defer cancel()
+
+		// Reload the value
+		_, err, _ := cache.group.Do(key, func() (any, error) {
+			return cache.load(ctx, key)
+		})
+		if err != nil {
+			cache.options.ErrorHandler(err)
+		}
+	})
+	if err != nil {
+		// The refresh was not started, allow the next Get to try again
+		cache.refreshing.Delete(key)
+		cache.options.ErrorHandler(err)
+	}
+}
+
+// load loads the value for a key with the Loader and stores it in the cache.
+// If the Loader reports the key as gouache.ErrNotFound, the key is remembered
+// as absent, or its entry removed without negative caching.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to load the value for
+//
+// Returns:
+//   - The loaded value
+//   - An error if the load fails, or the value cannot be stored
+func (cache *Cache) load(ctx context.Context, key string) (any, error) {
+	val, err := cache.loader(ctx, key)
+	if errors.Is(err, gouache.ErrNotFound) {
+		// The value no longer exists, stop serving it
+		if absentErr := cache.absent(ctx, key); absentErr != nil {
+			return nil, absentErr
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return val, cache.store(ctx, key, val)
+}
+
+// store wraps a value into a fresh Entry and stores it in the cache for
+// the hard TTL.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) store(ctx context.Context, key string, val any) error {
+	entry := &Entry{Value: val, SoftExpiry: time.Now().Add(cache.options.SoftTTL)}
+	return gouache.SetWithTTL(ctx, cache.cache, key, entry, cache.options.HardTTL)
+}
+
+// absent replaces the entry of a key the Loader doesn't have with
+// gouache.Absent for the NegativeTTL, or removes it without negative caching.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key the Loader doesn't have
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) absent(ctx context.Context, key string) error {
+	if cache.options.NegativeTTL > 0 {
+		return gouache.SetWithTTL(ctx, cache.cache, key, gouache.Absent, cache.options.NegativeTTL)
+	}
+	return cache.cache.Delete(ctx, key)
+}
+
+// toEntry converts a cached value to an Entry.
+//
+// Parameters:
+//   - key: The key the value belongs to, used in the error
+//   - val: The cached value
+//
+// Returns:
+//   - The entry
+//   - A *gouache.TypeMismatchError if the value is not an Entry
+func toEntry(key string, val any) (*Entry, error) {
+	switch entry := val.(type) {
+	case *Entry:
+		return entry, nil
+	case Entry:
+		return &entry, nil
+	default:
+		return nil, &gouache.TypeMismatchError{Key: key, Expected: reflect.TypeOf((*Entry)(nil)), Value: val}
+	}
+}
@@ -0,0 +1,239 @@
+package swr
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// versionLoader is a loader that returns an increasing version number on every call.
+type versionLoader struct {
+	calls atomic.Int32
+	delay time.Duration
+	err   atomic.Value
+}
+
+func (l *versionLoader) Load(ctx context.Context, key string) (any, error) {
+	n := l.calls.Add(1)
+	if l.delay > 0 {
+		time.Sleep(l.delay)
+	}
+	if err, ok := l.err.Load().(error); ok {
+		return nil, err
+	}
+	return int(n), nil
+}
+
+// syncGopher returns a Gopher that runs functions in a goroutine and lets
+// the test wait for them to finish.
+func syncGopher(wg *sync.WaitGroup) Gopher {
+	return func(f func()) error {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+		return nil
+	}
+}
+
+// TestCache_Fresh tests that fresh values are served from the cache.
+func TestCache_Fresh(t *testing.T) {
+	l := &versionLoader{}
+	cache := New(&sample.Cache{}, l.Load, WithSoftTTL(time.Minute))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		val, err := cache.Get(ctx, "key")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if val != 1 {
+			t.Errorf("Expected 1, got %v", val)
+		}
+	}
+	if calls := l.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 load, got %d", calls)
+	}
+}
+
+// TestCache_Stale tests that stale values are served immediately and refreshed once.
+func TestCache_Stale(t *testing.T) {
+	var wg sync.WaitGroup
+	l := &versionLoader{delay: 20 * time.Millisecond}
+	cache := New(&sample.Cache{}, l.Load,
+		WithSoftTTL(10*time.Millisecond),
+		WithHardTTL(time.Minute),
+		WithGopher(syncGopher(&wg)),
+	)
+	ctx := context.Background()
+
+	// Load the first version
+	if _, err := cache.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Let the value become stale
+	time.Sleep(15 * time.Millisecond)
+
+	// Concurrent reads get the stale value without waiting for the refresh
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		val, err := cache.Get(ctx, "key")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if val != 1 {
+			t.Errorf("Expected stale 1, got %v", val)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= l.delay {
+		t.Errorf("Expected stale reads not to wait for the refresh, took %v", elapsed)
+	}
+
+	// Exactly one refresh ran
+	wg.Wait()
+	if calls := l.calls.Load(); calls != 2 {
+		t.Errorf("Expected 2 loads, got %d", calls)
+	}
+
+	// The refreshed value is served
+	val, err := cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val != 2 {
+		t.Errorf("Expected refreshed 2, got %v", val)
+	}
+}
+
+// TestCache_HardTTL tests that the caller waits for the load once the hard TTL has passed.
+func TestCache_HardTTL(t *testing.T) {
+	l := &versionLoader{}
+	cache := New(&sample.Cache{}, l.Load,
+		WithSoftTTL(10*time.Millisecond),
+		WithHardTTL(20*time.Millisecond),
+	)
+	ctx := context.Background()
+
+	if _, err := cache.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Let the entry expire completely
+	time.Sleep(30 * time.Millisecond)
+
+	val, err := cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val != 2 {
+		t.Errorf("Expected reloaded 2, got %v", val)
+	}
+}
+
+// TestCache_RefreshError tests that a failed refresh keeps serving the stale value.
+func TestCache_RefreshError(t *testing.T) {
+	var wg sync.WaitGroup
+	var handled atomic.Int32
+	l := &versionLoader{}
+	cache := New(&sample.Cache{}, l.Load,
+		WithSoftTTL(10*time.Millisecond),
+		WithHardTTL(time.Minute),
+		WithGopher(syncGopher(&wg)),
+		WithErrorHandler(func(err error) { handled.Add(1) }),
+	)
+	ctx := context.Background()
+
+	if _, err := cache.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(15 * time.Millisecond)
+
+	// Make the refresh fail
+	l.err.Store(errors.New("load error"))
+	val, err := cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wg.Wait()
+
+	if val != 1 {
+		t.Errorf("Expected stale 1, got %v", val)
+	}
+	if n := handled.Load(); n != 1 {
+		t.Errorf("Expected the error handler to be called once, got %d", n)
+	}
+
+	// The stale value is still served
+	if val, err := cache.Get(ctx, "key"); err != nil || val != 1 {
+		t.Errorf("Expected stale 1, got %v and %v", val, err)
+	}
+	wg.Wait()
+}
+
+// TestCache_NotFound tests that a value the Loader no longer has is removed on refresh.
+func TestCache_NotFound(t *testing.T) {
+	var wg sync.WaitGroup
+	underlying := &sample.Cache{}
+	l := &versionLoader{}
+	cache := New(underlying, l.Load,
+		WithSoftTTL(10*time.Millisecond),
+		WithHardTTL(time.Minute),
+		WithGopher(syncGopher(&wg)),
+		WithErrorHandler(func(err error) {}),
+	)
+	ctx := context.Background()
+
+	if _, err := cache.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(15 * time.Millisecond)
+
+	// The refresh finds out that the value doesn't exist anymore
+	l.err.Store(gouache.ErrNotFound)
+	if _, err := cache.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wg.Wait()
+
+	if _, err := underlying.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the entry to be removed, got %v", err)
+	}
+}
+
+// TestCache_NegativeTTL tests that keys the Loader doesn't have are remembered as absent.
+func TestCache_NegativeTTL(t *testing.T) {
+	underlying := &sample.Cache{}
+	l := &versionLoader{}
+	l.err.Store(gouache.ErrNotFound)
+	cache := New(underlying, l.Load, WithNegativeTTL(10*time.Millisecond))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get(ctx, "key"); !errors.Is(err, gouache.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if n := l.calls.Load(); n != 1 {
+		t.Errorf("Expected the Loader to be called once, got %d", n)
+	}
+	if val, err := underlying.Get(ctx, "key"); err != nil || !gouache.IsAbsent(val) {
+		t.Errorf("Expected Absent, got %v and %v", val, err)
+	}
+
+	// The key is loaded again once the absent entry expired
+	time.Sleep(15 * time.Millisecond)
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, gouache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if n := l.calls.Load(); n != 2 {
+		t.Errorf("Expected the Loader to be called twice, got %d", n)
+	}
+}
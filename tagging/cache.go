@@ -0,0 +1,252 @@
+// Package tagging provides a cache implementation that attaches tags to
+// cache entries, so that all entries carrying a tag can be removed at once.
+//
+// This package implements the gouache.Cache interface by wrapping an existing
+// cache. The keys carrying each tag are recorded in an Index, an in-memory
+// MemoryIndex for in-process caches, or a Redis index for Redis caches.
+//
+//	cache := tagging.New(underlyingCache, &tagging.MemoryIndex{})
+//	err := cache.SetWithTags(ctx, "user:1:profile", profile, "user:1")
+//	err = cache.SetWithTags(ctx, "user:1:orders", orders, "user:1")
+//	err = cache.InvalidateTag(ctx, "user:1") // removes both entries
+package tagging
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-leo/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
+// Index records the keys carrying each tag.
+//
+// An index may return keys that are no longer cached, removing them again is
+// harmless. Entries should be forgotten once they expire, so that the index
+// doesn't grow without bounds.
+type Index interface {
+	// Add records that the key carries the tags, until expireAt, replacing
+	// the tags the key carried before. A zero expireAt means the entry does
+	// not expire.
+	Add(ctx context.Context, key string, tags []string, expireAt time.Time) error
+
+	// Keys returns the keys carrying the tag that may still be cached.
+	Keys(ctx context.Context, tag string) ([]string, error)
+
+	// Untag removes the keys from the tag.
+	Untag(ctx context.Context, tag string, keys []string) error
+
+	// Remove removes the key from all its tags.
+	Remove(ctx context.Context, key string) error
+}
+
+// Cache is a cache implementation that wraps another cache and records the
+// tags of its entries in an Index.
+//
+// The underlying cache may extend the TTL of the entries, e.g. with the
+// Jitter of redis.Cache or gocache.Cache, so the index can't know when they
+// expire: by default, the entries stay in the index until they are deleted
+// or their tag is invalidated. Set Grace to forget them once their TTL and
+// the grace period have passed.
+type Cache struct {
+	// Cache is the underlying cache implementation that stores the actual data.
+	Cache gouache.Cache
+
+	// Index records the keys carrying each tag.
+	Index Index
+
+	// Grace is how long the index keeps the entries stored with
+	// SetWithTTLAndTags after their TTL passed. It must be at least the
+	// maximum extension of the TTL by the underlying cache. Zero or less
+	// keeps the entries until they are deleted or their tag is invalidated.
+	Grace time.Duration
+}
+
+// New creates a tagging cache over a cache and an index. If the cache
+// implements gouache.EvictionCache, e.g. an lru.Cache created by lru.New,
+// the entries it evicts are removed from the index.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - index: The index of the tags
+//
+// Returns:
+//   - A tagging cache
+func New(c gouache.Cache, index Index) *Cache {
+	if evicting, ok := c.(gouache.EvictionCache); ok {
+		evicting.OnEvict(func(key string) {
+			_ = index.Remove(context.Background(), key)
+		})
+	}
+	return &Cache{Cache: c, Index: index}
+}
+
+// Middleware returns a gouache.Middleware recording the tags of the entries of
+// the next cache in an Index. The tagging methods are reached by asserting
+// the returned cache to *Cache.
+//
+// Parameters:
+//   - index: The index of the tags
+//
+// Returns:
+//   - A middleware wrapping the next cache with New
+func Middleware(index Index) gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		return New(next, index)
+	}
+}
+
+// Get retrieves a value from the underlying cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	return cache.Cache.Get(ctx, key)
+}
+
+// Set stores a value without tags in the underlying cache, then removes the
+// key from the tags it was previously stored with.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the underlying cache or the index fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	if err := cache.Cache.Set(ctx, key, val); err != nil {
+		return err
+	}
+	return cache.Index.Remove(ctx, key)
+}
+
+// Delete removes a value from the underlying cache, then from the index.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	if err := cache.Cache.Delete(ctx, key); err != nil {
+		return err
+	}
+	return cache.Index.Remove(ctx, key)
+}
+
+// SetWithTTL stores a value without tags in the underlying cache with the
+// given TTL, then removes the key from the tags it was previously stored with.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if the underlying cache or the index fails
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	if err := gouache.SetWithTTL(ctx, cache.Cache, key, val, ttl); err != nil {
+		return err
+	}
+	return cache.Index.Remove(ctx, key)
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if the operation fails
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	return gouache.GetWithTTL(ctx, cache.Cache, key)
+}
+
+// SetWithTags stores a value carrying the given tags, which replace the tags
+// the key was previously stored with.
+// The tags are recorded before the value is stored, so that a stored value
+// can always be found by its tags.
+//
+// The index doesn't know when the underlying cache expires the entry, prefer
+// SetWithTTLAndTags if it does.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - tags: The tags of the entry
+//
+// Returns:
+//   - An error if the index or the underlying cache fails
+func (cache *Cache) SetWithTags(ctx context.Context, key string, val any, tags ...string) error {
+	if err := cache.Index.Add(ctx, key, tags, time.Time{}); err != nil {
+		return err
+	}
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// SetWithTTLAndTags stores a value carrying the given tags with the given TTL.
+// The tags replace the tags the key was previously stored with. The index
+// forgets the entry once its TTL and the Grace have passed, if Grace is
+// positive.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//   - tags: The tags of the entry
+//
+// Returns:
+//   - An error if the index or the underlying cache fails
+func (cache *Cache) SetWithTTLAndTags(ctx context.Context, key string, val any, ttl time.Duration, tags ...string) error {
+	var expireAt time.Time
+	if ttl > 0 && cache.Grace > 0 {
+		expireAt = time.Now().Add(ttl + cache.Grace)
+	}
+	if err := cache.Index.Add(ctx, key, tags, expireAt); err != nil {
+		return err
+	}
+	return gouache.SetWithTTL(ctx, cache.Cache, key, val, ttl)
+}
+
+// InvalidateTag removes every entry carrying the tag from the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - tag: The tag to invalidate
+//
+// Returns:
+//   - An error if the index or the underlying cache fails
+func (cache *Cache) InvalidateTag(ctx context.Context, tag string) error {
+	keys, err := cache.Index.Keys(ctx, tag)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// Remove the entries before the tag, so that a failure can be retried
+	if err := gouache.DeleteMulti(ctx, cache.Cache, keys); err != nil && !errors.Is(err, gouache.ErrCacheMiss) {
+		return err
+	}
+	return cache.Index.Untag(ctx, tag, keys)
+}
@@ -0,0 +1,208 @@
+package tagging
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// TestCache_InvalidateTag tests that invalidating a tag removes every entry carrying it.
+func TestCache_InvalidateTag(t *testing.T) {
+	underlying := &sample.Cache{}
+	cache := &Cache{Cache: underlying, Index: &MemoryIndex{}}
+	ctx := context.Background()
+
+	if err := cache.SetWithTags(ctx, "user:1:profile", "profile", "user:1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cache.SetWithTags(ctx, "user:1:orders", "orders", "user:1", "orders"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cache.SetWithTags(ctx, "user:2:profile", "profile", "user:2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := cache.InvalidateTag(ctx, "user:1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The entries of user 1 are gone
+	for _, key := range []string{"user:1:profile", "user:1:orders"} {
+		if _, err := cache.Get(ctx, key); !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("Expected %s to be removed, got %v", key, err)
+		}
+	}
+
+	// The entries of user 2 are kept
+	if val, err := cache.Get(ctx, "user:2:profile"); err != nil || val != "profile" {
+		t.Errorf("Expected profile, got %v and %v", val, err)
+	}
+
+	// The tag is empty now
+	keys, err := cache.Index.Keys(ctx, "user:1")
+	if err != nil || len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v and %v", keys, err)
+	}
+}
+
+// TestCache_Delete tests that a deleted entry is removed from all its tags.
+func TestCache_Delete(t *testing.T) {
+	index := &MemoryIndex{}
+	cache := &Cache{Cache: &sample.Cache{}, Index: index}
+	ctx := context.Background()
+
+	if err := cache.SetWithTags(ctx, "key", "value", "a", "b"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, tag := range []string{"a", "b"} {
+		if keys, _ := index.Keys(ctx, tag); len(keys) != 0 {
+			t.Errorf("Expected no keys for %s, got %v", tag, keys)
+		}
+	}
+	if len(index.keys) != 0 || len(index.tags) != 0 {
+		t.Errorf("Expected an empty index, got %v and %v", index.keys, index.tags)
+	}
+}
+
+// TestCache_Expiration tests that the index forgets expired entries once the grace passed.
+func TestCache_Expiration(t *testing.T) {
+	index := &MemoryIndex{}
+	cache := &Cache{Cache: &sample.Cache{}, Index: index, Grace: 5 * time.Millisecond}
+	ctx := context.Background()
+
+	if err := cache.SetWithTTLAndTags(ctx, "short", "value", 5*time.Millisecond, "tag"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cache.SetWithTTLAndTags(ctx, "long", "value", time.Minute, "tag"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(15 * time.Millisecond)
+
+	keys, err := index.Keys(ctx, "tag")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 1 || keys[0] != "long" {
+		t.Errorf("Expected only long, got %v", keys)
+	}
+	if _, ok := index.keys["short"]; ok {
+		t.Error("Expected the expired key to be forgotten")
+	}
+}
+
+// TestMemoryIndex_Sweep tests that adds sweep the expired entries of other tags.
+func TestMemoryIndex_Sweep(t *testing.T) {
+	index := &MemoryIndex{}
+	ctx := context.Background()
+
+	if err := index.Add(ctx, "expired", []string{"old"}, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if err := index.Add(ctx, key, []string{"new"}, time.Time{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if _, ok := index.tags["old"]; ok {
+		t.Errorf("Expected the expired entry to be swept, got %v", index.tags)
+	}
+}
+
+// jitteredCache is a cache extending every TTL, like the Jitter of redis.Cache.
+type jitteredCache struct {
+	sample.Cache
+	extension time.Duration
+}
+
+func (c *jitteredCache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	return c.Cache.SetWithTTL(ctx, key, val, ttl+c.extension)
+}
+
+// TestCache_Jitter tests that entries whose TTL is extended by the underlying cache are still invalidated.
+func TestCache_Jitter(t *testing.T) {
+	underlying := &jitteredCache{extension: time.Minute}
+	cache := &Cache{Cache: underlying, Index: &MemoryIndex{}}
+	ctx := context.Background()
+
+	if err := cache.SetWithTTLAndTags(ctx, "key", "value", 5*time.Millisecond, "tag"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := cache.InvalidateTag(ctx, "tag"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := underlying.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the jittered entry to be invalidated, got %v", err)
+	}
+}
+
+// TestCache_ReplaceTags tests that storing a key again replaces its tags.
+func TestCache_ReplaceTags(t *testing.T) {
+	index := &MemoryIndex{}
+	cache := &Cache{Cache: &sample.Cache{}, Index: index}
+	ctx := context.Background()
+
+	_ = cache.SetWithTags(ctx, "key", "v1", "a", "b")
+	_ = cache.SetWithTags(ctx, "key", "v2", "b", "c")
+	for tag, expected := range map[string]int{"a": 0, "b": 1, "c": 1} {
+		if keys, _ := index.Keys(ctx, tag); len(keys) != expected {
+			t.Errorf("Expected %d keys for %s, got %v", expected, tag, keys)
+		}
+	}
+
+	// Invalidating a former tag keeps the value
+	_ = cache.InvalidateTag(ctx, "a")
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "v2" {
+		t.Errorf("Expected v2, got %v and %v", val, err)
+	}
+
+	// A value stored without tags loses them
+	_ = cache.Set(ctx, "key", "v3")
+	_ = cache.InvalidateTag(ctx, "b")
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "v3" {
+		t.Errorf("Expected v3, got %v and %v", val, err)
+	}
+	if len(index.keys) != 0 || len(index.tags) != 0 {
+		t.Errorf("Expected an empty index, got %v and %v", index.keys, index.tags)
+	}
+}
+
+// evictingCache is a cache reporting its evictions.
+type evictingCache struct {
+	sample.Cache
+	handlers []func(key string)
+}
+
+func (c *evictingCache) OnEvict(f func(key string)) {
+	c.handlers = append(c.handlers, f)
+}
+
+func (c *evictingCache) evict(ctx context.Context, key string) {
+	_ = c.Cache.Delete(ctx, key)
+	for _, f := range c.handlers {
+		f(key)
+	}
+}
+
+// TestNew_Evictions tests that the entries evicted by the underlying cache are removed from the index.
+func TestNew_Evictions(t *testing.T) {
+	underlying := &evictingCache{}
+	index := &MemoryIndex{}
+	cache := New(underlying, index)
+	ctx := context.Background()
+
+	_ = cache.SetWithTags(ctx, "key", "value", "a", "b")
+	underlying.evict(ctx, "key")
+	if len(index.keys) != 0 || len(index.tags) != 0 {
+		t.Errorf("Expected an empty index, got %v and %v", index.keys, index.tags)
+	}
+}
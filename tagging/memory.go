@@ -0,0 +1,179 @@
+package tagging
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Ensure that MemoryIndex implements the Index interface at compile time.
+var _ Index = (*MemoryIndex)(nil)
+
+// MemoryIndex is an in-memory Index, for in-process caches.
+//
+// Expired entries are forgotten lazily. Entries evicted by the underlying
+// cache are forgotten right away if the cache implements
+// gouache.EvictionCache and the tagging cache is created with New, e.g.:
+//
+//	lruCache, _ := lru.New(1000)
+//	cache := tagging.New(lruCache, &tagging.MemoryIndex{})
+//
+// Otherwise they are forgotten when their tag is invalidated.
+//
+// The zero value is an empty index ready to use.
+type MemoryIndex struct {
+	// mu guards the fields below
+	mu sync.Mutex
+
+	// tags holds the expiration time of every key by tag
+	tags map[string]map[string]time.Time
+
+	// keys holds the tags of every key
+	keys map[string]map[string]struct{}
+
+	// adds counts the Add calls since the last sweep of expired entries
+	adds int
+}
+
+// Add records that the key carries the tags, until expireAt, replacing the
+// tags the key carried before.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - tags: The tags of the entry
+//   - expireAt: The expiration time of the entry, zero for never
+//
+// Returns:
+//   - Always returns nil
+func (index *MemoryIndex) Add(ctx context.Context, key string, tags []string, expireAt time.Time) error {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if index.tags == nil {
+		index.tags = make(map[string]map[string]time.Time)
+		index.keys = make(map[string]map[string]struct{})
+	}
+
+	// Forget the previous tags of the key
+	for tag := range index.keys[key] {
+		index.untag(tag, key)
+	}
+
+	for _, tag := range tags {
+		// Record the key under the tag
+		members, ok := index.tags[tag]
+		if !ok {
+			members = make(map[string]time.Time)
+			index.tags[tag] = members
+		}
+		members[key] = expireAt
+
+		// Record the tag of the key
+		keyTags, ok := index.keys[key]
+		if !ok {
+			keyTags = make(map[string]struct{})
+			index.keys[key] = keyTags
+		}
+		keyTags[tag] = struct{}{}
+	}
+
+	// Sweep expired entries once there were as many adds as keys,
+	// which keeps the amortized cost of Add constant
+	index.adds++
+	if index.adds >= len(index.keys) {
+		index.sweep(time.Now())
+		index.adds = 0
+	}
+	return nil
+}
+
+// Keys returns the unexpired keys carrying the tag.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - tag: The tag
+//
+// Returns:
+//   - The keys carrying the tag
+//   - Always returns a nil error
+func (index *MemoryIndex) Keys(ctx context.Context, tag string) ([]string, error) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	now := time.Now()
+	keys := make([]string, 0, len(index.tags[tag]))
+	for key, expireAt := range index.tags[tag] {
+		if expired(expireAt, now) {
+			index.untag(tag, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Untag removes the keys from the tag.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - tag: The tag
+//   - keys: The keys to remove
+//
+// Returns:
+//   - Always returns nil
+func (index *MemoryIndex) Untag(ctx context.Context, tag string, keys []string) error {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	for _, key := range keys {
+		index.untag(tag, key)
+	}
+	return nil
+}
+
+// Remove removes the key from all its tags.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to remove
+//
+// Returns:
+//   - Always returns nil
+func (index *MemoryIndex) Remove(ctx context.Context, key string) error {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	for tag := range index.keys[key] {
+		index.untag(tag, key)
+	}
+	return nil
+}
+
+// untag removes a key from a tag, dropping emptied maps. The lock must be held.
+func (index *MemoryIndex) untag(tag string, key string) {
+	if members, ok := index.tags[tag]; ok {
+		delete(members, key)
+		if len(members) == 0 {
+			delete(index.tags, tag)
+		}
+	}
+	if keyTags, ok := index.keys[key]; ok {
+		delete(keyTags, tag)
+		if len(keyTags) == 0 {
+			delete(index.keys, key)
+		}
+	}
+}
+
+// sweep removes all expired entries. The lock must be held.
+func (index *MemoryIndex) sweep(now time.Time) {
+	for tag, members := range index.tags {
+		for key, expireAt := range members {
+			if expired(expireAt, now) {
+				index.untag(tag, key)
+			}
+		}
+	}
+}
+
+// expired reports whether an expiration time has passed, zero means never.
+func expired(expireAt time.Time, now time.Time) bool {
+	return !expireAt.IsZero() && !now.Before(expireAt)
+}
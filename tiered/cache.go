@@ -0,0 +1,215 @@
+// Package tiered provides a cache implementation that composes an ordered list
+// of caches into a multi-level cache, typically a short-lived in-process L1
+// cache in front of a shared distributed L2 cache.
+//
+// This package implements the gouache.Cache interface:
+// 1. Reads go top-down, the first layer that has the key answers, and the
+// layers above it are back-filled with the value
+// 2. Writes and deletes go through all layers bottom-up, so that an upper
+// layer never holds a value that a lower layer has lost
+package tiered
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-leo/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLCache interface at compile time.
+var _ gouache.TTLCache = (*Cache)(nil)
+
+// Layer is one level of a tiered cache.
+type Layer struct {
+	// Cache is the cache implementation of the layer.
+	Cache gouache.Cache
+
+	// TTL is an optional function to determine the time-to-live of the
+	// entries of the layer, so that for example L1 entries stay short-lived.
+	// If not provided, the layer's own expiration behavior is used.
+	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
+}
+
+// Cache is a multi-level cache, the first layer is the top (fastest) one.
+type Cache struct {
+	// Layers are the levels of the cache, ordered from top to bottom.
+	Layers []Layer
+
+	// ErrorHandler is an optional function called when back-filling an upper
+	// layer fails, the value read from the lower layer is returned anyway.
+	// If not provided, errors are logged with slog.
+	ErrorHandler func(error)
+}
+
+// Middleware returns a gouache.Middleware putting layers, e.g. an in-process
+// L1 cache, above the next cache, which becomes the bottom layer.
+//
+// Parameters:
+//   - layers: The layers above the next cache, ordered from top to bottom
+//
+// Returns:
+//   - A middleware wrapping the next cache into a Cache
+func Middleware(layers ...Layer) gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		all := make([]Layer, 0, len(layers)+1)
+		all = append(all, layers...)
+		return &Cache{Layers: append(all, Layer{Cache: next})}
+	}
+}
+
+// Get retrieves a value from the first layer that has the key, and back-fills
+// the layers above it. An entry back-filled from a layer that reports its
+// remaining time-to-live doesn't outlive the entry it was copied from.
+// It returns gouache.ErrCacheMiss if no layer has the key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if a layer fails, or gouache.ErrCacheMiss if no layer has the key
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	val, _, err := cache.GetWithTTL(ctx, key)
+	return val, err
+}
+
+// Set stores a value in all layers, bottom-up.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if storing the value in any layer fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	for i := len(cache.Layers) - 1; i >= 0; i-- {
+		if err := cache.set(ctx, cache.Layers[i], key, val, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a value from all layers, bottom-up, so that a concurrent Get
+// can't back-fill an upper layer from a lower layer that still has the value.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if removing the value from any layer fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	for i := len(cache.Layers) - 1; i >= 0; i-- {
+		if err := cache.Layers[i].Cache.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetWithTTL stores a value in all layers, bottom-up, with the given TTL.
+// A layer with a shorter TTL of its own keeps the shorter one.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if storing the value in any layer fails
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	for i := len(cache.Layers) - 1; i >= 0; i-- {
+		if err := cache.set(ctx, cache.Layers[i], key, val, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the first
+// layer that has the key, and back-fills the layers above it.
+// It returns gouache.ErrCacheMiss if no layer has the key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live in the layer that has the key, zero if unknown
+//   - An error if a layer fails, or gouache.ErrCacheMiss if no layer has the key
+func (cache *Cache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	for i, layer := range cache.Layers {
+		// Try the layers top-down
+		val, ttl, err := gouache.GetWithTTL(ctx, layer.Cache, key)
+		if errors.Is(err, gouache.ErrCacheMiss) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// Back-fill the layers above, bottom-up
+		for j := i - 1; j >= 0; j-- {
+			if err := cache.set(ctx, cache.Layers[j], key, val, ttl); err != nil {
+				cache.handleError(err)
+				break
+			}
+		}
+		return val, ttl, nil
+	}
+	return nil, 0, gouache.ErrCacheMiss
+}
+
+// set stores a value in a layer. The TTL is the layer's own TTL, capped by
+// the given TTL if positive.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - layer: The layer to store the value in
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - limit: The maximum time-to-live of the entry, zero or less for no limit
+//
+// Returns:
+//   - An error if the TTL function or the layer fails
+func (cache *Cache) set(ctx context.Context, layer Layer, key string, val any, limit time.Duration) error {
+	// Without a TTL of its own and a limit, let the layer decide
+	if layer.TTL == nil && limit <= 0 {
+		return layer.Cache.Set(ctx, key, val)
+	}
+
+	// Determine the TTL of the layer
+	var ttl time.Duration
+	if layer.TTL != nil {
+		var err error
+		ttl, err = layer.TTL(ctx, key, val)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Cap it by the limit
+	if limit > 0 && (ttl <= 0 || limit < ttl) {
+		ttl = limit
+	}
+	return gouache.SetWithTTL(ctx, layer.Cache, key, val, ttl)
+}
+
+// handleError reports a back-fill error to the ErrorHandler, or logs it.
+func (cache *Cache) handleError(err error) {
+	if cache.ErrorHandler != nil {
+		cache.ErrorHandler(err)
+		return
+	}
+	slog.Error("tiered.Cache.Get", slog.String("err", err.Error()))
+}
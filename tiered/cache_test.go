@@ -0,0 +1,157 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// failingCache is a cache whose writes always fail.
+type failingCache struct {
+	sample.Cache
+}
+
+func (c *failingCache) Set(ctx context.Context, key string, val any) error {
+	return errors.New("set error")
+}
+
+func (c *failingCache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	return errors.New("set error")
+}
+
+// fixedTTL returns a TTL function that always returns dur.
+func fixedTTL(dur time.Duration) func(ctx context.Context, key string, val any) (time.Duration, error) {
+	return func(ctx context.Context, key string, val any) (time.Duration, error) {
+		return dur, nil
+	}
+}
+
+// TestCache_BackFill tests that a lower layer hit back-fills the upper layers.
+func TestCache_BackFill(t *testing.T) {
+	l1, l2, l3 := &sample.Cache{}, &sample.Cache{}, &sample.Cache{}
+	cache := &Cache{Layers: []Layer{{Cache: l1}, {Cache: l2}, {Cache: l3}}}
+	ctx := context.Background()
+
+	if err := l3.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	val, err := cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val != "value" {
+		t.Errorf("Expected value, got %v", val)
+	}
+
+	// Both upper layers were back-filled
+	for i, layer := range []*sample.Cache{l1, l2} {
+		if val, err := layer.Get(ctx, "key"); err != nil || val != "value" {
+			t.Errorf("Expected layer %d to be back-filled, got %v and %v", i, val, err)
+		}
+	}
+
+	// A miss in every layer is a miss
+	if _, err := cache.Get(ctx, "other"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, got %v", err)
+	}
+}
+
+// TestCache_SetDelete tests that writes and deletes go through all layers.
+func TestCache_SetDelete(t *testing.T) {
+	l1, l2 := &sample.Cache{}, &sample.Cache{}
+	cache := &Cache{Layers: []Layer{{Cache: l1}, {Cache: l2}}}
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i, layer := range []*sample.Cache{l1, l2} {
+		if val, err := layer.Get(ctx, "key"); err != nil || val != "value" {
+			t.Errorf("Expected layer %d to have the value, got %v and %v", i, val, err)
+		}
+	}
+
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i, layer := range []*sample.Cache{l1, l2} {
+		if _, err := layer.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("Expected layer %d to be empty, got %v", i, err)
+		}
+	}
+}
+
+// TestCache_LayerTTL tests that each layer uses its own TTL, capped by the
+// remaining TTL of the layer a value is back-filled from.
+func TestCache_LayerTTL(t *testing.T) {
+	l1, l2 := &sample.Cache{}, &sample.Cache{}
+	cache := &Cache{Layers: []Layer{
+		{Cache: l1, TTL: fixedTTL(10 * time.Millisecond)},
+		{Cache: l2, TTL: fixedTTL(time.Minute)},
+	}}
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ttl, _ := l1.GetWithTTL(ctx, "key"); ttl <= 0 || ttl > 10*time.Millisecond {
+		t.Errorf("Expected a L1 TTL within 10ms, got %v", ttl)
+	}
+	if _, ttl, _ := l2.GetWithTTL(ctx, "key"); ttl <= 10*time.Millisecond || ttl > time.Minute {
+		t.Errorf("Expected a L2 TTL within 1m, got %v", ttl)
+	}
+
+	// The L1 entry expires first, and is back-filled from L2
+	time.Sleep(15 * time.Millisecond)
+	if _, err := l1.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Fatalf("Expected the L1 entry to expire, got %v", err)
+	}
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Fatalf("Expected value, got %v and %v", val, err)
+	}
+	if _, ttl, _ := l1.GetWithTTL(ctx, "key"); ttl <= 0 || ttl > 10*time.Millisecond {
+		t.Errorf("Expected a back-filled L1 TTL within 10ms, got %v", ttl)
+	}
+
+	// An L2 entry about to expire caps the back-filled L1 TTL
+	if err := l2.SetWithTTL(ctx, "short", "value", 5*time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := cache.Get(ctx, "short"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ttl, _ := l1.GetWithTTL(ctx, "short"); ttl <= 0 || ttl > 5*time.Millisecond {
+		t.Errorf("Expected a back-filled L1 TTL within 5ms, got %v", ttl)
+	}
+}
+
+// TestCache_BackFillError tests that a failed back-fill still returns the value.
+func TestCache_BackFillError(t *testing.T) {
+	var handled error
+	l2 := &sample.Cache{}
+	cache := &Cache{
+		Layers:       []Layer{{Cache: &failingCache{}}, {Cache: l2}},
+		ErrorHandler: func(err error) { handled = err },
+	}
+	ctx := context.Background()
+
+	if err := l2.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected value, got %v and %v", val, err)
+	}
+	if handled == nil {
+		t.Error("Expected the error handler to be called")
+	}
+
+	// Writes report the failure
+	if err := cache.Set(ctx, "key", "value"); err == nil {
+		t.Error("Expected an error")
+	}
+}
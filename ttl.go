@@ -0,0 +1,91 @@
+package gouache
+
+import (
+	"context"
+	"time"
+)
+
+// TTLCache is an optional interface that a Cache can implement to support
+// a time-to-live duration per entry, given at the call site.
+//
+// A ttl of zero or less means that the entry never expires.
+//
+// Use the SetWithTTL and GetWithTTL helpers to call these methods,
+// they fall back to the Cache methods for implementations that do not
+// support per-entry expiration.
+type TTLCache interface {
+	// SetWithTTL stores a value in the cache under the specified key,
+	// the entry expires once the ttl has passed.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key under which the value will be stored
+	//   - val: The value to store
+	//   - ttl: The time-to-live of the entry, zero or less for no expiration
+	//
+	// Returns:
+	//   - An error if the operation fails
+	SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error
+
+	// GetWithTTL retrieves a value and its remaining time-to-live from the cache.
+	// It returns ErrCacheMiss if the key does not exist or has expired.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key to retrieve the value for
+	//
+	// Returns:
+	//   - The cached value or nil if not found
+	//   - The remaining time-to-live, zero if the entry never expires
+	//   - An error if the operation fails, or ErrCacheMiss if key doesn't exist
+	GetWithTTL(ctx context.Context, key string) (any, time.Duration, error)
+}
+
+// SetWithTTL stores a value in the cache with the given time-to-live.
+// If the cache implements TTLCache its SetWithTTL method is used,
+// otherwise the value is stored with Set and the cache's own expiration
+// policy applies.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - cache: The cache to store the value in
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if the operation fails
+func SetWithTTL(ctx context.Context, cache Cache, key string, val any, ttl time.Duration) error {
+	// Prefer the native TTL implementation
+	if ttlCache, ok := cache.(TTLCache); ok {
+		return ttlCache.SetWithTTL(ctx, key, val, ttl)
+	}
+
+	// Fall back to Set, the TTL can't be applied
+	return cache.Set(ctx, key, val)
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the cache.
+// If the cache implements TTLCache its GetWithTTL method is used,
+// otherwise the value is retrieved with Get and the remaining time-to-live
+// is reported as zero.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - cache: The cache to retrieve the value from
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if the operation fails, or ErrCacheMiss if key doesn't exist
+func GetWithTTL(ctx context.Context, cache Cache, key string) (any, time.Duration, error) {
+	// Prefer the native TTL implementation
+	if ttlCache, ok := cache.(TTLCache); ok {
+		return ttlCache.GetWithTTL(ctx, key)
+	}
+
+	// Fall back to Get, the remaining TTL is unknown
+	val, err := cache.Get(ctx, key)
+	return val, 0, err
+}
@@ -0,0 +1,217 @@
+// Package ttl provides reusable time-to-live policies for caches.
+//
+// The policies build a Func, which has the signature of the TTL fields of the
+// gocache and redis caches and of the TTL options of the wrapping caches, and
+// can be composed: for example a per-prefix policy of fixed TTLs, jittered so
+// that keys loaded together don't expire together.
+//
+//	cache := &redis.Cache{
+//		Cache: rdb,
+//		TTL: ttl.Prefix(map[string]ttl.Func{
+//			"user:":    ttl.Fixed(time.Hour),
+//			"session:": ttl.Fixed(10 * time.Minute),
+//		}, ttl.Fixed(time.Minute)),
+//		Jitter: ttl.Percent(0.1, ttl.Hashed),
+//	}
+package ttl
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Func determines the time-to-live of a cache entry.
+// A zero or negative TTL means the entry does not expire.
+type Func func(ctx context.Context, key string, val any) (time.Duration, error)
+
+// Jitter spreads a time-to-live, returning a TTL in [ttl, ttl+spread).
+// It has the signature of the Jitter fields of the gocache and redis caches.
+type Jitter func(key string, ttl time.Duration) time.Duration
+
+// Rand returns a number in the half-open interval [0.0,1.0) for a key.
+// It is the source of randomness of a Jitter.
+type Rand func(key string) float64
+
+// Random is a Rand that returns a new pseudo-random number on every call,
+// ignoring the key.
+func Random(key string) float64 {
+	return rand.Float64()
+}
+
+// Hashed is a Rand that derives the number from the FNV-1a hash of the key,
+// so that a key always gets the same jitter, in every process.
+func Hashed(key string) float64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	// Use the top 53 bits, the precision of a float64 mantissa
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
+
+// Fixed returns a Func that always returns the given TTL.
+//
+// Parameters:
+//   - ttl: The time-to-live of every entry
+//
+// Returns:
+//   - A Func returning ttl
+func Fixed(ttl time.Duration) Func {
+	return func(ctx context.Context, key string, val any) (time.Duration, error) {
+		return ttl, nil
+	}
+}
+
+// Prefix returns a Func that chooses the policy by key prefix.
+// The longest matching prefix wins, keys without a match use the fallback.
+//
+// Parameters:
+//   - rules: The policies by key prefix
+//   - fallback: The policy of keys without a matching prefix, nil for no expiration
+//
+// Returns:
+//   - A Func dispatching on the key prefix
+func Prefix(rules map[string]Func, fallback Func) Func {
+	// Sort the prefixes longest first, so the first match is the longest
+	prefixes := make([]string, 0, len(rules))
+	for prefix := range rules {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+
+	return func(ctx context.Context, key string, val any) (time.Duration, error) {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				return rules[prefix](ctx, key, val)
+			}
+		}
+		return call(fallback, ctx, key, val)
+	}
+}
+
+// ByType returns a Func that chooses the policy by the dynamic type of the value.
+// Values of other types use the fallback.
+//
+// Parameters:
+//   - rules: The policies by value type
+//   - fallback: The policy of values of other types, nil for no expiration
+//
+// Returns:
+//   - A Func dispatching on the value type
+func ByType(rules map[reflect.Type]Func, fallback Func) Func {
+	return func(ctx context.Context, key string, val any) (time.Duration, error) {
+		if f, ok := rules[reflect.TypeOf(val)]; ok {
+			return f(ctx, key, val)
+		}
+		return call(fallback, ctx, key, val)
+	}
+}
+
+// TypeOf returns the reflect.Type of T, for use as a key of the ByType rules.
+//
+// Returns:
+//   - The type of T, also for interface types
+func TypeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Jittered returns a Func that applies the jitter to the TTLs of another Func.
+// Zero or negative TTLs are not jittered.
+//
+// Parameters:
+//   - f: The policy determining the TTL
+//   - jitter: The jitter to apply
+//
+// Returns:
+//   - A Func returning jittered TTLs
+func Jittered(f Func, jitter Jitter) Func {
+	return func(ctx context.Context, key string, val any) (time.Duration, error) {
+		ttl, err := f(ctx, key, val)
+		if err != nil {
+			return 0, err
+		}
+		return Apply(jitter, key, ttl), nil
+	}
+}
+
+// Percent returns a Jitter that extends a TTL by up to the given fraction of it.
+// For example, with a fraction of 0.1 a TTL of 10m becomes a TTL in [10m, 11m).
+//
+// Parameters:
+//   - fraction: The maximum extension relative to the TTL, 0.1 for 10%
+//   - r: The source of randomness, Random if nil
+//
+// Returns:
+//   - A Jitter extending TTLs proportionally
+func Percent(fraction float64, r Rand) Jitter {
+	r = orRandom(r)
+	return func(key string, ttl time.Duration) time.Duration {
+		return extend(ttl, float64(ttl)*fraction*r(key))
+	}
+}
+
+// Range returns a Jitter that extends a TTL by up to the given duration.
+// For example, with a spread of 30s a TTL of 10m becomes a TTL in [10m, 10m30s).
+//
+// Parameters:
+//   - spread: The maximum extension
+//   - r: The source of randomness, Random if nil
+//
+// Returns:
+//   - A Jitter extending TTLs by an absolute amount
+func Range(spread time.Duration, r Rand) Jitter {
+	r = orRandom(r)
+	return func(key string, ttl time.Duration) time.Duration {
+		return extend(ttl, float64(spread)*r(key))
+	}
+}
+
+// Apply applies a jitter to a TTL. A nil jitter, and zero or negative TTLs,
+// which mean no expiration, leave the TTL unchanged.
+//
+// Parameters:
+//   - jitter: The jitter to apply, may be nil
+//   - key: The key of the entry
+//   - ttl: The time-to-live to jitter
+//
+// Returns:
+//   - The jittered time-to-live
+func Apply(jitter func(key string, ttl time.Duration) time.Duration, key string, ttl time.Duration) time.Duration {
+	if jitter == nil || ttl <= 0 {
+		return ttl
+	}
+	return jitter(key, ttl)
+}
+
+// call calls a Func, returning zero (no expiration) if it is nil.
+func call(f Func, ctx context.Context, key string, val any) (time.Duration, error) {
+	if f == nil {
+		return 0, nil
+	}
+	return f(ctx, key, val)
+}
+
+// orRandom returns r, or Random if r is nil.
+func orRandom(r Rand) Rand {
+	if r == nil {
+		return Random
+	}
+	return r
+}
+
+// extend adds a non-negative extension to a TTL without overflowing.
+func extend(ttl time.Duration, extension float64) time.Duration {
+	if extension <= 0 {
+		return ttl
+	}
+	if extension >= float64(math.MaxInt64-ttl) {
+		return math.MaxInt64
+	}
+	return ttl + time.Duration(extension)
+}
@@ -0,0 +1,124 @@
+package ttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestPrefix tests that the longest matching prefix determines the TTL.
+func TestPrefix(t *testing.T) {
+	f := Prefix(map[string]Func{
+		"user:":       Fixed(time.Hour),
+		"user:admin:": Fixed(time.Minute),
+	}, Fixed(time.Second))
+	ctx := context.Background()
+
+	tests := map[string]time.Duration{
+		"user:1":       time.Hour,
+		"user:admin:1": time.Minute,
+		"order:1":      time.Second,
+	}
+	for key, expected := range tests {
+		ttl, err := f(ctx, key, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ttl != expected {
+			t.Errorf("Expected %v for %q, got %v", expected, key, ttl)
+		}
+	}
+
+	// Without fallback, unmatched keys don't expire
+	ttl, err := Prefix(nil, nil)(ctx, "order:1", nil)
+	if err != nil || ttl != 0 {
+		t.Errorf("Expected 0 without error, got %v and %v", ttl, err)
+	}
+}
+
+// TestByType tests that the dynamic type of the value determines the TTL.
+func TestByType(t *testing.T) {
+	errBoom := errors.New("boom")
+	f := ByType(map[reflect.Type]Func{
+		TypeOf[string](): Fixed(time.Hour),
+		TypeOf[error]():  Fixed(time.Minute),
+		TypeOf[*int]():   func(ctx context.Context, key string, val any) (time.Duration, error) { return 0, errBoom },
+	}, Fixed(time.Second))
+	ctx := context.Background()
+
+	if ttl, _ := f(ctx, "key", "value"); ttl != time.Hour {
+		t.Errorf("Expected 1h for a string, got %v", ttl)
+	}
+	if ttl, _ := f(ctx, "key", 42); ttl != time.Second {
+		t.Errorf("Expected the fallback for an int, got %v", ttl)
+	}
+	if _, err := f(ctx, "key", new(int)); !errors.Is(err, errBoom) {
+		t.Errorf("Expected the policy error, got %v", err)
+	}
+}
+
+// TestPercent tests that the jitter stays within the fraction of the TTL.
+func TestPercent(t *testing.T) {
+	jitter := Percent(0.1, nil)
+	for i := 0; i < 1000; i++ {
+		ttl := jitter("key", 10*time.Minute)
+		if ttl < 10*time.Minute || ttl >= 11*time.Minute {
+			t.Fatalf("Expected a TTL in [10m, 11m), got %v", ttl)
+		}
+	}
+}
+
+// TestRange tests that the jitter stays within the absolute range and
+// actually spreads the TTLs.
+func TestRange(t *testing.T) {
+	jitter := Range(30*time.Second, nil)
+	seen := make(map[time.Duration]struct{})
+	for i := 0; i < 1000; i++ {
+		ttl := jitter("key", 10*time.Minute)
+		if ttl < 10*time.Minute || ttl >= 10*time.Minute+30*time.Second {
+			t.Fatalf("Expected a TTL in [10m, 10m30s), got %v", ttl)
+		}
+		seen[ttl] = struct{}{}
+	}
+	if len(seen) < 2 {
+		t.Errorf("Expected spread TTLs, got %v", seen)
+	}
+}
+
+// TestHashed tests that the hashed jitter is deterministic per key and
+// differs between keys.
+func TestHashed(t *testing.T) {
+	jitter := Range(time.Hour, Hashed)
+	seen := make(map[time.Duration]struct{})
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		ttl := jitter(key, time.Minute)
+		if again := jitter(key, time.Minute); again != ttl {
+			t.Fatalf("Expected the same TTL for %q, got %v and %v", key, ttl, again)
+		}
+		seen[ttl] = struct{}{}
+	}
+	if len(seen) < 90 {
+		t.Errorf("Expected different keys to get different TTLs, got %d distinct", len(seen))
+	}
+}
+
+// TestJittered tests the composition of a policy with a jitter.
+func TestJittered(t *testing.T) {
+	extendBySecond := func(key string, ttl time.Duration) time.Duration { return ttl + time.Second }
+	ctx := context.Background()
+
+	ttl, err := Jittered(Fixed(time.Minute), extendBySecond)(ctx, "key", nil)
+	if err != nil || ttl != time.Minute+time.Second {
+		t.Errorf("Expected 1m1s without error, got %v and %v", ttl, err)
+	}
+
+	// Entries without expiration stay without expiration
+	ttl, err = Jittered(Fixed(0), extendBySecond)(ctx, "key", nil)
+	if err != nil || ttl != 0 {
+		t.Errorf("Expected 0 without error, got %v and %v", ttl, err)
+	}
+}
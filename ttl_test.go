@@ -0,0 +1,55 @@
+package gouache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// TestTTL_Native tests the TTL helpers on a cache with native TTL support.
+func TestTTL_Native(t *testing.T) {
+	cache := &sample.Cache{}
+	ctx := context.Background()
+
+	if err := gouache.SetWithTTL(ctx, cache, "a", 1, time.Minute); err != nil {
+		t.Fatalf("Unexpected error when setting value: %v", err)
+	}
+	val, ttl, err := gouache.GetWithTTL(ctx, cache, "a")
+	if err != nil {
+		t.Fatalf("Unexpected error when getting value: %v", err)
+	}
+	if val != 1 {
+		t.Errorf("Expected 1, but got %v", val)
+	}
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected a remaining TTL within a minute, but got %v", ttl)
+	}
+}
+
+// TestTTL_Fallback tests the TTL helpers on a cache without native TTL support.
+func TestTTL_Fallback(t *testing.T) {
+	cache := &loopCache{data: make(map[string]any)}
+	ctx := context.Background()
+
+	// SetWithTTL falls back to Set
+	if err := gouache.SetWithTTL(ctx, cache, "a", 1, time.Minute); err != nil {
+		t.Fatalf("Unexpected error when setting value: %v", err)
+	}
+
+	// GetWithTTL falls back to Get and reports an unknown TTL as zero
+	val, ttl, err := gouache.GetWithTTL(ctx, cache, "a")
+	if err != nil {
+		t.Fatalf("Unexpected error when getting value: %v", err)
+	}
+	if val != 1 || ttl != 0 {
+		t.Errorf("Expected 1 with zero TTL, but got %v with %v", val, ttl)
+	}
+
+	// Misses are reported as ErrCacheMiss
+	if _, _, err := gouache.GetWithTTL(ctx, cache, "b"); err != gouache.ErrCacheMiss {
+		t.Errorf("Expected ErrCacheMiss, but got: %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package gouache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ErrTypeMismatch is the error wrapped by TypeMismatchError, it can be used
+// with errors.Is to detect a value of an unexpected type.
+var ErrTypeMismatch = errors.New("gouache: type mismatch")
+
+// TypeMismatchError is returned by TypedCache and the typed database adapter
+// when a value doesn't have the expected type.
+type TypeMismatchError struct {
+	// Key is the key the value belongs to.
+	Key string
+
+	// Expected is the type the value was expected to have.
+	Expected reflect.Type
+
+	// Value is the value of the unexpected type.
+	Value any
+}
+
+// Error returns a description of the mismatch.
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("gouache: type mismatch for key %q: expected %s, got %T", e.Key, e.Expected, e.Value)
+}
+
+// Unwrap returns ErrTypeMismatch.
+func (e *TypeMismatchError) Unwrap() error {
+	return ErrTypeMismatch
+}
+
+// TypedCache is a type-safe wrapper around a Cache whose values are all of type V.
+//
+// Values read from the underlying cache are converted to V with a checked type
+// assertion; a value of another type is reported as a *TypeMismatchError rather
+// than causing a panic at the call site. The Absent sentinel is reported as
+// ErrNotFound.
+type TypedCache[V any] struct {
+	// Cache is the underlying cache implementation that stores the actual data.
+	Cache Cache
+}
+
+// Get retrieves a value from the cache by its key.
+// It returns ErrCacheMiss if the key does not exist.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or the zero value of V if not found
+//   - An error if the operation fails, ErrCacheMiss if key doesn't exist,
+//     ErrNotFound if the key is cached as Absent, or a *TypeMismatchError
+//     if the cached value is not a V
+func (cache *TypedCache[V]) Get(ctx context.Context, key string) (V, error) {
+	val, err := cache.Cache.Get(ctx, key)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return castCached[V](key, val)
+}
+
+// Set stores a value in the cache under the specified key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *TypedCache[V]) Set(ctx context.Context, key string, val V) error {
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// Delete removes a value from the cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *TypedCache[V]) Delete(ctx context.Context, key string) error {
+	return cache.Cache.Delete(ctx, key)
+}
+
+// GetWithTTL retrieves a value and its remaining time-to-live from the cache.
+// See the GetWithTTL helper for caches without native TTL support.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or the zero value of V if not found
+//   - The remaining time-to-live, zero if the entry never expires or it is unknown
+//   - An error if the operation fails, ErrCacheMiss if key doesn't exist,
+//     ErrNotFound if the key is cached as Absent, or a *TypeMismatchError
+//     if the cached value is not a V
+func (cache *TypedCache[V]) GetWithTTL(ctx context.Context, key string) (V, time.Duration, error) {
+	val, ttl, err := GetWithTTL(ctx, cache.Cache, key)
+	if err != nil {
+		var zero V
+		return zero, 0, err
+	}
+	v, err := castCached[V](key, val)
+	if err != nil {
+		return v, 0, err
+	}
+	return v, ttl, nil
+}
+
+// SetWithTTL stores a value in the cache with the given time-to-live.
+// See the SetWithTTL helper for caches without native TTL support.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or less for no expiration
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *TypedCache[V]) SetWithTTL(ctx context.Context, key string, val V, ttl time.Duration) error {
+	return SetWithTTL(ctx, cache.Cache, key, val, ttl)
+}
+
+// GetMulti retrieves the values for the given keys from the cache.
+// Keys that do not exist or are cached as Absent are omitted from the
+// returned map.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map of the found keys to their cached values
+//   - An error if the operation fails, or a *TypeMismatchError if any cached
+//     value is not a V
+func (cache *TypedCache[V]) GetMulti(ctx context.Context, keys []string) (map[string]V, error) {
+	vals, err := GetMulti(ctx, cache.Cache, keys)
+	if err != nil {
+		return nil, err
+	}
+	typed := make(map[string]V, len(vals))
+	for key, val := range vals {
+		if IsAbsent(val) {
+			continue
+		}
+		v, err := cast[V](key, val)
+		if err != nil {
+			return nil, err
+		}
+		typed[key] = v
+	}
+	return typed, nil
+}
+
+// SetMulti stores every key/value pair of the given map in the cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The key/value pairs to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *TypedCache[V]) SetMulti(ctx context.Context, vals map[string]V) error {
+	untyped := make(map[string]any, len(vals))
+	for key, val := range vals {
+		untyped[key] = val
+	}
+	return SetMulti(ctx, cache.Cache, untyped)
+}
+
+// DeleteMulti removes the values for the given keys from the cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *TypedCache[V]) DeleteMulti(ctx context.Context, keys []string) error {
+	return DeleteMulti(ctx, cache.Cache, keys)
+}
+
+// TypedDatabase is the type-safe counterpart of Database for records of type V.
+// Use AsDatabase to pass it where a Database is expected, e.g. to ddd.New.
+type TypedDatabase[V any] interface {
+	// Select retrieves a record from the database by its key.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key to query the record for
+	//
+	// Returns:
+	//   - The queried record
+	//   - An error if the operation fails, or ErrNotFound if the record doesn't exist
+	Select(ctx context.Context, key string) (V, error)
+
+	// Upsert inserts or updates a record in the database.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key of the record to upsert
+	//   - val: The value to store
+	//
+	// Returns:
+	//   - An error if the operation fails
+	Upsert(ctx context.Context, key string, val V) error
+
+	// Delete removes a record from the database by its key.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key of the record to delete
+	//
+	// Returns:
+	//   - An error if the operation fails
+	Delete(ctx context.Context, key string) error
+}
+
+// AsDatabase adapts a TypedDatabase to the Database interface.
+// Upserting a value that is not a V returns a *TypeMismatchError.
+//
+// Parameters:
+//   - db: The typed database to adapt
+//
+// Returns:
+//   - A Database implementation backed by db
+func AsDatabase[V any](db TypedDatabase[V]) Database {
+	return &typedDatabase[V]{db: db}
+}
+
+// typedDatabase adapts a TypedDatabase to the Database interface.
+type typedDatabase[V any] struct {
+	db TypedDatabase[V]
+}
+
+// Select retrieves a record from the typed database.
+func (database *typedDatabase[V]) Select(ctx context.Context, key string) (any, error) {
+	val, err := database.db.Select(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// Upsert checks the type of the value and upserts it into the typed database.
+func (database *typedDatabase[V]) Upsert(ctx context.Context, key string, val any) error {
+	v, err := cast[V](key, val)
+	if err != nil {
+		return err
+	}
+	return database.db.Upsert(ctx, key, v)
+}
+
+// Delete removes a record from the typed database.
+func (database *typedDatabase[V]) Delete(ctx context.Context, key string) error {
+	return database.db.Delete(ctx, key)
+}
+
+// castCached converts a cached value to V, reporting the Absent sentinel
+// as ErrNotFound.
+//
+// Parameters:
+//   - key: The key the value belongs to, used in the error
+//   - val: The cached value to convert
+//
+// Returns:
+//   - The value as a V
+//   - ErrNotFound if the value is Absent, or a *TypeMismatchError if it is not a V
+func castCached[V any](key string, val any) (V, error) {
+	if IsAbsent(val) {
+		var zero V
+		return zero, ErrNotFound
+	}
+	return cast[V](key, val)
+}
+
+// cast converts a value to V with a checked type assertion.
+// A nil value is converted to the zero value of V if V can hold nil.
+//
+// Parameters:
+//   - key: The key the value belongs to, used in the error
+//   - val: The value to convert
+//
+// Returns:
+//   - The value as a V
+//   - A *TypeMismatchError if the value is not a V
+func cast[V any](key string, val any) (V, error) {
+	if v, ok := val.(V); ok {
+		return v, nil
+	}
+
+	var zero V
+	typ := reflect.TypeOf((*V)(nil)).Elem()
+
+	// A nil value is a valid V for pointer-like types
+	if val == nil {
+		switch typ.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			return zero, nil
+		}
+	}
+
+	return zero, &TypeMismatchError{Key: key, Expected: typ, Value: val}
+}
@@ -0,0 +1,149 @@
+package gouache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// user is the value type used by the typed tests.
+type user struct {
+	Name string
+}
+
+// userDatabase is a typed database of users backed by a map.
+type userDatabase struct {
+	data map[string]*user
+}
+
+func (db *userDatabase) Select(ctx context.Context, key string) (*user, error) {
+	return db.data[key], nil
+}
+
+func (db *userDatabase) Upsert(ctx context.Context, key string, val *user) error {
+	db.data[key] = val
+	return nil
+}
+
+func (db *userDatabase) Delete(ctx context.Context, key string) error {
+	delete(db.data, key)
+	return nil
+}
+
+// TestTypedCache tests the Get, Set and Delete methods of TypedCache.
+func TestTypedCache(t *testing.T) {
+	cache := &gouache.TypedCache[*user]{Cache: &sample.Cache{}}
+	ctx := context.Background()
+
+	// Set and get back a typed value
+	alice := &user{Name: "alice"}
+	if err := cache.Set(ctx, "alice", alice); err != nil {
+		t.Fatalf("Unexpected error when setting value: %v", err)
+	}
+	result, err := cache.Get(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Unexpected error when getting value: %v", err)
+	}
+	if result != alice {
+		t.Errorf("Expected %v, but got %v", alice, result)
+	}
+
+	// Misses are passed through
+	if err := cache.Delete(ctx, "alice"); err != nil {
+		t.Fatalf("Unexpected error when deleting value: %v", err)
+	}
+	if _, err := cache.Get(ctx, "alice"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, but got: %v", err)
+	}
+}
+
+// TestTypedCache_TypeMismatch tests that a value of the wrong type is reported as an error.
+func TestTypedCache_TypeMismatch(t *testing.T) {
+	underlying := &sample.Cache{}
+	cache := &gouache.TypedCache[*user]{Cache: underlying}
+	ctx := context.Background()
+
+	// Store a value of another type behind the typed cache's back
+	if err := underlying.Set(ctx, "bob", "not a user"); err != nil {
+		t.Fatalf("Unexpected error when setting value: %v", err)
+	}
+
+	_, err := cache.Get(ctx, "bob")
+	if !errors.Is(err, gouache.ErrTypeMismatch) {
+		t.Fatalf("Expected ErrTypeMismatch, but got: %v", err)
+	}
+	var mismatch *gouache.TypeMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected a *TypeMismatchError, but got: %T", err)
+	}
+	if mismatch.Key != "bob" || mismatch.Value != "not a user" {
+		t.Errorf("Unexpected mismatch details: %+v", mismatch)
+	}
+
+	// The batch operation reports the mismatch as well
+	if _, err := cache.GetMulti(ctx, []string{"bob"}); !errors.Is(err, gouache.ErrTypeMismatch) {
+		t.Errorf("Expected ErrTypeMismatch, but got: %v", err)
+	}
+
+	// A nil value is the zero value of a pointer type
+	if err := underlying.Set(ctx, "nobody", nil); err != nil {
+		t.Fatalf("Unexpected error when setting value: %v", err)
+	}
+	if result, err := cache.Get(ctx, "nobody"); err != nil || result != nil {
+		t.Errorf("Expected nil without error, but got %v and %v", result, err)
+	}
+}
+
+// TestAsDatabase tests the adapter from TypedDatabase to Database.
+func TestAsDatabase(t *testing.T) {
+	typed := &userDatabase{data: make(map[string]*user)}
+	db := gouache.AsDatabase[*user](typed)
+	ctx := context.Background()
+
+	// Upsert and select a typed record
+	alice := &user{Name: "alice"}
+	if err := db.Upsert(ctx, "alice", alice); err != nil {
+		t.Fatalf("Unexpected error when upserting record: %v", err)
+	}
+	result, err := db.Select(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Unexpected error when selecting record: %v", err)
+	}
+	if result != alice {
+		t.Errorf("Expected %v, but got %v", alice, result)
+	}
+
+	// Upserting a value of another type is rejected
+	if err := db.Upsert(ctx, "bob", "not a user"); !errors.Is(err, gouache.ErrTypeMismatch) {
+		t.Errorf("Expected ErrTypeMismatch, but got: %v", err)
+	}
+	if _, ok := typed.data["bob"]; ok {
+		t.Error("Expected the mismatched record not to be stored")
+	}
+}
+
+// TestTypedCache_Absent tests that the Absent sentinel is reported as ErrNotFound.
+func TestTypedCache_Absent(t *testing.T) {
+	underlying := &sample.Cache{}
+	cache := &gouache.TypedCache[*user]{Cache: underlying}
+	ctx := context.Background()
+
+	if err := underlying.Set(ctx, "ghost", gouache.Absent); err != nil {
+		t.Fatalf("Unexpected error when setting value: %v", err)
+	}
+	if _, err := cache.Get(ctx, "ghost"); !errors.Is(err, gouache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, but got: %v", err)
+	}
+
+	// Absent keys are omitted from batch results
+	vals, err := cache.GetMulti(ctx, []string{"ghost"})
+	if err != nil {
+		t.Fatalf("Unexpected error when getting values: %v", err)
+	}
+	if len(vals) != 0 {
+		t.Errorf("Expected no values, but got %v", vals)
+	}
+}
@@ -0,0 +1,449 @@
+// Package xfetch provides a read-through cache that refreshes entries
+// probabilistically before they expire, using the XFetch algorithm from
+// "Optimal Probabilistic Cache Stampede Prevention" (Vattani et al.).
+//
+// This package implements the gouache.Cache interface by wrapping an existing
+// cache. Every value is stored together with the time it took to compute and
+// its expiry time. On every read, a caller decides to recompute the value early
+// with a probability that grows as the expiry approaches and with the compute
+// time, so that in a fleet of processes sharing a cache usually a single caller
+// recomputes a hot key before it expires, instead of all of them at once after.
+package xfetch
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/loader"
+	"golang.org/x/sync/singleflight"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Entry is the value stored in the underlying cache, a cached value together
+// with the data XFetch needs. Caches that serialize values need a
+// Marshal/Unmarshal pair that round-trips Entry.
+type Entry struct {
+	// Value is the cached value.
+	Value any
+
+	// Delta is the time it took to compute the value.
+	Delta time.Duration
+
+	// Expiry is the time at which the value expires, zero if it never expires.
+	Expiry time.Time
+}
+
+// options holds configuration options for the XFetch cache.
+type options struct {
+	// TTL determines the time-to-live of the loaded entries.
+	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
+
+	// Beta scales the probability of an early recomputation.
+	Beta float64
+
+	// Rand returns a random number in the half-open interval [0.0,1.0).
+	Rand func() float64
+
+	// ErrorHandler is called when an early recomputation fails.
+	ErrorHandler func(error)
+
+	// NegativeTTL is the time-to-live of the gouache.Absent entries remembering
+	// keys the loader doesn't have, zero disables negative caching.
+	NegativeTTL time.Duration
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithTTL returns an Option that sets a function to determine the
+// time-to-live of the loaded entries.
+//
+// Parameters:
+//   - ttl: A function that returns the time-to-live for a key and value
+//
+// Returns:
+//   - An Option function that sets the TTL
+func WithTTL(ttl func(ctx context.Context, key string, val any) (time.Duration, error)) Option {
+	return func(o *options) {
+		o.TTL = ttl
+	}
+}
+
+// WithBeta returns an Option that sets the beta factor of XFetch.
+// A beta greater than 1.0 favors earlier recomputation, a beta lower than
+// 1.0 favors later recomputation.
+//
+// Parameters:
+//   - beta: The beta factor, 1.0 by default
+//
+// Returns:
+//   - An Option function that sets the Beta
+func WithBeta(beta float64) Option {
+	return func(o *options) {
+		o.Beta = beta
+	}
+}
+
+// WithRand returns an Option that sets the source of random numbers.
+//
+// Parameters:
+//   - f: A function returning a random number in the half-open interval [0.0,1.0)
+//
+// Returns:
+//   - An Option function that sets the Rand
+func WithRand(f func() float64) Option {
+	return func(o *options) {
+		o.Rand = f
+	}
+}
+
+// WithErrorHandler returns an Option that sets a custom error handler for
+// errors that occur during an early recomputation. The still valid cached
+// value is returned to the caller in that case.
+//
+// Parameters:
+//   - f: A function to handle errors
+//
+// Returns:
+//   - An Option function that sets the ErrorHandler
+func WithErrorHandler(f func(error)) Option {
+	return func(o *options) {
+		o.ErrorHandler = f
+	}
+}
+
+// WithNegativeTTL returns an Option that enables negative caching.
+// When the loader returns gouache.ErrNotFound for a key, gouache.Absent is
+// stored in the cache for the given duration, and lookups of the key return
+// gouache.ErrNotFound without calling the loader until it expires. Without
+// negative caching the entry of the key is removed instead.
+//
+// Parameters:
+//   - dur: The time-to-live of absent entries, zero or less to disable negative caching
+//
+// Returns:
+//   - An Option function that sets the NegativeTTL
+func WithNegativeTTL(dur time.Duration) Option {
+	return func(o *options) {
+		o.NegativeTTL = dur
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Set default TTL to 1m if not specified
+	if o.TTL == nil {
+		o.TTL = func(ctx context.Context, key string, val any) (time.Duration, error) {
+			return time.Minute, nil
+		}
+	}
+
+	// Set default beta to 1.0 if not specified or invalid
+	if o.Beta <= 0 {
+		o.Beta = 1.0
+	}
+
+	// Set default random source if not specified
+	if o.Rand == nil {
+		o.Rand = rand.Float64
+	}
+
+	// Set default error handler if not specified
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = func(err error) {
+			slog.Error("xfetch.Cache.Get", slog.String("err", err.Error()))
+		}
+	}
+	return o
+}
+
+// Cache is a read-through cache that recomputes entries probabilistically
+// before they expire.
+type Cache struct {
+	// options contains configuration options for the cache
+	options *options
+
+	// cache is the underlying cache implementation
+	cache gouache.Cache
+
+	// loader computes the values
+	loader loader.Loader
+
+	// group is the singleflight group used to coalesce loads of the same key.
+	group singleflight.Group
+}
+
+// New creates a new XFetch cache with the specified cache, loader and options.
+//
+// Parameters:
+//   - c: The underlying cache implementation, it should implement gouache.TTLCache
+//     so that entries are removed once they expire
+//   - l: The function computing the values
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - An XFetch cache
+func New(c gouache.Cache, l loader.Loader, opts ...Option) *Cache {
+	return &Cache{options: newOptions(opts...), cache: c, loader: l}
+}
+
+// Middleware returns a gouache.Middleware recomputing the entries of the next cache early with XFetch.
+//
+// Parameters:
+//   - l: The loader of the missing values
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A middleware wrapping the next cache with New
+func Middleware(l loader.Loader, opts ...Option) gouache.Middleware {
+	return func(next gouache.Cache) gouache.Cache {
+		return New(next, l, opts...)
+	}
+}
+
+// Get retrieves a value from the cache by its key.
+//
+// A missing or expired value is loaded before returning. A valid value is
+// recomputed early, before returning, if
+//
+//	now - Delta * beta * ln(rand()) >= Expiry
+//
+// Concurrent loads of the same key in this process are coalesced.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached or loaded value
+//   - An error if the operation or the load fails
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	// Try to get the entry from cache first
+	val, err := cache.cache.Get(ctx, key)
+	if errors.Is(err, gouache.ErrCacheMiss) {
+		return cache.load(ctx, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys remembered as absent are not loaded again until they expire
+	if gouache.IsAbsent(val) {
+		return nil, gouache.ErrNotFound
+	}
+
+	// Unwrap the entry
+	entry, err := toEntry(key, val)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries without expiration are never recomputed
+	if entry.Expiry.IsZero() {
+		return entry.Value, nil
+	}
+
+	// Entries of caches without expiration support are checked here
+	now := time.Now()
+	if !now.Before(entry.Expiry) {
+		return cache.load(ctx, key)
+	}
+
+	// Keep the entry unless this caller wins the early recomputation
+	if !cache.shouldRecompute(now, entry) {
+		return entry.Value, nil
+	}
+
+	// Recompute early, the cached value is still valid if that fails
+	fresh, err := cache.load(ctx, key)
+	if err != nil {
+		cache.options.ErrorHandler(err)
+		return entry.Value, nil
+	}
+	return fresh, nil
+}
+
+// Set stores a value in the cache under the specified key.
+// The compute time of a value set this way is unknown, so it is only
+// recomputed once it expires.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	return cache.store(ctx, key, val, 0)
+}
+
+// Delete removes a value from the cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	return cache.cache.Delete(ctx, key)
+}
+
+// shouldRecompute decides whether an entry is recomputed early. Entries
+// without expiration are never recomputed.
+//
+// Parameters:
+//   - now: The current time
+//   - entry: The cached entry
+//
+// Returns:
+//   - true if the entry should be recomputed
+func (cache *Cache) shouldRecompute(now time.Time, entry *Entry) bool {
+	if entry.Expiry.IsZero() {
+		return false
+	}
+
+	// Clamp the sample to (0.0,1.0), so that the logarithm of 1 - sample is
+	// finite and negative even if Rand returns 1.0 or a value out of range
+	sample := cache.options.Rand()
+	switch {
+	case sample <= 0:
+		sample = math.SmallestNonzeroFloat64
+	case sample >= 1:
+		sample = math.Nextafter(1, 0)
+	}
+	gap := -float64(entry.Delta) * cache.options.Beta * math.Log(1-sample)
+	return !now.Add(time.Duration(gap)).Before(entry.Expiry)
+}
+
+// load computes the value for a key with the loader, measuring the compute
+// time, and stores it in the cache. Concurrent loads of the same key are
+// coalesced. If the loader reports the key as gouache.ErrNotFound, the key is
+// remembered as absent, or its entry removed without negative caching.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to load the value for
+//
+// Returns:
+//   - The loaded value
+//   - An error if the load fails, or the value cannot be stored
+func (cache *Cache) load(ctx context.Context, key string) (any, error) {
+	val, err, _ := cache.group.Do(key, func() (any, error) {
+		// Compute the value and measure how long it takes
+		start := time.Now()
+		val, err := cache.loader(ctx, key)
+		delta := time.Since(start)
+
+		if errors.Is(err, gouache.ErrNotFound) {
+			// The value no longer exists, stop serving it
+			if absentErr := cache.absent(ctx, key); absentErr != nil {
+				return nil, absentErr
+			}
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		return val, cache.store(ctx, key, val, delta)
+	})
+	return val, err
+}
+
+// store wraps a value into an Entry and stores it in the cache for its TTL.
+// The entry never expires if the TTL is zero or less.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - delta: The time it took to compute the value
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) store(ctx context.Context, key string, val any, delta time.Duration) error {
+	ttl, err := cache.options.TTL(ctx, key, val)
+	if err != nil {
+		return err
+	}
+	entry := &Entry{Value: val, Delta: delta}
+	if ttl > 0 {
+		entry.Expiry = time.Now().Add(ttl)
+	}
+	return gouache.SetWithTTL(ctx, cache.cache, key, entry, ttl)
+}
+
+// absent replaces the entry of a key the loader doesn't have with
+// gouache.Absent for the NegativeTTL, or removes it without negative caching.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key the loader doesn't have
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) absent(ctx context.Context, key string) error {
+	if cache.options.NegativeTTL > 0 {
+		return gouache.SetWithTTL(ctx, cache.cache, key, gouache.Absent, cache.options.NegativeTTL)
+	}
+	return cache.cache.Delete(ctx, key)
+}
+
+// toEntry converts a cached value to an Entry.
+//
+// Parameters:
+//   - key: The key the value belongs to, used in the error
+//   - val: The cached value
+//
+// Returns:
+//   - The entry
+//   - A *gouache.TypeMismatchError if the value is not an Entry
+func toEntry(key string, val any) (*Entry, error) {
+	switch entry := val.(type) {
+	case *Entry:
+		return entry, nil
+	case Entry:
+		return &entry, nil
+	default:
+		return nil, &gouache.TypeMismatchError{Key: key, Expected: reflect.TypeOf((*Entry)(nil)), Value: val}
+	}
+}
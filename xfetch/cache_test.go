@@ -0,0 +1,249 @@
+package xfetch
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-leo/gouache"
+	"github.com/go-leo/gouache/sample"
+)
+
+// versionLoader is a loader that returns an increasing version number on every call.
+type versionLoader struct {
+	calls atomic.Int32
+	delay time.Duration
+	err   atomic.Value
+}
+
+func (l *versionLoader) Load(ctx context.Context, key string) (any, error) {
+	n := l.calls.Add(1)
+	if l.delay > 0 {
+		time.Sleep(l.delay)
+	}
+	if err, ok := l.err.Load().(error); ok {
+		return nil, err
+	}
+	return int(n), nil
+}
+
+// fixedTTL returns a TTL function that always returns dur.
+func fixedTTL(dur time.Duration) func(ctx context.Context, key string, val any) (time.Duration, error) {
+	return func(ctx context.Context, key string, val any) (time.Duration, error) {
+		return dur, nil
+	}
+}
+
+// TestCache_Get tests that a loaded value is served from the cache, far from expiry.
+func TestCache_Get(t *testing.T) {
+	l := &versionLoader{}
+	cache := New(&sample.Cache{}, l.Load, WithTTL(fixedTTL(time.Minute)))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		val, err := cache.Get(ctx, "key")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if val != 1 {
+			t.Errorf("Expected 1, got %v", val)
+		}
+	}
+	if calls := l.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 load, got %d", calls)
+	}
+}
+
+// TestCache_EarlyRecompute tests that a value is recomputed before it expires
+// when the random draw is unlucky enough.
+func TestCache_EarlyRecompute(t *testing.T) {
+	var draw atomic.Value
+	draw.Store(0.0)
+	l := &versionLoader{delay: 10 * time.Millisecond}
+	cache := New(&sample.Cache{}, l.Load,
+		WithTTL(fixedTTL(100*time.Millisecond)),
+		WithRand(func() float64 { return draw.Load().(float64) }),
+	)
+	ctx := context.Background()
+
+	if _, err := cache.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// -ln(1-0) = 0, the entry is kept
+	if val, err := cache.Get(ctx, "key"); err != nil || val != 1 {
+		t.Fatalf("Expected cached 1, got %v and %v", val, err)
+	}
+
+	// -ln(1-0.999999999) is about 20.7, times a delta of at least 10ms is
+	// beyond the remaining 100ms
+	draw.Store(0.999999999)
+	val, err := cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val != 2 {
+		t.Errorf("Expected recomputed 2, got %v", val)
+	}
+}
+
+// TestCache_Beta tests that the beta factor scales the probability of an early recomputation.
+func TestCache_Beta(t *testing.T) {
+	l := &versionLoader{delay: 10 * time.Millisecond}
+	// -ln(1-0.5) is about 0.69, so the gap is about 7ms times beta
+	cache := New(&sample.Cache{}, l.Load,
+		WithTTL(fixedTTL(time.Second)),
+		WithRand(func() float64 { return 0.5 }),
+		WithBeta(1000),
+	)
+	ctx := context.Background()
+
+	if _, err := cache.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, err := cache.Get(ctx, "key"); err != nil || val != 2 {
+		t.Errorf("Expected recomputed 2, got %v and %v", val, err)
+	}
+}
+
+// TestCache_RandOutOfRange tests that random numbers of 1.0 or more favor an early recomputation.
+func TestCache_RandOutOfRange(t *testing.T) {
+	for _, draw := range []float64{1.0, 1.5} {
+		l := &versionLoader{delay: 10 * time.Millisecond}
+		// The draw is clamped below 1.0, -ln(1-draw) is about 36.7
+		cache := New(&sample.Cache{}, l.Load,
+			WithTTL(fixedTTL(100*time.Millisecond)),
+			WithRand(func() float64 { return draw }),
+		)
+		ctx := context.Background()
+
+		if _, err := cache.Get(ctx, "key"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if val, err := cache.Get(ctx, "key"); err != nil || val != 2 {
+			t.Errorf("Expected recomputed 2 for %v, got %v and %v", draw, val, err)
+		}
+	}
+}
+
+// TestCache_Expired tests that an expired entry is reloaded.
+func TestCache_Expired(t *testing.T) {
+	l := &versionLoader{}
+	cache := New(&sample.Cache{}, l.Load, WithTTL(fixedTTL(10*time.Millisecond)))
+	ctx := context.Background()
+
+	if _, err := cache.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(15 * time.Millisecond)
+
+	if val, err := cache.Get(ctx, "key"); err != nil || val != 2 {
+		t.Errorf("Expected reloaded 2, got %v and %v", val, err)
+	}
+}
+
+// TestCache_NoExpiry tests that an entry stored without TTL never expires nor is recomputed early.
+func TestCache_NoExpiry(t *testing.T) {
+	l := &versionLoader{delay: time.Millisecond}
+	underlying := &sample.Cache{}
+	cache := New(underlying, l.Load,
+		WithTTL(fixedTTL(0)),
+		WithRand(func() float64 { return 0.999999999 }),
+		WithBeta(1000),
+	)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if val, err := cache.Get(ctx, "key"); err != nil || val != 1 {
+			t.Fatalf("Expected cached 1, got %v and %v", val, err)
+		}
+	}
+	val, _ := underlying.Get(ctx, "key")
+	if entry := val.(*Entry); !entry.Expiry.IsZero() {
+		t.Errorf("Expected a zero expiry, got %v", entry.Expiry)
+	}
+	if calls := l.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 load, got %d", calls)
+	}
+}
+
+// TestCache_RecomputeError tests that a failed early recomputation serves the cached value.
+func TestCache_RecomputeError(t *testing.T) {
+	var handled atomic.Int32
+	l := &versionLoader{delay: 10 * time.Millisecond}
+	cache := New(&sample.Cache{}, l.Load,
+		WithTTL(fixedTTL(100*time.Millisecond)),
+		WithRand(func() float64 { return 0.999999999 }),
+		WithErrorHandler(func(err error) { handled.Add(1) }),
+	)
+	ctx := context.Background()
+
+	if _, err := cache.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	l.err.Store(errors.New("load error"))
+	val, err := cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val != 1 {
+		t.Errorf("Expected cached 1, got %v", val)
+	}
+	if n := handled.Load(); n != 1 {
+		t.Errorf("Expected the error handler to be called once, got %d", n)
+	}
+}
+
+// TestCache_NotFound tests that a value the loader no longer has is removed.
+func TestCache_NotFound(t *testing.T) {
+	underlying := &sample.Cache{}
+	l := &versionLoader{}
+	cache := New(underlying, l.Load, WithTTL(fixedTTL(10*time.Millisecond)))
+	ctx := context.Background()
+
+	if _, err := cache.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(15 * time.Millisecond)
+
+	l.err.Store(gouache.ErrNotFound)
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, gouache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := underlying.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the entry to be removed, got %v", err)
+	}
+}
+
+// TestCache_NegativeTTL tests that keys the loader doesn't have are remembered as absent.
+func TestCache_NegativeTTL(t *testing.T) {
+	underlying := &sample.Cache{}
+	l := &versionLoader{}
+	l.err.Store(gouache.ErrNotFound)
+	cache := New(underlying, l.Load, WithNegativeTTL(10*time.Millisecond))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get(ctx, "key"); !errors.Is(err, gouache.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if n := l.calls.Load(); n != 1 {
+		t.Errorf("Expected the loader to be called once, got %d", n)
+	}
+	if val, err := underlying.Get(ctx, "key"); err != nil || !gouache.IsAbsent(val) {
+		t.Errorf("Expected Absent, got %v and %v", val, err)
+	}
+
+	// The key is loaded again once the absent entry expired
+	time.Sleep(15 * time.Millisecond)
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, gouache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if n := l.calls.Load(); n != 2 {
+		t.Errorf("Expected the loader to be called twice, got %d", n)
+	}
+}